@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	codeclarity_src "github.com/CodeClarityCE/plugin-php-sbom/src"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/diff"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+	codeclarity "github.com/CodeClarityCE/utility-types/codeclarity_db"
+	"github.com/google/uuid"
+)
+
+// runDiff compares two SBOMs. Each side is a project directory, a composer.lock
+// file or a native JSON SBOM produced by php-sbom.
+func runDiff(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("php-sbom diff", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "markdown", "report format: markdown or json")
+	verbose := flags.Bool("v", false, "print analysis logs")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: php-sbom diff [flags] <before> <after>")
+		fmt.Fprintln(stderr, "Each side is a project directory, a composer.lock or a JSON SBOM.")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return parseFailure(err)
+	}
+	if flags.NArg() != 2 || (*format != "markdown" && *format != "json") {
+		flags.Usage()
+		return EXIT_USAGE_ERROR
+	}
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	before, err := loadSnapshot(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return EXIT_ANALYSIS_FAILURE
+	}
+	after, err := loadSnapshot(flags.Arg(1))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return EXIT_ANALYSIS_FAILURE
+	}
+	report := diff.CompareSnapshots(before, after)
+
+	if *format == "json" {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintln(stderr, err)
+			return EXIT_ANALYSIS_FAILURE
+		}
+		return EXIT_OK
+	}
+	fmt.Fprint(stdout, report.Markdown())
+	return EXIT_OK
+}
+
+// loadSnapshot reads one side of a diff
+func loadSnapshot(path string) (diff.Snapshot, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return diff.Snapshot{}, err
+	}
+	if stat.IsDir() {
+		output := codeclarity_src.Start(path, uuid.UUID{}, nil)
+		if output.AnalysisInfo.Status == codeclarity.FAILURE {
+			return diff.Snapshot{}, fmt.Errorf("analysis of %s failed", path)
+		}
+		return diff.FromOutput(output), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return diff.Snapshot{}, err
+	}
+	var document map[string]json.RawMessage
+	if err := json.Unmarshal(data, &document); err != nil {
+		return diff.Snapshot{}, fmt.Errorf("%s is not a JSON document: %w", path, err)
+	}
+	if _, ok := document["workspaces"]; ok {
+		var output types.Output
+		if err := json.Unmarshal(data, &output); err != nil {
+			return diff.Snapshot{}, fmt.Errorf("%s is not a valid SBOM: %w", path, err)
+		}
+		return diff.FromOutput(output), nil
+	}
+	return diff.FromLockFile(path)
+}
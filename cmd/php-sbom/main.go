@@ -0,0 +1,268 @@
+// Command php-sbom generates the SBOM of a PHP project from the command line,
+// without the database and queue the plugin needs. It produces the same
+// output as the platform and can fail CI builds on a policy.
+//
+// Usage:
+//
+//	php-sbom [flags] [directory]
+//	php-sbom diff [flags] <before> <after>
+//	php-sbom timeline [flags] [directory]
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	codeclarity_src "github.com/CodeClarityCE/plugin-php-sbom/src"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/diagnostics"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/export"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/options"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/policy"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+	codeclarity "github.com/CodeClarityCE/utility-types/codeclarity_db"
+	"github.com/google/uuid"
+)
+
+// Exit codes
+const (
+	EXIT_OK               = 0
+	EXIT_POLICY_VIOLATION = 1
+	EXIT_ANALYSIS_FAILURE = 2
+	EXIT_USAGE_ERROR      = 3
+)
+
+// stringList is a flag that can be repeated
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "diff":
+			return runDiff(args[1:], stdout, stderr)
+		case "timeline":
+			return runTimeline(args[1:], stdout, stderr)
+		}
+	}
+
+	flags := flag.NewFlagSet("php-sbom", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "json", "output format: json, cyclonedx or spdx")
+	outputFile := flags.String("o", "", "write the SBOM to this file instead of stdout")
+	includeDev := flags.Bool("dev", true, "include dev dependencies")
+	failOn := flags.String("fail-on", "", "comma separated policy, e.g. severity:high,stability:fork,license:GPL-3.0-only,php-conflict")
+	revision := flags.String("revision", "", "read the manifests at this commit, branch or tag of the git repository instead of the working tree")
+	timeout := flags.Duration("timeout", 0, "stop the analysis after this duration, e.g. 5m, and output the partial SBOM")
+	projects := flags.Bool("projects", false, "analyze each project with its own lock file on its own, and output them with an index")
+	verbose := flags.Bool("v", false, "print analysis logs")
+	var excludes stringList
+	flags.Var(&excludes, "exclude", "exclude packages matching this glob, e.g. acme/* (repeatable)")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: php-sbom [flags] [directory]")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return parseFailure(err)
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return EXIT_USAGE_ERROR
+	}
+	directory := "."
+	if flags.NArg() == 1 {
+		directory = flags.Arg(0)
+	}
+
+	exportFormat, err := export.ParseFormat(*format)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return EXIT_USAGE_ERROR
+	}
+	failPolicy, err := policy.Parse(*failOn)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return EXIT_USAGE_ERROR
+	}
+	for _, pattern := range excludes {
+		if err := validatePattern(pattern); err != nil {
+			fmt.Fprintln(stderr, err)
+			return EXIT_USAGE_ERROR
+		}
+	}
+
+	// The analysis logs only go to stderr on demand
+	logs := io.Discard
+	if *verbose {
+		logs = stderr
+	}
+	ctx := diagnostics.WithLogger(context.Background(), log.New(logs, "", log.LstdFlags))
+
+	opts := options.Default()
+	opts.IncludeDev = *includeDev
+	opts.Timeout = *timeout
+
+	if *projects {
+		opts.MultiProject = true
+		var projectsOutput types.ProjectsOutput
+		if *revision != "" {
+			projectsOutput = codeclarity_src.StartProjectsAtRevision(ctx, directory, *revision, uuid.UUID{}, nil, opts)
+		} else {
+			projectsOutput = codeclarity_src.StartProjects(ctx, directory, uuid.UUID{}, nil, opts)
+		}
+		return writeProjects(projectsOutput, directory, exportFormat, excludes, failPolicy, *outputFile, stdout, stderr)
+	}
+
+	var output types.Output
+	if *revision != "" {
+		output = codeclarity_src.StartAtRevision(ctx, directory, *revision, uuid.UUID{}, nil, opts)
+	} else {
+		output = codeclarity_src.StartContext(ctx, directory, uuid.UUID{}, nil, opts)
+	}
+	if output.AnalysisInfo.Status == codeclarity.FAILURE {
+		fmt.Fprintf(stderr, "analysis of %s failed\n", directory)
+		for _, analysisError := range output.AnalysisInfo.Errors {
+			fmt.Fprintf(stderr, "  %s\n", analysisError.Private.Description)
+		}
+		return EXIT_ANALYSIS_FAILURE
+	}
+	for _, warning := range output.AnalysisInfo.Warnings {
+		if warning.Severity == "warning" {
+			fmt.Fprintf(stderr, "warning: %s\n", formatWarning(warning))
+		}
+	}
+	output = codeclarity_src.ExcludePackages(output, excludes)
+
+	data, err := export.Generate(output, exportFormat)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return EXIT_ANALYSIS_FAILURE
+	}
+	if *outputFile == "" {
+		if _, err := stdout.Write(data); err != nil {
+			fmt.Fprintln(stderr, err)
+			return EXIT_ANALYSIS_FAILURE
+		}
+	} else if err := os.WriteFile(*outputFile, data, 0644); err != nil {
+		fmt.Fprintln(stderr, err)
+		return EXIT_ANALYSIS_FAILURE
+	}
+
+	violations := failPolicy.Evaluate(output)
+	for _, violation := range violations {
+		fmt.Fprintf(stderr, "policy violation [%s] %s\n", violation.Rule, violation.Message)
+	}
+	if len(violations) > 0 {
+		return EXIT_POLICY_VIOLATION
+	}
+	return EXIT_OK
+}
+
+// writeProjects writes the output of the multi-project mode, and evaluates
+// the policy on each project
+func writeProjects(projectsOutput types.ProjectsOutput, directory string, exportFormat export.Format, excludes []string, failPolicy policy.Policy, outputFile string, stdout io.Writer, stderr io.Writer) int {
+	index := projectsOutput.Index
+	if index.Status == codeclarity.FAILURE {
+		fmt.Fprintf(stderr, "analysis of %s failed\n", directory)
+		for _, analysisError := range index.Errors {
+			fmt.Fprintf(stderr, "  %s\n", analysisError.Private.Description)
+		}
+		for _, summary := range index.Projects {
+			for _, analysisError := range projectsOutput.Projects[summary.ID].AnalysisInfo.Errors {
+				fmt.Fprintf(stderr, "  %s: %s\n", summary.ID, analysisError.Private.Description)
+			}
+		}
+		return EXIT_ANALYSIS_FAILURE
+	}
+	for _, warning := range index.Warnings {
+		if warning.Severity == "warning" {
+			fmt.Fprintf(stderr, "warning: %s\n", formatWarning(warning))
+		}
+	}
+	for _, summary := range index.Projects {
+		output := projectsOutput.Projects[summary.ID]
+		if output.AnalysisInfo.Status == codeclarity.FAILURE {
+			fmt.Fprintf(stderr, "warning: project %s failed\n", summary.ID)
+		}
+		for _, warning := range output.AnalysisInfo.Warnings {
+			if warning.Severity == "warning" {
+				fmt.Fprintf(stderr, "warning: %s: %s\n", summary.ID, formatWarning(warning))
+			}
+		}
+		projectsOutput.Projects[summary.ID] = codeclarity_src.ExcludePackages(output, excludes)
+	}
+
+	data, err := export.GenerateProjects(projectsOutput, exportFormat)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return EXIT_ANALYSIS_FAILURE
+	}
+	if outputFile == "" {
+		if _, err := stdout.Write(data); err != nil {
+			fmt.Fprintln(stderr, err)
+			return EXIT_ANALYSIS_FAILURE
+		}
+	} else if err := os.WriteFile(outputFile, data, 0644); err != nil {
+		fmt.Fprintln(stderr, err)
+		return EXIT_ANALYSIS_FAILURE
+	}
+
+	violated := false
+	for _, summary := range index.Projects {
+		for _, violation := range failPolicy.Evaluate(projectsOutput.Projects[summary.ID]) {
+			fmt.Fprintf(stderr, "policy violation %s [%s] %s\n", summary.ID, violation.Rule, violation.Message)
+			violated = true
+		}
+	}
+	if violated {
+		return EXIT_POLICY_VIOLATION
+	}
+	return EXIT_OK
+}
+
+// parseFailure is the exit code of a command whose flags could not be
+// parsed: -h and -help only print the usage
+func parseFailure(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return EXIT_OK
+	}
+	return EXIT_USAGE_ERROR
+}
+
+func validatePattern(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+	}
+	return nil
+}
+
+// formatWarning renders a warning as file:line:column: description
+func formatWarning(warning types.Diagnostic) string {
+	location := warning.File
+	if location != "" && warning.Line > 0 {
+		location = fmt.Sprintf("%s:%d:%d", location, warning.Line, warning.Column)
+	}
+	if location == "" {
+		return warning.Description
+	}
+	return location + ": " + warning.Description
+}
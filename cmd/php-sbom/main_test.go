@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeProject writes a project requiring a MIT and a dev only package
+func writeProject(t *testing.T) string {
+	dir := t.TempDir()
+	files := map[string]string{
+		"composer.json": `{"name": "acme/app", "require": {"acme/http": "^1.0"}, "require-dev": {"acme/testing": "^2.0"}}`,
+		"composer.lock": `{
+			"packages": [{"name": "acme/http", "version": "1.2.0", "license": ["MIT"]}],
+			"packages-dev": [{"name": "acme/testing", "version": "2.0.0", "license": ["BSD-3-Clause"]}]
+		}`,
+	}
+	for name, content := range files {
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	return dir
+}
+
+// runCommand runs the command line and returns its exit code and outputs
+func runCommand(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun(t *testing.T) {
+	dir := writeProject(t)
+
+	code, stdout, _ := runCommand(dir)
+	assert.Equal(t, EXIT_OK, code)
+	var output map[string]any
+	assert.Nil(t, json.Unmarshal([]byte(stdout), &output))
+	assert.Contains(t, output, "workspaces")
+
+	file := filepath.Join(t.TempDir(), "bom.json")
+	code, stdout, _ = runCommand("-format", "cyclonedx", "-o", file, "-dev=false", dir)
+	assert.Equal(t, EXIT_OK, code)
+	assert.Empty(t, stdout)
+	data, err := os.ReadFile(file)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"bomFormat": "CycloneDX"`)
+	assert.Contains(t, string(data), "pkg:composer/acme/http@1.2.0")
+	assert.NotContains(t, string(data), "acme/testing")
+}
+
+func TestRunPolicyViolation(t *testing.T) {
+	dir := writeProject(t)
+
+	code, stdout, stderr := runCommand("-fail-on", "license:MIT", dir)
+	assert.Equal(t, EXIT_POLICY_VIOLATION, code)
+	// The SBOM is still written
+	assert.NotEmpty(t, stdout)
+	assert.Contains(t, stderr, "policy violation [license:MIT] acme/http@1.2.0")
+
+	// Excluded packages are not evaluated
+	code, _, _ = runCommand("-fail-on", "license:MIT", "-exclude", "acme/http", dir)
+	assert.Equal(t, EXIT_OK, code)
+
+	code, _, _ = runCommand("-projects", "-fail-on", "license:MIT", dir)
+	assert.Equal(t, EXIT_POLICY_VIOLATION, code)
+}
+
+func TestRunAnalysisFailure(t *testing.T) {
+	code, stdout, stderr := runCommand(filepath.Join(t.TempDir(), "missing"))
+	assert.Equal(t, EXIT_ANALYSIS_FAILURE, code)
+	assert.Empty(t, stdout)
+	assert.Contains(t, stderr, "failed")
+
+	code, _, _ = runCommand(t.TempDir())
+	assert.Equal(t, EXIT_ANALYSIS_FAILURE, code)
+
+	code, _, _ = runCommand("-projects", t.TempDir())
+	assert.Equal(t, EXIT_ANALYSIS_FAILURE, code)
+
+	code, _, _ = runCommand("-revision", "main", t.TempDir())
+	assert.Equal(t, EXIT_ANALYSIS_FAILURE, code)
+}
+
+func TestRunUsageError(t *testing.T) {
+	dir := writeProject(t)
+	for name, args := range map[string][]string{
+		"unknown flag":     {"-unknown", dir},
+		"unknown format":   {"-format", "xml", dir},
+		"invalid policy":   {"-fail-on", "severity:critical", dir},
+		"invalid exclude":  {"-exclude", "[", dir},
+		"several projects": {dir, dir},
+		"invalid timeout":  {"-timeout", "soon", dir},
+	} {
+		t.Run(name, func(t *testing.T) {
+			code, stdout, _ := runCommand(args...)
+			assert.Equal(t, EXIT_USAGE_ERROR, code)
+			assert.Empty(t, stdout)
+		})
+	}
+}
+
+func TestRunHelp(t *testing.T) {
+	for _, args := range [][]string{{"-h"}, {"diff", "-h"}, {"timeline", "-help"}} {
+		code, stdout, stderr := runCommand(args...)
+		assert.Equal(t, EXIT_OK, code, args)
+		assert.Empty(t, stdout)
+		assert.Contains(t, stderr, "Usage", args)
+	}
+
+	code, _, _ := runCommand("-unknown")
+	assert.Equal(t, EXIT_USAGE_ERROR, code)
+}
+
+func TestRunKeepsTheStandardLogger(t *testing.T) {
+	writer := log.Writer()
+	code, _, stderr := runCommand(writeProject(t))
+	assert.Equal(t, EXIT_OK, code)
+	assert.Empty(t, stderr)
+	assert.Equal(t, writer, log.Writer())
+
+	code, _, stderr = runCommand("-v", writeProject(t))
+	assert.Equal(t, EXIT_OK, code)
+	assert.NotEmpty(t, stderr)
+	assert.Equal(t, writer, log.Writer())
+}
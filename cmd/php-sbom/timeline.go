@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/gitobject"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/timeline"
+)
+
+// runTimeline prints the history of the dependencies of the project in directory
+func runTimeline(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("php-sbom timeline", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	revision := flags.String("revision", "HEAD", "commit, branch or tag the history starts from")
+	lockPath := flags.String("lock", "", "path of the lock file in the repository, composer.lock of the directory by default")
+	maxCommits := flags.Int("max-commits", 0, "stop after walking this many commits, 0 for the whole history")
+	packageName := flags.String("package", "", "only report this package")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: php-sbom timeline [flags] [directory]")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return parseFailure(err)
+	}
+	if flags.NArg() > 1 || *maxCommits < 0 {
+		flags.Usage()
+		return EXIT_USAGE_ERROR
+	}
+	directory := "."
+	if flags.NArg() == 1 {
+		directory = flags.Arg(0)
+	}
+
+	repository, err := gitobject.Open(directory)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return EXIT_ANALYSIS_FAILURE
+	}
+	if *lockPath == "" {
+		subdirectory, err := repository.RelativePath(directory)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return EXIT_USAGE_ERROR
+		}
+		*lockPath = path.Join(subdirectory, timeline.DEFAULT_LOCK_PATH)
+	}
+
+	history, err := timeline.Build(repository, timeline.Options{Revision: *revision, LockPath: *lockPath, MaxCommits: *maxCommits})
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return EXIT_ANALYSIS_FAILURE
+	}
+	if *packageName != "" {
+		kept := history.Packages[:0]
+		for _, pkg := range history.Packages {
+			if strings.EqualFold(pkg.Name, *packageName) {
+				kept = append(kept, pkg)
+			}
+		}
+		history.Packages = kept
+	}
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(history); err != nil {
+		fmt.Fprintln(stderr, err)
+		return EXIT_ANALYSIS_FAILURE
+	}
+	return EXIT_OK
+}
@@ -3,12 +3,18 @@ package main
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"time"
 
 	codeclarity_src "github.com/CodeClarityCE/plugin-php-sbom/src"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/export"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/gitobject"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/options"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/server"
 	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
 	amqp_helper "github.com/CodeClarityCE/utility-amqp-helper"
 	dbhelper "github.com/CodeClarityCE/utility-dbhelper/helper"
@@ -16,6 +22,7 @@ import (
 	codeclarity "github.com/CodeClarityCE/utility-types/codeclarity_db"
 	"github.com/CodeClarityCE/utility-types/exceptions"
 	plugin_db "github.com/CodeClarityCE/utility-types/plugin_db"
+	"github.com/google/uuid"
 	"github.com/uptrace/bun"
 	"github.com/uptrace/bun/dialect/pgdialect"
 	"github.com/uptrace/bun/driver/pgdriver"
@@ -30,7 +37,13 @@ type Arguments struct {
 // main is the entry point of the program.
 // It reads the configuration, initializes the necessary databases and graph,
 // and starts listening on the queue.
+// When HTTP_LISTEN_ADDR is set, it serves the HTTP API instead.
 func main() {
+	if addr := os.Getenv("HTTP_LISTEN_ADDR"); addr != "" {
+		serveHTTP(addr)
+		return
+	}
+
 	config, err := readConfig()
 	if err != nil {
 		log.Printf("%v", err)
@@ -77,14 +90,37 @@ func main() {
 	amqp_helper.Listen("dispatcher_"+config.Name, callback, args, config)
 }
 
+// HTTP_READ_TIMEOUT bounds the upload of a request to the HTTP API
+const HTTP_READ_TIMEOUT = 2 * time.Minute
+
+// serveHTTP runs the HTTP API, which needs neither the databases nor the queue.
+// Each analysis is bounded by ANALYSIS_TIMEOUT, and the response must be
+// written within it once the upload is read.
+func serveHTTP(addr string) {
+	timeout := analysisTimeout()
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           server.NewHandlerWithTimeout(timeout),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       HTTP_READ_TIMEOUT,
+		WriteTimeout:      HTTP_READ_TIMEOUT + timeout + time.Minute,
+		IdleTimeout:       time.Minute,
+	}
+	log.Printf("PHP SBOM HTTP server listening on %s", addr)
+	if err := httpServer.ListenAndServe(); err != nil {
+		log.Printf("%v", err)
+	}
+}
+
 // startAnalysis is a function that performs the PHP SBOM analysis.
 // It takes the following parameters:
+// - ctx: Context bounding the analysis, a partial SBOM is stored when it expires.
 // - args: Arguments for the analysis.
 // - dispatcherMessage: DispatcherPluginMessage containing information about the analysis.
 // - config: Plugin configuration.
 // - analysis_document: Analysis document containing the analysis configuration.
 // It returns a map[string]any containing the result of the analysis, the analysis status, and an error if any.
-func startAnalysis(args Arguments, dispatcherMessage types_amqp.DispatcherPluginMessage, config plugin_db.Plugin, analysis_document codeclarity.Analysis) (map[string]any, codeclarity.AnalysisStatus, error) {
+func startAnalysis(ctx context.Context, args Arguments, dispatcherMessage types_amqp.DispatcherPluginMessage, config plugin_db.Plugin, analysis_document codeclarity.Analysis) (map[string]any, codeclarity.AnalysisStatus, error) {
 	// Get analysis config
 	messageData := analysis_document.Config[config.Name].(map[string]any)
 
@@ -98,30 +134,19 @@ func startAnalysis(args Arguments, dispatcherMessage types_amqp.DispatcherPlugin
 	projectInterface, ok := messageData["project"]
 	if !ok || projectInterface == nil {
 		// Return failure if project path is not provided
-		sbomOutput := types.Output{
-			AnalysisInfo: types.AnalysisInfo{
-				Status: codeclarity.FAILURE,
-				Errors: []exceptions.Error{
-					{
-						Public:  exceptions.ErrorContent{Type: exceptions.GENERIC_ERROR, Description: "Project path not provided in analysis configuration"},
-						Private: exceptions.ErrorContent{Type: "ProjectPathMissingException", Description: "The 'project' field is missing from the analysis configuration"},
-					},
-				},
-			},
-		}
-		
-		result := codeclarity.Result{
-			Result:     types.ConvertOutputToMap(sbomOutput),
-			AnalysisId: dispatcherMessage.AnalysisId,
-			Plugin:     config.Name,
-			CreatedOn:  time.Now(),
-		}
-		_, err := args.codeclarity.NewInsert().Model(&result).Exec(context.Background())
-		if err != nil {
-			panic(err)
-		}
-		
-		return map[string]any{"sbomKey": result.Id}, codeclarity.FAILURE, nil
+		return saveFailure(args, dispatcherMessage, config, exceptions.Error{
+			Public:  exceptions.ErrorContent{Type: exceptions.GENERIC_ERROR, Description: "Project path not provided in analysis configuration"},
+			Private: exceptions.ErrorContent{Type: "ProjectPathMissingException", Description: "The 'project' field is missing from the analysis configuration"},
+		})
+	}
+
+	// Per-analysis options, the defaults apply to the keys that are not set
+	opts, err := options.Parse(messageData)
+	if err != nil {
+		return saveFailure(args, dispatcherMessage, config, exceptions.Error{
+			Public:  exceptions.ErrorContent{Type: exceptions.GENERIC_ERROR, Description: "Invalid analysis configuration"},
+			Private: exceptions.ErrorContent{Type: "InvalidAnalysisOptionsException", Description: err.Error()},
+		})
 	}
 	
 	project := path + "/" + projectInterface.(string)
@@ -131,8 +156,24 @@ func startAnalysis(args Arguments, dispatcherMessage types_amqp.DispatcherPlugin
 	log.Printf("PHP SBOM Debug - project config: %s", projectInterface.(string))
 	log.Printf("PHP SBOM Debug - full project path: %s", project)
 
-	// Start the plugin
-	sbomOutput := codeclarity_src.Start(project, analysis_document.Id, args.knowledge)
+	// In multi-project mode, each project is stored as a result of its own
+	if opts.MultiProject {
+		var projectsOutput types.ProjectsOutput
+		if revision := analysisRevision(messageData, project); revision != "" {
+			projectsOutput = codeclarity_src.StartProjectsAtRevision(ctx, project, revision, analysis_document.Id, args.knowledge, opts)
+		} else {
+			projectsOutput = codeclarity_src.StartProjects(ctx, project, analysis_document.Id, args.knowledge, opts)
+		}
+		return saveProjects(args, dispatcherMessage, config, opts, projectsOutput)
+	}
+
+	// Start the plugin, on the requested revision when there is one
+	var sbomOutput types.Output
+	if revision := analysisRevision(messageData, project); revision != "" {
+		sbomOutput = codeclarity_src.StartAtRevision(ctx, project, revision, analysis_document.Id, args.knowledge, opts)
+	} else {
+		sbomOutput = codeclarity_src.StartContext(ctx, project, analysis_document.Id, args.knowledge, opts)
+	}
 
 	// Convert output to map and store result
 	result := codeclarity.Result{
@@ -141,7 +182,7 @@ func startAnalysis(args Arguments, dispatcherMessage types_amqp.DispatcherPlugin
 		Plugin:     config.Name,
 		CreatedOn:  time.Now(),
 	}
-	_, err := args.codeclarity.NewInsert().Model(&result).Exec(context.Background())
+	_, err = args.codeclarity.NewInsert().Model(&result).Exec(context.Background())
 	if err != nil {
 		return nil, codeclarity.FAILURE, fmt.Errorf("failed to save result: %w", err)
 	}
@@ -153,9 +194,165 @@ func startAnalysis(args Arguments, dispatcherMessage types_amqp.DispatcherPlugin
 	res["sbomKey"] = result.Id
 	res["packageCount"] = getTotalDependencyCountFromOutput(sbomOutput)
 	res["framework"] = sbomOutput.AnalysisInfo.Extra.Framework
+	res["policyViolations"] = len(sbomOutput.AnalysisInfo.Extra.PolicyViolations)
+
+	// The other requested formats are stored as results of their own
+	if sbomOutput.AnalysisInfo.Status != codeclarity.FAILURE {
+		for _, format := range opts.OutputFormats {
+			if format == export.FORMAT_JSON {
+				continue
+			}
+			documentId, err := saveDocument(args, dispatcherMessage, config, sbomOutput, format)
+			if err != nil {
+				return nil, codeclarity.FAILURE, err
+			}
+			res[string(format)+"Key"] = documentId
+		}
+	}
 
 	// The output is always a map[string]any
-	return res, sbomOutput.AnalysisInfo.Status, nil
+	// A partial SBOM still lets the next plugins run, its warnings are in the result
+	status := sbomOutput.AnalysisInfo.Status
+	if status == types.SUCCESS_WITH_WARNINGS {
+		res["warnings"] = len(sbomOutput.AnalysisInfo.Warnings)
+		status = codeclarity.SUCCESS
+	}
+	return res, status, nil
+}
+
+// saveProjects stores the output of each project, in the order of the index,
+// then the index. The sbomKey of the step is the first project, or the index
+// when no project could be analyzed, and projects lists the key of each one.
+func saveProjects(args Arguments, dispatcherMessage types_amqp.DispatcherPluginMessage, config plugin_db.Plugin, opts options.Options, projectsOutput types.ProjectsOutput) (map[string]any, codeclarity.AnalysisStatus, error) {
+	res := make(map[string]any)
+	projects := []map[string]any{}
+	warnings := len(projectsOutput.Index.Warnings)
+	for _, summary := range projectsOutput.Index.Projects {
+		sbomOutput := projectsOutput.Projects[summary.ID]
+		result := codeclarity.Result{
+			Result:     types.ConvertOutputToMap(sbomOutput),
+			AnalysisId: dispatcherMessage.AnalysisId,
+			Plugin:     config.Name,
+			CreatedOn:  time.Now(),
+		}
+		if _, err := args.codeclarity.NewInsert().Model(&result).Exec(context.Background()); err != nil {
+			return nil, codeclarity.FAILURE, fmt.Errorf("failed to save result of project %s: %w", summary.ID, err)
+		}
+
+		entry := map[string]any{
+			"id":           summary.ID,
+			"name":         summary.Name,
+			"status":       summary.Status,
+			"sbomKey":      result.Id,
+			"packageCount": summary.Dependencies,
+		}
+		if sbomOutput.AnalysisInfo.Status != codeclarity.FAILURE {
+			for _, format := range opts.OutputFormats {
+				if format == export.FORMAT_JSON {
+					continue
+				}
+				documentId, err := saveDocument(args, dispatcherMessage, config, sbomOutput, format)
+				if err != nil {
+					return nil, codeclarity.FAILURE, err
+				}
+				entry[string(format)+"Key"] = documentId
+			}
+		}
+		if _, ok := res["sbomKey"]; !ok {
+			res["sbomKey"] = result.Id
+		}
+		projects = append(projects, entry)
+		warnings += len(sbomOutput.AnalysisInfo.Warnings)
+	}
+
+	index := codeclarity.Result{
+		Result:     map[string]any{"index": projectsOutput.Index},
+		AnalysisId: dispatcherMessage.AnalysisId,
+		Plugin:     config.Name,
+		CreatedOn:  time.Now(),
+	}
+	if _, err := args.codeclarity.NewInsert().Model(&index).Exec(context.Background()); err != nil {
+		return nil, codeclarity.FAILURE, fmt.Errorf("failed to save project index: %w", err)
+	}
+	res["indexKey"] = index.Id
+	if _, ok := res["sbomKey"]; !ok {
+		res["sbomKey"] = index.Id
+	}
+	res["projects"] = projects
+
+	status := projectsOutput.Index.Status
+	if status == types.SUCCESS_WITH_WARNINGS {
+		res["warnings"] = warnings
+		status = codeclarity.SUCCESS
+	}
+	return res, status, nil
+}
+
+// saveFailure stores a failed output for an analysis that could not start
+func saveFailure(args Arguments, dispatcherMessage types_amqp.DispatcherPluginMessage, config plugin_db.Plugin, analysisError exceptions.Error) (map[string]any, codeclarity.AnalysisStatus, error) {
+	sbomOutput := types.Output{
+		AnalysisInfo: types.AnalysisInfo{
+			Status: codeclarity.FAILURE,
+			Errors: []exceptions.Error{analysisError},
+		},
+	}
+	
+	result := codeclarity.Result{
+		Result:     types.ConvertOutputToMap(sbomOutput),
+		AnalysisId: dispatcherMessage.AnalysisId,
+		Plugin:     config.Name,
+		CreatedOn:  time.Now(),
+	}
+	_, err := args.codeclarity.NewInsert().Model(&result).Exec(context.Background())
+	if err != nil {
+		panic(err)
+	}
+	
+	return map[string]any{"sbomKey": result.Id}, codeclarity.FAILURE, nil
+}
+
+// saveDocument stores the output exported to a format and returns the id of the result
+func saveDocument(args Arguments, dispatcherMessage types_amqp.DispatcherPluginMessage, config plugin_db.Plugin, sbomOutput types.Output, format export.Format) (uuid.UUID, error) {
+	data, err := export.Generate(sbomOutput, format)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	var document map[string]any
+	if err := json.Unmarshal(data, &document); err != nil {
+		return uuid.UUID{}, fmt.Errorf("failed to decode %s document: %w", format, err)
+	}
+
+	result := codeclarity.Result{
+		Result:     document,
+		AnalysisId: dispatcherMessage.AnalysisId,
+		Plugin:     config.Name,
+		CreatedOn:  time.Now(),
+	}
+	if _, err := args.codeclarity.NewInsert().Model(&result).Exec(context.Background()); err != nil {
+		return uuid.UUID{}, fmt.Errorf("failed to save %s result: %w", format, err)
+	}
+	return result.Id, nil
+}
+
+// analysisRevision returns the commit_id, or else the branch, to read the
+// manifests from. A branch missing from the repository falls back to the
+// working tree, as a checkout may not have the branch ref; a missing
+// commit_id is an error reported by the analysis.
+func analysisRevision(messageData map[string]any, project string) string {
+	repository, err := gitobject.Open(project)
+	if err != nil {
+		return ""
+	}
+	if commitId, ok := messageData["commit_id"].(string); ok && commitId != "" {
+		return commitId
+	}
+	if branch, ok := messageData["branch"].(string); ok && branch != "" {
+		if _, err := repository.ResolveRevision(branch); err == nil {
+			return branch
+		}
+		log.Printf("PHP SBOM Debug - branch %s not found, analyzing the working tree", branch)
+	}
+	return ""
 }
 
 // getTotalDependencyCountFromOutput counts total dependencies from the output
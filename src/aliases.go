@@ -0,0 +1,82 @@
+package src
+
+import (
+	"strings"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/constraint"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/graph"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/parser"
+)
+
+// aliasIndex holds the aliases of the locked package versions, keyed by
+// normalized package name and version
+type aliasIndex map[string][]string
+
+func aliasKey(name string, version string) string {
+	return graph.NormalizeName(name) + VERSION_SEPARATOR + strings.ToLower(version)
+}
+
+// buildAliasIndex collects the aliases declared in the lock aliases section,
+// in the root inline aliases and in the branch-alias of every locked package
+func buildAliasIndex(composerJSON *parser.ComposerJSON, composerLock *parser.ComposerLock) aliasIndex {
+	index := make(aliasIndex)
+	if composerLock == nil {
+		return index
+	}
+
+	for _, alias := range composerLock.Aliases {
+		index.add(alias.Package, alias.Version, alias.Alias)
+	}
+
+	if composerJSON != nil {
+		for _, requires := range []map[string]string{composerJSON.Require, composerJSON.RequireDev} {
+			for name, requirement := range requires {
+				if version, alias, ok := parser.ParseInlineAlias(requirement); ok {
+					index.add(name, version, alias)
+				}
+			}
+		}
+	}
+
+	for _, packages := range [][]parser.PackageInfo{composerLock.Packages, composerLock.PackagesDev} {
+		for _, pkg := range packages {
+			if alias, ok := parser.BranchAliases(pkg.Extra)[pkg.Version]; ok {
+				index.add(pkg.Name, pkg.Version, alias)
+			}
+		}
+	}
+
+	return index
+}
+
+func (index aliasIndex) add(name string, version string, alias string) {
+	key := aliasKey(name, version)
+	for _, existing := range index[key] {
+		if existing == alias {
+			return
+		}
+	}
+	index[key] = append(index[key], alias)
+}
+
+// aliasesOf returns the aliases of a locked package version
+func (index aliasIndex) aliasesOf(name string, version string) []string {
+	return index[aliasKey(name, version)]
+}
+
+// comparableVersion returns a version usable for range comparisons such as
+// vulnerability matching. Dev branches use the normalized form of their first
+// numeric alias, e.g. dev-main as 2.1.x-dev gives 2.1.9999999.9999999-dev.
+func comparableVersion(version string, aliases []string) string {
+	parsed, err := constraint.ParseVersion(version)
+	if err == nil && !parsed.IsBranch() {
+		return parsed.String()
+	}
+	for _, alias := range aliases {
+		parsedAlias, err := constraint.ParseVersion(alias)
+		if err == nil && !parsedAlias.IsBranch() {
+			return parsedAlias.String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,46 @@
+package src
+
+import (
+	"sort"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/export"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+)
+
+// aggregateComponents lists the packages of every workspace by package URL,
+// with the workspaces using each version
+func aggregateComponents(workspaces map[string]types.WorkSpace) map[string]types.Component {
+	ids := make([]string, 0, len(workspaces))
+	for id := range workspaces {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	components := make(map[string]types.Component)
+	for _, id := range ids {
+		for name, versions := range workspaces[id].Dependencies {
+			purl := export.PackageURL(name, "")
+			component, ok := components[purl]
+			if !ok {
+				component = types.Component{Name: name, Versions: make(map[string]types.ComponentVersion)}
+			}
+			for version, info := range versions {
+				componentVersion := component.Versions[version]
+				componentVersion.PURL = export.PackageURL(name, version)
+				scope := types.SCOPE_DEV
+				if info.Prod {
+					scope = types.SCOPE_PROD
+				}
+				componentVersion.Workspaces = append(componentVersion.Workspaces, types.ComponentUsage{
+					Workspace: id,
+					Scope:     scope,
+					Direct:    info.Direct,
+				})
+				component.Versions[version] = componentVersion
+			}
+			component.VersionSkew = len(component.Versions) > 1
+			components[purl] = component
+		}
+	}
+	return components
+}
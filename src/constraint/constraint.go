@@ -0,0 +1,589 @@
+package constraint
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Bound is one end of an interval of versions
+type Bound struct {
+	Version   Version
+	Inclusive bool
+	// Unbounded is true for -infinity on the low end and +infinity on the high end
+	Unbounded bool
+}
+
+// Interval is a contiguous range of versions
+type Interval struct {
+	Low  Bound
+	High Bound
+}
+
+// Set is a union of version intervals and of named dev branches.
+// It is the result of parsing a Composer constraint.
+type Set struct {
+	Intervals []Interval
+	Branches  []string
+}
+
+// Any returns the set matching every numeric version
+func Any() Set {
+	return Set{Intervals: []Interval{{Low: Bound{Unbounded: true}, High: Bound{Unbounded: true}}}}
+}
+
+// Empty returns the set matching nothing
+func Empty() Set {
+	return Set{}
+}
+
+// IsEmpty reports whether no version satisfies the set
+func (s Set) IsEmpty() bool {
+	return len(s.Intervals) == 0 && len(s.Branches) == 0
+}
+
+// IsAny reports whether the set matches every numeric version, as * does
+func (s Set) IsAny() bool {
+	for _, interval := range s.Intervals {
+		if interval.Low.Unbounded && interval.High.Unbounded {
+			return true
+		}
+	}
+	return false
+}
+
+// HasUpperBound reports whether every interval of the set is bounded above
+func (s Set) HasUpperBound() bool {
+	for _, interval := range s.Intervals {
+		if interval.High.Unbounded {
+			return false
+		}
+	}
+	return true
+}
+
+// Contains reports whether a version satisfies the set
+func (s Set) Contains(v Version) bool {
+	if v.IsBranch() {
+		for _, branch := range s.Branches {
+			if branch == v.Branch {
+				return true
+			}
+		}
+		return false
+	}
+	for _, interval := range s.Intervals {
+		if interval.contains(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Intersect returns the versions satisfying both sets
+func (s Set) Intersect(other Set) Set {
+	result := Set{}
+	for _, a := range s.Intervals {
+		for _, b := range other.Intervals {
+			if interval, ok := intersectIntervals(a, b); ok {
+				result.Intervals = append(result.Intervals, interval)
+			}
+		}
+	}
+	for _, branch := range s.Branches {
+		for _, otherBranch := range other.Branches {
+			if branch == otherBranch {
+				result.Branches = append(result.Branches, branch)
+			}
+		}
+	}
+	return result.normalize()
+}
+
+// Union returns the versions satisfying either set
+func (s Set) Union(other Set) Set {
+	result := Set{
+		Intervals: append(append([]Interval{}, s.Intervals...), other.Intervals...),
+		Branches:  append(append([]string{}, s.Branches...), other.Branches...),
+	}
+	return result.normalize()
+}
+
+// Majors returns the distinct major versions the set allows, and false if an
+// interval is unbounded so the count cannot be known
+func (s Set) Majors() ([]int, bool) {
+	var majors []int
+	seen := make(map[int]bool)
+	for _, interval := range s.Intervals {
+		if interval.Low.Unbounded || interval.High.Unbounded {
+			return nil, false
+		}
+		high := interval.High.Version.Major()
+		// <2.0.0-dev excludes the whole 2.x series
+		if !interval.High.Inclusive && isSeriesStart(interval.High.Version) {
+			high--
+		}
+		for major := interval.Low.Version.Major(); major <= high; major++ {
+			if !seen[major] {
+				seen[major] = true
+				majors = append(majors, major)
+			}
+		}
+	}
+	sort.Ints(majors)
+	return majors, true
+}
+
+// String renders the set as a Composer constraint
+func (s Set) String() string {
+	if s.IsEmpty() {
+		return "<none>"
+	}
+	var parts []string
+	for _, interval := range s.Intervals {
+		parts = append(parts, interval.String())
+	}
+	for _, branch := range s.Branches {
+		parts = append(parts, "dev-"+branch)
+	}
+	return strings.Join(parts, " || ")
+}
+
+// String renders the interval as a Composer constraint
+func (i Interval) String() string {
+	switch {
+	case i.Low.Unbounded && i.High.Unbounded:
+		return "*"
+	case !i.Low.Unbounded && !i.High.Unbounded && i.Low.Inclusive && i.High.Inclusive && i.Low.Version.Compare(i.High.Version) == 0:
+		return "==" + formatBound(i.Low.Version)
+	}
+
+	var parts []string
+	if !i.Low.Unbounded {
+		operator := ">"
+		if i.Low.Inclusive {
+			operator = ">="
+		}
+		parts = append(parts, operator+formatBound(i.Low.Version))
+	}
+	if !i.High.Unbounded {
+		operator := "<"
+		if i.High.Inclusive {
+			operator = "<="
+		}
+		parts = append(parts, operator+formatBound(i.High.Version))
+	}
+	return strings.Join(parts, " ")
+}
+
+// formatBound hides the implicit -dev suffix Composer adds to range bounds
+func formatBound(v Version) string {
+	if v.Stability == STABILITY_DEV && v.StabilityNumber == 0 && !v.IsBranch() && v.Parts[1] != BRANCH_VERSION_PART {
+		v.Stability = STABILITY_STABLE
+	}
+	return v.String()
+}
+
+func isSeriesStart(v Version) bool {
+	return v.Parts[1] == 0 && v.Parts[2] == 0 && v.Parts[3] == 0 && v.Stability == STABILITY_DEV
+}
+
+func (i Interval) contains(v Version) bool {
+	if !i.Low.Unbounded {
+		cmp := v.Compare(i.Low.Version)
+		if cmp < 0 || (cmp == 0 && !i.Low.Inclusive) {
+			return false
+		}
+	}
+	if !i.High.Unbounded {
+		cmp := v.Compare(i.High.Version)
+		if cmp > 0 || (cmp == 0 && !i.High.Inclusive) {
+			return false
+		}
+	}
+	return true
+}
+
+func intersectIntervals(a, b Interval) (Interval, bool) {
+	result := Interval{Low: maxLow(a.Low, b.Low), High: minHigh(a.High, b.High)}
+	if result.Low.Unbounded || result.High.Unbounded {
+		return result, true
+	}
+	cmp := result.Low.Version.Compare(result.High.Version)
+	if cmp > 0 || (cmp == 0 && !(result.Low.Inclusive && result.High.Inclusive)) {
+		return Interval{}, false
+	}
+	return result, true
+}
+
+func maxLow(a, b Bound) Bound {
+	switch {
+	case a.Unbounded:
+		return b
+	case b.Unbounded:
+		return a
+	}
+	cmp := a.Version.Compare(b.Version)
+	switch {
+	case cmp > 0:
+		return a
+	case cmp < 0:
+		return b
+	case !a.Inclusive:
+		return a
+	default:
+		return b
+	}
+}
+
+func minHigh(a, b Bound) Bound {
+	switch {
+	case a.Unbounded:
+		return b
+	case b.Unbounded:
+		return a
+	}
+	cmp := a.Version.Compare(b.Version)
+	switch {
+	case cmp < 0:
+		return a
+	case cmp > 0:
+		return b
+	case !a.Inclusive:
+		return a
+	default:
+		return b
+	}
+}
+
+// normalize sorts the intervals and merges the overlapping ones
+func (s Set) normalize() Set {
+	sort.Slice(s.Intervals, func(i, j int) bool {
+		a, b := s.Intervals[i].Low, s.Intervals[j].Low
+		if a.Unbounded || b.Unbounded {
+			return a.Unbounded && !b.Unbounded
+		}
+		return a.Version.Compare(b.Version) < 0
+	})
+
+	var merged []Interval
+	for _, interval := range s.Intervals {
+		if len(merged) == 0 {
+			merged = append(merged, interval)
+			continue
+		}
+		last := &merged[len(merged)-1]
+		if overlaps(*last, interval) {
+			last.High = maxHigh(last.High, interval.High)
+			continue
+		}
+		merged = append(merged, interval)
+	}
+	s.Intervals = merged
+
+	sort.Strings(s.Branches)
+	var branches []string
+	for i, branch := range s.Branches {
+		if i == 0 || s.Branches[i-1] != branch {
+			branches = append(branches, branch)
+		}
+	}
+	s.Branches = branches
+	return s
+}
+
+// overlaps reports whether b, starting after a, overlaps or touches a
+func overlaps(a, b Interval) bool {
+	if a.High.Unbounded || b.Low.Unbounded {
+		return true
+	}
+	cmp := b.Low.Version.Compare(a.High.Version)
+	return cmp < 0 || (cmp == 0 && (a.High.Inclusive || b.Low.Inclusive))
+}
+
+func maxHigh(a, b Bound) Bound {
+	if a.Unbounded || b.Unbounded {
+		return Bound{Unbounded: true}
+	}
+	cmp := a.Version.Compare(b.Version)
+	switch {
+	case cmp > 0:
+		return a
+	case cmp < 0:
+		return b
+	case a.Inclusive:
+		return a
+	default:
+		return b
+	}
+}
+
+var (
+	orSeparator       = regexp.MustCompile(`\s*\|\|?\s*`)
+	operatorSpacing   = regexp.MustCompile(`(>=|<=|<>|!=|==|=|>|<|\^|~)\s+`)
+	hyphenRange       = regexp.MustCompile(`^(\S+)\s+-\s+(\S+)$`)
+	andSeparator      = regexp.MustCompile(`\s*,\s*|\s+`)
+	operatorPattern   = regexp.MustCompile(`^(>=|<=|<>|!=|==|=|>|<)?(.+)$`)
+	wildcardPattern   = regexp.MustCompile(`^v?(\d+)(?:\.(\d+))?(?:\.(\d+))?\.[x*]$`)
+	partialPattern    = regexp.MustCompile(`^v?(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:\.(\d+))?$`)
+	stabilitySuffix   = regexp.MustCompile(`@(stable|rc|beta|alpha|dev)$`)
+	aliasSeparator    = regexp.MustCompile(`\s+as\s+`)
+	referenceSuffixes = regexp.MustCompile(`#\S*`)
+)
+
+// Parse parses a Composer version constraint such as "^7.4 || ^8.0",
+// ">=1.0 <2.0", "1.0 - 2.0", "~1.2", "1.2.*" or "dev-main"
+func Parse(constraint string) (Set, error) {
+	alternatives, err := Alternatives(constraint)
+	if err != nil {
+		return Set{}, err
+	}
+
+	result := Empty()
+	for _, set := range alternatives {
+		result = result.Union(set)
+	}
+	return result, nil
+}
+
+// Alternatives parses each || alternative of a constraint separately
+func Alternatives(constraint string) ([]Set, error) {
+	constraint = strings.TrimSpace(constraint)
+	// Inline aliases match on the actual version
+	if parts := aliasSeparator.Split(constraint, 2); len(parts) == 2 {
+		constraint = parts[0]
+	}
+	constraint = referenceSuffixes.ReplaceAllString(constraint, "")
+	if constraint == "" {
+		return nil, fmt.Errorf("empty constraint")
+	}
+
+	var alternatives []Set
+	for _, alternative := range orSeparator.Split(constraint, -1) {
+		if strings.TrimSpace(alternative) == "" {
+			return nil, fmt.Errorf("empty alternative in %q", constraint)
+		}
+		set, err := parseConjunction(alternative)
+		if err != nil {
+			return nil, err
+		}
+		alternatives = append(alternatives, set)
+	}
+	return alternatives, nil
+}
+
+// MustParse parses a constraint and panics if it is invalid
+func MustParse(constraint string) Set {
+	set, err := Parse(constraint)
+	if err != nil {
+		panic(err)
+	}
+	return set
+}
+
+func parseConjunction(constraint string) (Set, error) {
+	constraint = strings.TrimSpace(constraint)
+	if matches := hyphenRange.FindStringSubmatch(constraint); matches != nil {
+		return parseHyphenRange(matches[1], matches[2])
+	}
+
+	constraint = operatorSpacing.ReplaceAllString(constraint, "$1")
+	var result *Set
+	for _, atom := range andSeparator.Split(constraint, -1) {
+		if atom == "" {
+			continue
+		}
+		set, err := parseAtom(atom)
+		if err != nil {
+			return Set{}, err
+		}
+		if result == nil {
+			result = &set
+			continue
+		}
+		intersection := result.Intersect(set)
+		result = &intersection
+	}
+	if result == nil {
+		return Any(), nil
+	}
+	return *result, nil
+}
+
+func parseHyphenRange(low, high string) (Set, error) {
+	lowVersion, err := ParseVersion(low)
+	if err != nil {
+		return Set{}, err
+	}
+	lowVersion.Stability = STABILITY_DEV
+
+	interval := Interval{Low: Bound{Version: lowVersion, Inclusive: true}}
+	if matches := partialPattern.FindStringSubmatch(high); matches != nil && matches[4] == "" && (matches[2] == "" || matches[3] == "") {
+		// Partial upper bound: 1.0 - 2.1 means >=1.0 <2.2
+		position := 1
+		if matches[2] != "" {
+			position = 2
+		}
+		interval.High = Bound{Version: bump(matches, position)}
+	} else {
+		highVersion, err := ParseVersion(high)
+		if err != nil {
+			return Set{}, err
+		}
+		interval.High = Bound{Version: highVersion, Inclusive: true}
+	}
+	return Set{Intervals: []Interval{interval}}, nil
+}
+
+func parseAtom(atom string) (Set, error) {
+	atom = stabilitySuffix.ReplaceAllString(strings.ToLower(atom), "")
+	if atom == "" || atom == "*" || atom == "x" || atom == "v*" {
+		return Any(), nil
+	}
+
+	if strings.HasPrefix(atom, "dev-") {
+		return Set{Branches: []string{atom[len("dev-"):]}}, nil
+	}
+
+	switch atom[0] {
+	case '^':
+		return parseCaret(atom[1:])
+	case '~':
+		return parseTilde(atom[1:])
+	}
+
+	if matches := wildcardPattern.FindStringSubmatch(atom); matches != nil {
+		position := 1
+		if matches[3] != "" {
+			position = 3
+		} else if matches[2] != "" {
+			position = 2
+		}
+		low := versionFromParts(matches[1:], STABILITY_DEV)
+		high := bump(append([]string{""}, matches[1:]...), position)
+		return rangeSet(low, high), nil
+	}
+
+	matches := operatorPattern.FindStringSubmatch(atom)
+	operator, rawVersion := matches[1], matches[2]
+	version, err := ParseVersion(rawVersion)
+	if err != nil {
+		return Set{}, fmt.Errorf("invalid constraint %q: %w", atom, err)
+	}
+	if version.IsBranch() {
+		return Set{Branches: []string{version.Branch}}, nil
+	}
+
+	switch operator {
+	case ">=":
+		if version.Stability == STABILITY_STABLE && !strings.Contains(rawVersion, "-") {
+			version.Stability = STABILITY_DEV
+		}
+		return Set{Intervals: []Interval{{Low: Bound{Version: version, Inclusive: true}, High: Bound{Unbounded: true}}}}, nil
+	case ">":
+		return Set{Intervals: []Interval{{Low: Bound{Version: version}, High: Bound{Unbounded: true}}}}, nil
+	case "<":
+		if version.Stability == STABILITY_STABLE && !strings.Contains(rawVersion, "-") {
+			version.Stability = STABILITY_DEV
+		}
+		return Set{Intervals: []Interval{{Low: Bound{Unbounded: true}, High: Bound{Version: version}}}}, nil
+	case "<=":
+		return Set{Intervals: []Interval{{Low: Bound{Unbounded: true}, High: Bound{Version: version, Inclusive: true}}}}, nil
+	case "!=", "<>":
+		return Set{Intervals: []Interval{
+			{Low: Bound{Unbounded: true}, High: Bound{Version: version}},
+			{Low: Bound{Version: version}, High: Bound{Unbounded: true}},
+		}}, nil
+	default:
+		return Set{Intervals: []Interval{{Low: Bound{Version: version, Inclusive: true}, High: Bound{Version: version, Inclusive: true}}}}, nil
+	}
+}
+
+// parseCaret parses ^1.2.3, allowing changes that do not modify the left-most non-zero part
+func parseCaret(raw string) (Set, error) {
+	matches := partialPattern.FindStringSubmatch(raw)
+	if matches == nil {
+		version, err := ParseVersion(raw)
+		if err != nil {
+			return Set{}, fmt.Errorf("invalid caret constraint %q: %w", raw, err)
+		}
+		matches = []string{"", strconv.Itoa(version.Parts[0]), strconv.Itoa(version.Parts[1]), strconv.Itoa(version.Parts[2]), ""}
+	}
+
+	position := 3
+	switch {
+	case matches[1] != "0" || matches[2] == "":
+		position = 1
+	case matches[2] != "0" || matches[3] == "":
+		position = 2
+	}
+
+	low, err := ParseVersion(raw)
+	if err != nil {
+		return Set{}, fmt.Errorf("invalid caret constraint %q: %w", raw, err)
+	}
+	if low.Stability == STABILITY_STABLE {
+		low.Stability = STABILITY_DEV
+	}
+	return rangeSet(low, bump(matches, position)), nil
+}
+
+// parseTilde parses ~1.2, allowing the last specified part to increase
+func parseTilde(raw string) (Set, error) {
+	matches := partialPattern.FindStringSubmatch(raw)
+	if matches == nil {
+		version, err := ParseVersion(raw)
+		if err != nil {
+			return Set{}, fmt.Errorf("invalid tilde constraint %q: %w", raw, err)
+		}
+		matches = []string{"", strconv.Itoa(version.Parts[0]), strconv.Itoa(version.Parts[1]), strconv.Itoa(version.Parts[2]), ""}
+	}
+
+	position := 1
+	for i := 4; i >= 1; i-- {
+		if matches[i] != "" {
+			position = i
+			break
+		}
+	}
+	highPosition := position - 1
+	if highPosition < 1 {
+		highPosition = 1
+	}
+
+	low, err := ParseVersion(raw)
+	if err != nil {
+		return Set{}, fmt.Errorf("invalid tilde constraint %q: %w", raw, err)
+	}
+	if low.Stability == STABILITY_STABLE {
+		low.Stability = STABILITY_DEV
+	}
+	return rangeSet(low, bump(matches, highPosition)), nil
+}
+
+// bump increments the part at position (1-based) of the matched version parts
+// and zeroes the following ones, returning the lowest version of that series
+func bump(matches []string, position int) Version {
+	v := versionFromParts(matches[1:], STABILITY_DEV)
+	v.Parts[position-1]++
+	for i := position; i < 4; i++ {
+		v.Parts[i] = 0
+	}
+	return v
+}
+
+func versionFromParts(parts []string, stability Stability) Version {
+	v := Version{Stability: stability}
+	for i := 0; i < 4 && i < len(parts); i++ {
+		if parts[i] != "" {
+			v.Parts[i], _ = strconv.Atoi(parts[i])
+		}
+	}
+	return v
+}
+
+func rangeSet(low, high Version) Set {
+	return Set{Intervals: []Interval{{Low: Bound{Version: low, Inclusive: true}, High: Bound{Version: high}}}}
+}
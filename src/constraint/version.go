@@ -0,0 +1,222 @@
+package constraint
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Stability is the stability of a version, ordered from least to most stable
+type Stability int
+
+const (
+	STABILITY_DEV Stability = iota
+	STABILITY_ALPHA
+	STABILITY_BETA
+	STABILITY_RC
+	STABILITY_STABLE
+	STABILITY_PATCH
+)
+
+// String returns the name Composer uses for the stability
+func (s Stability) String() string {
+	switch s {
+	case STABILITY_DEV:
+		return "dev"
+	case STABILITY_ALPHA:
+		return "alpha"
+	case STABILITY_BETA:
+		return "beta"
+	case STABILITY_RC:
+		return "RC"
+	case STABILITY_PATCH:
+		return "patch"
+	default:
+		return "stable"
+	}
+}
+
+// ParseStability parses a stability name as used in minimum-stability and @flags
+func ParseStability(name string) Stability {
+	switch strings.ToLower(name) {
+	case "dev":
+		return STABILITY_DEV
+	case "alpha", "a":
+		return STABILITY_ALPHA
+	case "beta", "b":
+		return STABILITY_BETA
+	case "rc":
+		return STABILITY_RC
+	case "patch", "pl", "p":
+		return STABILITY_PATCH
+	default:
+		return STABILITY_STABLE
+	}
+}
+
+// Version is a normalized Composer version
+type Version struct {
+	Parts           [4]int
+	Stability       Stability
+	StabilityNumber int
+	// Branch is set for dev branches such as dev-main, which are not ordered
+	Branch   string
+	Original string
+}
+
+// IsBranch reports whether the version is a named dev branch (dev-*)
+func (v Version) IsBranch() bool {
+	return v.Branch != ""
+}
+
+// Major returns the major version
+func (v Version) Major() int {
+	return v.Parts[0]
+}
+
+// Compare compares two numeric versions and returns -1, 0 or 1.
+// Named branches are only equal to themselves and sort before numeric versions.
+func (v Version) Compare(other Version) int {
+	if v.IsBranch() || other.IsBranch() {
+		switch {
+		case v.IsBranch() && other.IsBranch():
+			return strings.Compare(v.Branch, other.Branch)
+		case v.IsBranch():
+			return -1
+		default:
+			return 1
+		}
+	}
+	for i := range v.Parts {
+		if v.Parts[i] != other.Parts[i] {
+			return compareInt(v.Parts[i], other.Parts[i])
+		}
+	}
+	if v.Stability != other.Stability {
+		return compareInt(int(v.Stability), int(other.Stability))
+	}
+	return compareInt(v.StabilityNumber, other.StabilityNumber)
+}
+
+// String returns the normalized form of the version
+func (v Version) String() string {
+	if v.IsBranch() {
+		return "dev-" + v.Branch
+	}
+	s := fmt.Sprintf("%d.%d.%d", v.Parts[0], v.Parts[1], v.Parts[2])
+	if v.Parts[3] != 0 {
+		s += fmt.Sprintf(".%d", v.Parts[3])
+	}
+	if v.Stability != STABILITY_STABLE {
+		s += "-" + v.Stability.String()
+		if v.StabilityNumber != 0 {
+			s += strconv.Itoa(v.StabilityNumber)
+		}
+	}
+	return s
+}
+
+// BRANCH_VERSION_PART is the value Composer uses for the x in 1.2.x-dev
+const BRANCH_VERSION_PART = 9999999
+
+var (
+	versionPattern    = regexp.MustCompile(`^(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:\.(\d+))?(?:[._-]?(?:(stable|beta|b|rc|alpha|a|patch|pl|p)((?:[.-]?\d+)*)?)?([.-]?dev)?)?$`)
+	branchPattern     = regexp.MustCompile(`^(\d+)(?:\.(\d+|[x*]))?(?:\.(\d+|[x*]))?(?:\.(\d+|[x*]))?[.-]?dev$`)
+	datetimeVersion   = regexp.MustCompile(`^\d{4}(?:[.:-]?\d{2}){1,6}(?:[.:-]?\d{1,3})?$`)
+	stabilityFlagPart = regexp.MustCompile(`@(stable|rc|beta|alpha|dev)$`)
+	digitsPattern     = regexp.MustCompile(`\d+`)
+)
+
+// ParseVersion parses a Composer version such as v1.2.3, 1.0.0-beta2,
+// 2.1.x-dev or dev-main
+func ParseVersion(version string) (Version, error) {
+	original := version
+	version = strings.TrimSpace(version)
+	version = stabilityFlagPart.ReplaceAllString(strings.ToLower(version), "")
+	// Commit references and build metadata are not part of the version
+	if i := strings.IndexAny(version, "#+"); i >= 0 {
+		version = version[:i]
+	}
+	version = strings.TrimPrefix(version, "=")
+
+	if strings.HasPrefix(version, "dev-") {
+		if len(version) == len("dev-") {
+			return Version{}, fmt.Errorf("invalid version %q", original)
+		}
+		return Version{Branch: version[len("dev-"):], Stability: STABILITY_DEV, Original: original}, nil
+	}
+
+	if version == "" {
+		return Version{}, fmt.Errorf("invalid version %q", original)
+	}
+	if version[0] == 'v' {
+		version = version[1:]
+	}
+
+	if matches := branchPattern.FindStringSubmatch(version); matches != nil && strings.ContainsAny(version, "x*") {
+		v := Version{Stability: STABILITY_DEV, Original: original}
+		for i := 0; i < 4; i++ {
+			part := matches[i+1]
+			if part == "" || part == "x" || part == "*" {
+				v.Parts[i] = BRANCH_VERSION_PART
+				continue
+			}
+			v.Parts[i], _ = strconv.Atoi(part)
+		}
+		return v, nil
+	}
+
+	if datetimeVersion.MatchString(version) {
+		v := Version{Stability: STABILITY_STABLE, Original: original}
+		digits := digitsPattern.FindAllString(version, 4)
+		for i, digit := range digits {
+			v.Parts[i], _ = strconv.Atoi(digit)
+		}
+		return v, nil
+	}
+
+	matches := versionPattern.FindStringSubmatch(version)
+	if matches == nil {
+		return Version{}, fmt.Errorf("invalid version %q", original)
+	}
+
+	v := Version{Stability: STABILITY_STABLE, Original: original}
+	for i := 0; i < 4; i++ {
+		if matches[i+1] != "" {
+			v.Parts[i], _ = strconv.Atoi(matches[i+1])
+		}
+	}
+	if matches[5] != "" {
+		v.Stability = ParseStability(matches[5])
+		number := strings.TrimLeft(matches[6], ".-")
+		if number != "" {
+			number = strings.SplitN(strings.ReplaceAll(number, "-", "."), ".", 2)[0]
+			v.StabilityNumber, _ = strconv.Atoi(number)
+		}
+	}
+	if matches[7] != "" {
+		v.Stability = STABILITY_DEV
+	}
+	return v, nil
+}
+
+// VersionStability returns the stability of a version string, dev branches included
+func VersionStability(version string) Stability {
+	v, err := ParseVersion(version)
+	if err != nil {
+		return STABILITY_DEV
+	}
+	return v.Stability
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,237 @@
+// Package diagnostics collects the errors, warnings and notes of a single
+// analysis. A collector travels with the analysis in its context, so
+// concurrent or successive analyses never see each other's diagnostics.
+package diagnostics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+	"github.com/CodeClarityCE/utility-types/exceptions"
+)
+
+// Severity of a diagnostic
+type Severity string
+
+const (
+	// SEVERITY_ERROR is reported in the errors of the analysis
+	SEVERITY_ERROR Severity = "error"
+	// SEVERITY_WARNING is something the user should fix, the analysis went on
+	SEVERITY_WARNING Severity = "warning"
+	// SEVERITY_INFO is a note on how the analysis was done
+	SEVERITY_INFO Severity = "info"
+)
+
+// Kinds of diagnostics
+const (
+	ERROR_INVALID_ROOT_MANIFEST    exceptions.ERROR_TYPE = "InvalidRootManifest"
+	ERROR_ANALYSIS_INTERRUPTED     exceptions.ERROR_TYPE = "AnalysisInterrupted"
+	WARNING_MISSING_LOCK_FILE      exceptions.ERROR_TYPE = "MissingLockFile"
+	WARNING_INVALID_MANIFEST       exceptions.ERROR_TYPE = "InvalidManifest"
+	WARNING_INVALID_LOCK_FILE      exceptions.ERROR_TYPE = "InvalidLockFile"
+	WARNING_INVALID_PHAR           exceptions.ERROR_TYPE = "InvalidPHAR"
+	WARNING_ANALYSIS_TRUNCATED     exceptions.ERROR_TYPE = "AnalysisTruncated"
+	WARNING_INVALID_PHP_CONSTRAINT exceptions.ERROR_TYPE = "InvalidPHPConstraint"
+	INFO_CONSTRAINTS_UNLOCATED     exceptions.ERROR_TYPE = "ConstraintsNotLocated"
+)
+
+// Location is where a diagnostic comes from, any part may be unknown
+type Location struct {
+	File   string
+	Line   int
+	Column int
+}
+
+// String renders the location as file:line:column
+func (l Location) String() string {
+	switch {
+	case l.File == "":
+		return ""
+	case l.Line == 0:
+		return l.File
+	case l.Column == 0:
+		return fmt.Sprintf("%s:%d", l.File, l.Line)
+	default:
+		return fmt.Sprintf("%s:%d:%d", l.File, l.Line, l.Column)
+	}
+}
+
+// Diagnostic is a single error, warning or note
+type Diagnostic struct {
+	Severity Severity
+	Public   exceptions.ErrorContent
+	Private  exceptions.ErrorContent
+	Location Location
+}
+
+// Collector accumulates the diagnostics of one analysis. Its methods are safe
+// for concurrent use, and a nil collector discards everything.
+type Collector struct {
+	mu          sync.Mutex
+	diagnostics []Diagnostic
+}
+
+// NewCollector returns an empty collector
+func NewCollector() *Collector {
+	return &Collector{}
+}
+
+type contextKey struct{}
+
+// WithCollector returns a context carrying the collector
+func WithCollector(ctx context.Context, collector *Collector) context.Context {
+	return context.WithValue(ctx, contextKey{}, collector)
+}
+
+// NewContext returns a context carrying a new collector, and the collector
+func NewContext(ctx context.Context) (context.Context, *Collector) {
+	collector := NewCollector()
+	return WithCollector(ctx, collector), collector
+}
+
+type loggerKey struct{}
+
+// WithLogger returns a context whose analysis writes its logs to logger
+// instead of the standard logger
+func WithLogger(ctx context.Context, logger *log.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// Logger returns the logger of the context, the standard logger when there is none
+func Logger(ctx context.Context) *log.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*log.Logger); ok {
+		return logger
+	}
+	return log.Default()
+}
+
+// FromContext returns the collector of the context, nil when there is none
+func FromContext(ctx context.Context) *Collector {
+	collector, _ := ctx.Value(contextKey{}).(*Collector)
+	return collector
+}
+
+// Add records a diagnostic
+func (c *Collector) Add(diagnostic Diagnostic) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.diagnostics = append(c.diagnostics, diagnostic)
+}
+
+// AddError records an error, with the arguments of exceptions.AddError
+func (c *Collector) AddError(publicDescription string, publicType exceptions.ERROR_TYPE, privateDescription string, privateType exceptions.ERROR_TYPE) {
+	c.Add(Diagnostic{
+		Severity: SEVERITY_ERROR,
+		Public:   exceptions.ErrorContent{Description: publicDescription, Type: publicType},
+		Private:  exceptions.ErrorContent{Description: privateDescription, Type: privateType},
+	})
+}
+
+// Warn records a warning
+func (c *Collector) Warn(kind exceptions.ERROR_TYPE, description string, location Location) {
+	c.note(SEVERITY_WARNING, kind, description, location)
+}
+
+// Info records an informational note
+func (c *Collector) Info(kind exceptions.ERROR_TYPE, description string, location Location) {
+	c.note(SEVERITY_INFO, kind, description, location)
+}
+
+func (c *Collector) note(severity Severity, kind exceptions.ERROR_TYPE, description string, location Location) {
+	content := exceptions.ErrorContent{Description: description, Type: kind}
+	c.Add(Diagnostic{Severity: severity, Public: content, Private: content, Location: location})
+}
+
+// Interrupted records that a step of the analysis stopped early because its
+// context was done, and what was skipped as a result
+func (c *Collector) Interrupted(err error, skipped string) {
+	c.Warn(WARNING_ANALYSIS_TRUNCATED, fmt.Sprintf("Analysis interrupted (%v): %s", err, skipped), Location{})
+}
+
+// IsInterruption reports whether err comes from a context that is done
+func IsInterruption(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// Truncated reports whether a step of the analysis was interrupted
+func (c *Collector) Truncated() bool {
+	for _, diagnostic := range c.Diagnostics() {
+		if diagnostic.Public.Type == WARNING_ANALYSIS_TRUNCATED {
+			return true
+		}
+	}
+	return false
+}
+
+// Diagnostics returns every diagnostic in the order they were recorded
+func (c *Collector) Diagnostics() []Diagnostic {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Diagnostic(nil), c.diagnostics...)
+}
+
+// HasErrors reports whether an error was recorded
+func (c *Collector) HasErrors() bool {
+	for _, diagnostic := range c.Diagnostics() {
+		if diagnostic.Severity == SEVERITY_ERROR {
+			return true
+		}
+	}
+	return false
+}
+
+// HasWarnings reports whether a warning was recorded, notes do not count
+func (c *Collector) HasWarnings() bool {
+	for _, diagnostic := range c.Diagnostics() {
+		if diagnostic.Severity == SEVERITY_WARNING {
+			return true
+		}
+	}
+	return false
+}
+
+// Errors returns the errors in the format of AnalysisInfo.Errors. The
+// location, which that format has no field for, ends the private description.
+func (c *Collector) Errors() []exceptions.Error {
+	analysisErrors := []exceptions.Error{}
+	for _, diagnostic := range c.Diagnostics() {
+		if diagnostic.Severity != SEVERITY_ERROR {
+			continue
+		}
+		private := diagnostic.Private
+		if location := diagnostic.Location.String(); location != "" {
+			private.Description = fmt.Sprintf("%s (%s)", private.Description, location)
+		}
+		analysisErrors = append(analysisErrors, exceptions.Error{Public: diagnostic.Public, Private: private})
+	}
+	return analysisErrors
+}
+
+// Warnings returns the warnings and notes in the format of AnalysisInfo.Warnings
+func (c *Collector) Warnings() []types.Diagnostic {
+	warnings := []types.Diagnostic{}
+	for _, diagnostic := range c.Diagnostics() {
+		if diagnostic.Severity == SEVERITY_ERROR {
+			continue
+		}
+		warnings = append(warnings, types.Diagnostic{
+			Severity:    string(diagnostic.Severity),
+			Type:        string(diagnostic.Private.Type),
+			Description: diagnostic.Private.Description,
+			File:        diagnostic.Location.File,
+			Line:        diagnostic.Location.Line,
+			Column:      diagnostic.Location.Column,
+		})
+	}
+	return warnings
+}
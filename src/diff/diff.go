@@ -0,0 +1,227 @@
+package diff
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/constraint"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+)
+
+// Kinds of version changes
+const (
+	CHANGE_MAJOR = "major"
+	CHANGE_MINOR = "minor"
+	CHANGE_PATCH = "patch"
+	// CHANGE_PRE_RELEASE is a change of stability only, e.g. 2.0.0-RC1 to 2.0.0
+	CHANGE_PRE_RELEASE = "pre-release"
+	// CHANGE_REFERENCE is a new commit of the same dev branch
+	CHANGE_REFERENCE = "reference"
+	// CHANGE_BRANCH is a change from or to a dev branch, which cannot be ordered
+	CHANGE_BRANCH = "branch"
+)
+
+// Package scopes
+const (
+	SCOPE_PROD = "prod"
+	SCOPE_DEV  = "dev"
+)
+
+// Report lists the differences between two SBOMs
+type Report struct {
+	Added      []PackageChange `json:"added"`
+	Removed    []PackageChange `json:"removed"`
+	Upgraded   []PackageChange `json:"upgraded"`
+	Downgraded []PackageChange `json:"downgraded"`
+	// Changed lists the changes that cannot be ordered, such as branch switches
+	Changed         []PackageChange  `json:"changed"`
+	LicenseChanges  []LicenseChange  `json:"license_changes"`
+	ScopeChanges    []ScopeChange    `json:"scope_changes"`
+	PlatformChanges []PlatformChange `json:"platform_changes"`
+}
+
+// PackageChange is a package added, removed or resolved to another version
+type PackageChange struct {
+	// Workspace is the ID of the workspace of the package, empty for the root
+	Workspace string `json:"workspace,omitempty"`
+	Name      string `json:"name"`
+	Before    string `json:"before,omitempty"`
+	After     string `json:"after,omitempty"`
+	// Change is the kind of version change: major, minor, patch, pre-release, reference or branch
+	Change string `json:"change,omitempty"`
+	Scope  string `json:"scope"`
+}
+
+// LicenseChange is a package whose licenses changed
+type LicenseChange struct {
+	Workspace string   `json:"workspace,omitempty"`
+	Name      string   `json:"name"`
+	Before    []string `json:"before"`
+	After     []string `json:"after"`
+}
+
+// ScopeChange is a package that moved between production and dev
+type ScopeChange struct {
+	Workspace string `json:"workspace,omitempty"`
+	Name      string `json:"name"`
+	Before    string `json:"before"`
+	After     string `json:"after"`
+}
+
+// PlatformChange is a platform requirement added, removed or constrained differently
+type PlatformChange struct {
+	Name   string   `json:"name"`
+	Before []string `json:"before"`
+	After  []string `json:"after"`
+}
+
+// IsEmpty reports whether the two SBOMs are equivalent
+func (r Report) IsEmpty() bool {
+	return len(r.Added)+len(r.Removed)+len(r.Upgraded)+len(r.Downgraded)+len(r.Changed)+
+		len(r.LicenseChanges)+len(r.ScopeChanges)+len(r.PlatformChanges) == 0
+}
+
+// Compare compares two analysis outputs
+func Compare(before types.Output, after types.Output) Report {
+	return CompareSnapshots(FromOutput(before), FromOutput(after))
+}
+
+// CompareLockFiles compares two composer.lock files
+func CompareLockFiles(beforePath string, afterPath string) (Report, error) {
+	before, err := FromLockFile(beforePath)
+	if err != nil {
+		return Report{}, err
+	}
+	after, err := FromLockFile(afterPath)
+	if err != nil {
+		return Report{}, err
+	}
+	return CompareSnapshots(before, after), nil
+}
+
+// CompareSnapshots compares two snapshots
+func CompareSnapshots(before Snapshot, after Snapshot) Report {
+	report := Report{
+		Added:           []PackageChange{},
+		Removed:         []PackageChange{},
+		Upgraded:        []PackageChange{},
+		Downgraded:      []PackageChange{},
+		Changed:         []PackageChange{},
+		LicenseChanges:  []LicenseChange{},
+		ScopeChanges:    []ScopeChange{},
+		PlatformChanges: []PlatformChange{},
+	}
+
+	for _, key := range sortedKeys(before.Packages, after.Packages) {
+		old, hadOld := before.Packages[key]
+		current, hasCurrent := after.Packages[key]
+		switch {
+		case !hadOld:
+			report.Added = append(report.Added, PackageChange{Workspace: current.Workspace, Name: current.Name, After: current.Version, Scope: scope(current)})
+			continue
+		case !hasCurrent:
+			report.Removed = append(report.Removed, PackageChange{Workspace: old.Workspace, Name: old.Name, Before: old.Version, Scope: scope(old)})
+			continue
+		}
+
+		if change, direction := classify(old, current); change != "" {
+			packageChange := PackageChange{Workspace: current.Workspace, Name: current.Name, Before: old.Version, After: current.Version, Change: change, Scope: scope(current)}
+			switch {
+			case direction > 0:
+				report.Upgraded = append(report.Upgraded, packageChange)
+			case direction < 0:
+				report.Downgraded = append(report.Downgraded, packageChange)
+			default:
+				report.Changed = append(report.Changed, packageChange)
+			}
+		}
+		if !sameLicenses(old.Licenses, current.Licenses) {
+			report.LicenseChanges = append(report.LicenseChanges, LicenseChange{Workspace: current.Workspace, Name: current.Name, Before: old.Licenses, After: current.Licenses})
+		}
+		if old.Prod != current.Prod {
+			report.ScopeChanges = append(report.ScopeChanges, ScopeChange{Workspace: current.Workspace, Name: current.Name, Before: scope(old), After: scope(current)})
+		}
+	}
+
+	for _, name := range sortedKeys(before.Platform, after.Platform) {
+		old, current := before.Platform[name], after.Platform[name]
+		if strings.Join(old, "\x00") != strings.Join(current, "\x00") {
+			report.PlatformChanges = append(report.PlatformChanges, PlatformChange{Name: name, Before: nonNil(old), After: nonNil(current)})
+		}
+	}
+
+	return report
+}
+
+// classify returns the kind of change between two versions of a package and
+// its direction: 1 for an upgrade, -1 for a downgrade, 0 when not ordered
+func classify(old Package, current Package) (string, int) {
+	if old.Version == current.Version {
+		if old.Reference != "" && current.Reference != "" && old.Reference != current.Reference {
+			return CHANGE_REFERENCE, 0
+		}
+		return "", 0
+	}
+
+	oldVersion, oldErr := constraint.ParseVersion(old.Version)
+	currentVersion, currentErr := constraint.ParseVersion(current.Version)
+	if oldErr != nil || currentErr != nil || oldVersion.IsBranch() || currentVersion.IsBranch() {
+		return CHANGE_BRANCH, 0
+	}
+
+	direction := currentVersion.Compare(oldVersion)
+	switch {
+	case oldVersion.Parts[0] != currentVersion.Parts[0]:
+		return CHANGE_MAJOR, direction
+	case oldVersion.Parts[1] != currentVersion.Parts[1]:
+		return CHANGE_MINOR, direction
+	case oldVersion.Parts[2] != currentVersion.Parts[2] || oldVersion.Parts[3] != currentVersion.Parts[3]:
+		return CHANGE_PATCH, direction
+	case direction != 0:
+		return CHANGE_PRE_RELEASE, direction
+	default:
+		// Same normalized version written differently, e.g. v1.0.0 and 1.0.0
+		return "", 0
+	}
+}
+
+func scope(pkg Package) string {
+	if pkg.Prod {
+		return SCOPE_PROD
+	}
+	return SCOPE_DEV
+}
+
+func sameLicenses(a []string, b []string) bool {
+	normalize := func(licenses []string) string {
+		sorted := append([]string(nil), licenses...)
+		for i := range sorted {
+			sorted[i] = strings.ToLower(strings.TrimSpace(sorted[i]))
+		}
+		sort.Strings(sorted)
+		return strings.Join(sorted, "\x00")
+	}
+	return normalize(a) == normalize(b)
+}
+
+func sortedKeys[V any](maps ...map[string]V) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, m := range maps {
+		for key := range m {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
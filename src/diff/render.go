@@ -0,0 +1,79 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Markdown renders the report for a pull request comment
+func (r Report) Markdown() string {
+	var builder strings.Builder
+	builder.WriteString("## Dependency changes\n\n")
+	if r.IsEmpty() {
+		builder.WriteString("No dependency changes.\n")
+		return builder.String()
+	}
+
+	writeTable := func(title string, changes []PackageChange) {
+		if len(changes) == 0 {
+			return
+		}
+		fmt.Fprintf(&builder, "### %s (%d)\n\n", title, len(changes))
+		builder.WriteString("| Package | Before | After | Change | Scope |\n")
+		builder.WriteString("|---|---|---|---|---|\n")
+		for _, change := range changes {
+			fmt.Fprintf(&builder, "| %s | %s | %s | %s | %s |\n", displayName(change.Workspace, change.Name), orDash(change.Before), orDash(change.After), orDash(change.Change), change.Scope)
+		}
+		builder.WriteString("\n")
+	}
+	writeTable("Added", r.Added)
+	writeTable("Removed", r.Removed)
+	writeTable("Upgraded", r.Upgraded)
+	writeTable("Downgraded", r.Downgraded)
+	writeTable("Changed", r.Changed)
+
+	if len(r.LicenseChanges) > 0 {
+		fmt.Fprintf(&builder, "### License changes (%d)\n\n", len(r.LicenseChanges))
+		for _, change := range r.LicenseChanges {
+			fmt.Fprintf(&builder, "- %s: %s → %s\n", displayName(change.Workspace, change.Name), joinOrNone(change.Before), joinOrNone(change.After))
+		}
+		builder.WriteString("\n")
+	}
+	if len(r.ScopeChanges) > 0 {
+		fmt.Fprintf(&builder, "### Scope changes (%d)\n\n", len(r.ScopeChanges))
+		for _, change := range r.ScopeChanges {
+			fmt.Fprintf(&builder, "- %s: %s → %s\n", displayName(change.Workspace, change.Name), change.Before, change.After)
+		}
+		builder.WriteString("\n")
+	}
+	if len(r.PlatformChanges) > 0 {
+		fmt.Fprintf(&builder, "### Platform requirements (%d)\n\n", len(r.PlatformChanges))
+		for _, change := range r.PlatformChanges {
+			fmt.Fprintf(&builder, "- %s: %s → %s\n", change.Name, joinOrNone(change.Before), joinOrNone(change.After))
+		}
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+// displayName names a package of a workspace after the workspace
+func displayName(workspace string, name string) string {
+	if workspace == "" {
+		return name
+	}
+	return name + " (" + workspace + ")"
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+func joinOrNone(values []string) string {
+	if len(values) == 0 {
+		return "none"
+	}
+	return strings.Join(values, ", ")
+}
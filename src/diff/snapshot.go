@@ -0,0 +1,156 @@
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/parser"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+)
+
+// Snapshot is the part of an SBOM the diff engine compares
+type Snapshot struct {
+	// Packages are keyed by workspace and lower case name, see packageKey
+	Packages map[string]Package
+	// Platform maps the platform requirements to the constraints put on them
+	Platform map[string][]string
+}
+
+// Package is a resolved package of a snapshot
+type Package struct {
+	// Workspace is the ID of the workspace locking the package, empty for
+	// the root and for lock files
+	Workspace string
+	Name      string
+	Version   string
+	Reference string
+	Licenses  []string
+	Prod      bool
+}
+
+// FromOutput builds a snapshot from an analysis output. Each workspace keeps
+// its own packages, so that a version locked by a workspace only is compared
+// too. The root is compared like a lock file.
+func FromOutput(output types.Output) Snapshot {
+	snapshot := newSnapshot()
+
+	workspaceNames := make([]string, 0, len(output.WorkSpaces))
+	for name := range output.WorkSpaces {
+		workspaceNames = append(workspaceNames, name)
+	}
+	sort.Strings(workspaceNames)
+
+	for _, workspaceName := range workspaceNames {
+		workspace := output.WorkSpaces[workspaceName]
+		workspaceID := workspaceName
+		if workspaceID == types.DEFAULT_WORKSPACE_CHARACTER {
+			workspaceID = ""
+		}
+		for name, versions := range workspace.Dependencies {
+			for version, info := range versions {
+				snapshot.add(Package{
+					Workspace: workspaceID,
+					Name:      name,
+					Version:   version,
+					Reference: info.Reference,
+					Licenses:  info.Licenses,
+					Prod:      info.Prod,
+				})
+			}
+		}
+		for name, requirement := range workspace.Platform {
+			for _, constraint := range requirement.Constraints {
+				snapshot.addPlatform(name, constraint.Constraint)
+			}
+		}
+	}
+	snapshot.sortPlatform()
+	return snapshot
+}
+
+// FromLock builds a snapshot from a parsed composer.lock
+func FromLock(composerLock *parser.ComposerLock) Snapshot {
+	snapshot := newSnapshot()
+	if composerLock == nil {
+		return snapshot
+	}
+
+	sections := []struct {
+		packages []parser.PackageInfo
+		prod     bool
+	}{
+		{composerLock.Packages, true},
+		{composerLock.PackagesDev, false},
+	}
+	for _, section := range sections {
+		for _, pkg := range section.packages {
+			snapshot.add(Package{
+				Name:      pkg.Name,
+				Version:   pkg.Version,
+				Reference: pkg.Source.Reference,
+				Licenses:  parser.NormalizeLicense(pkg.License),
+				Prod:      section.prod,
+			})
+			for name, constraint := range pkg.Require {
+				if parser.IsPlatformPackage(name) {
+					snapshot.addPlatform(name, constraint)
+				}
+			}
+		}
+	}
+	for _, platform := range []parser.PlatformMap{composerLock.Platform, composerLock.PlatformDev} {
+		for name, constraint := range platform {
+			snapshot.addPlatform(name, constraint)
+		}
+	}
+	snapshot.sortPlatform()
+	return snapshot
+}
+
+// FromLockFile builds a snapshot from a composer.lock file
+func FromLockFile(filePath string) (Snapshot, error) {
+	composerLock, err := parser.ParseComposerLock(filePath)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+	return FromLock(composerLock), nil
+}
+
+func newSnapshot() Snapshot {
+	return Snapshot{
+		Packages: make(map[string]Package),
+		Platform: make(map[string][]string),
+	}
+}
+
+// packageKey sorts the packages of the root first, then those of each workspace
+func packageKey(workspace string, name string) string {
+	return workspace + "\x00" + strings.ToLower(name)
+}
+
+// add keeps a single version of a package per workspace, production usage
+// winning over dev usage
+func (s Snapshot) add(pkg Package) {
+	key := packageKey(pkg.Workspace, pkg.Name)
+	if existing, ok := s.Packages[key]; ok && (existing.Prod || !pkg.Prod) {
+		return
+	}
+	s.Packages[key] = pkg
+}
+
+func (s Snapshot) addPlatform(name string, constraint string) {
+	key := strings.ToLower(name)
+	for _, existing := range s.Platform[key] {
+		if existing == constraint {
+			return
+		}
+	}
+	s.Platform[key] = append(s.Platform[key], constraint)
+}
+
+func (s Snapshot) sortPlatform() {
+	for _, constraints := range s.Platform {
+		sort.Strings(constraints)
+	}
+}
@@ -0,0 +1,155 @@
+package export
+
+import (
+	"strings"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/notices"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+	"github.com/google/uuid"
+)
+
+// CYCLONEDX_SPEC_VERSION is the CycloneDX specification version of exported BOMs
+const CYCLONEDX_SPEC_VERSION = "1.5"
+
+// CycloneDXBOM is a CycloneDX JSON document
+type CycloneDXBOM struct {
+	BOMFormat    string                `json:"bomFormat"`
+	SpecVersion  string                `json:"specVersion"`
+	SerialNumber string                `json:"serialNumber"`
+	Version      int                   `json:"version"`
+	Metadata     CycloneDXMetadata     `json:"metadata"`
+	Components   []CycloneDXComponent  `json:"components"`
+	Dependencies []CycloneDXDependency `json:"dependencies"`
+}
+
+// CycloneDXMetadata describes the BOM and the analyzed project
+type CycloneDXMetadata struct {
+	Timestamp string             `json:"timestamp,omitempty"`
+	Tools     CycloneDXTools     `json:"tools"`
+	Component CycloneDXComponent `json:"component"`
+}
+
+// CycloneDXTools lists the tools that produced the BOM
+type CycloneDXTools struct {
+	Components []CycloneDXComponent `json:"components"`
+}
+
+// CycloneDXComponent is a package of the BOM
+type CycloneDXComponent struct {
+	Type        string              `json:"type"`
+	BOMRef      string              `json:"bom-ref,omitempty"`
+	Group       string              `json:"group,omitempty"`
+	Name        string              `json:"name"`
+	Version     string              `json:"version,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Scope       string              `json:"scope,omitempty"`
+	PURL        string              `json:"purl,omitempty"`
+	Licenses    []CycloneDXLicense  `json:"licenses,omitempty"`
+	Properties  []CycloneDXProperty `json:"properties,omitempty"`
+}
+
+// CycloneDXLicense is either a single license or an SPDX expression
+type CycloneDXLicense struct {
+	License    *CycloneDXLicenseID `json:"license,omitempty"`
+	Expression string              `json:"expression,omitempty"`
+}
+
+// CycloneDXLicenseID identifies a license by SPDX id, or by name when it is
+// not on the SPDX License List
+type CycloneDXLicenseID struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// CycloneDXProperty is a name/value pair attached to a component
+type CycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// CycloneDXDependency lists the components a component depends on
+type CycloneDXDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// CycloneDX converts the output to a CycloneDX BOM
+func CycloneDX(output types.Output) CycloneDXBOM {
+	components, rootDependencies := collectComponents(output)
+	name := rootName(output)
+	rootRef := PackageURL(name, "")
+
+	bom := CycloneDXBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  CYCLONEDX_SPEC_VERSION,
+		SerialNumber: "urn:uuid:" + uuid.New().String(),
+		Version:      1,
+		Metadata: CycloneDXMetadata{
+			Timestamp: output.AnalysisInfo.Time.AnalysisEndTime,
+			Tools: CycloneDXTools{Components: []CycloneDXComponent{
+				{Type: "application", Name: TOOL_NAME},
+			}},
+			Component: cycloneDXComponent("application", name, "", rootRef),
+		},
+		Components:   make([]CycloneDXComponent, 0, len(components)),
+		Dependencies: []CycloneDXDependency{{Ref: rootRef, DependsOn: nonNil(rootDependencies)}},
+	}
+
+	for _, c := range components {
+		component := cycloneDXComponent("library", c.Name, c.Version, c.PURL)
+		component.PURL = c.PURL
+		component.Description = c.Info.Description
+		component.Licenses = cycloneDXLicenses(c.Info.Licenses)
+		component.Scope = "required"
+		if !c.Info.Prod {
+			component.Scope = "optional"
+			component.Properties = append(component.Properties, CycloneDXProperty{Name: "composer:dev", Value: "true"})
+		}
+		if c.Info.Type != "" {
+			component.Properties = append(component.Properties, CycloneDXProperty{Name: "composer:type", Value: c.Info.Type})
+		}
+		bom.Components = append(bom.Components, component)
+		bom.Dependencies = append(bom.Dependencies, CycloneDXDependency{Ref: c.PURL, DependsOn: nonNil(c.DependsOn)})
+	}
+
+	return bom
+}
+
+// cycloneDXComponent splits the vendor of a Composer name into the component group
+func cycloneDXComponent(componentType string, name string, version string, ref string) CycloneDXComponent {
+	component := CycloneDXComponent{Type: componentType, BOMRef: ref, Name: name, Version: version}
+	if vendor, pkg, ok := strings.Cut(name, "/"); ok {
+		component.Group = vendor
+		component.Name = pkg
+	}
+	return component
+}
+
+// cycloneDXLicenses lists the licenses of a package by SPDX id or by name.
+// Several licenses are a single expression when they all have an SPDX id.
+func cycloneDXLicenses(licenses []string) []CycloneDXLicense {
+	var result []CycloneDXLicense
+	var ids []string
+	for _, license := range licenses {
+		if license = strings.TrimSpace(license); license == "" {
+			continue
+		}
+		if id, ok := notices.LicenseID(license); ok {
+			ids = append(ids, id)
+			result = append(result, CycloneDXLicense{License: &CycloneDXLicenseID{ID: id}})
+		} else {
+			result = append(result, CycloneDXLicense{License: &CycloneDXLicenseID{Name: license}})
+		}
+	}
+	if len(result) > 1 && len(ids) == len(result) {
+		return []CycloneDXLicense{{Expression: licenseExpression(ids)}}
+	}
+	return result
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
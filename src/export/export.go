@@ -0,0 +1,192 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/notices"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+)
+
+// Format is the document format an SBOM is exported to
+type Format string
+
+const (
+	// FORMAT_JSON is the native js-sbom compatible output
+	FORMAT_JSON      Format = "json"
+	FORMAT_CYCLONEDX Format = "cyclonedx"
+	FORMAT_SPDX      Format = "spdx"
+)
+
+// TOOL_NAME is the tool reported as the author of exported documents
+const TOOL_NAME = "php-sbom"
+
+// ParseFormat parses a format name, accepting the usual aliases
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "json", "native":
+		return FORMAT_JSON, nil
+	case "cyclonedx", "cdx":
+		return FORMAT_CYCLONEDX, nil
+	case "spdx":
+		return FORMAT_SPDX, nil
+	default:
+		return "", fmt.Errorf("unsupported export format: %s", name)
+	}
+}
+
+// Generate renders the output in the given format as indented JSON
+func Generate(output types.Output, format Format) ([]byte, error) {
+	var document any
+	switch format {
+	case FORMAT_JSON:
+		document = output
+	case FORMAT_CYCLONEDX:
+		document = CycloneDX(output)
+	case FORMAT_SPDX:
+		document = SPDX(output)
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+
+	data, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s document: %w", format, err)
+	}
+	return append(data, '\n'), nil
+}
+
+// GenerateProjects renders the output of the multi-project mode: its index,
+// and the output of each project in the given format
+func GenerateProjects(output types.ProjectsOutput, format Format) ([]byte, error) {
+	projects := make(map[string]any, len(output.Projects))
+	for id, project := range output.Projects {
+		switch format {
+		case FORMAT_JSON:
+			projects[id] = project
+		case FORMAT_CYCLONEDX:
+			projects[id] = CycloneDX(project)
+		case FORMAT_SPDX:
+			projects[id] = SPDX(project)
+		default:
+			return nil, fmt.Errorf("unsupported export format: %s", format)
+		}
+	}
+
+	data, err := json.MarshalIndent(map[string]any{"index": output.Index, "projects": projects}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s document: %w", format, err)
+	}
+	return append(data, '\n'), nil
+}
+
+// component is a package version of the output, merged across workspaces
+type component struct {
+	Name    string
+	Version string
+	PURL    string
+	Info    types.Versions
+	// DependsOn lists the package URLs of the resolved dependencies
+	DependsOn []string
+}
+
+// collectComponents lists the package versions of every workspace once, sorted
+// by name and version, along with the package URLs of the direct dependencies
+// of the root package
+func collectComponents(output types.Output) ([]component, []string) {
+	components := make(map[string]*component)
+	var rootDependencies []string
+
+	for _, workspaceName := range sortedWorkspaceNames(output.WorkSpaces) {
+		workspace := output.WorkSpaces[workspaceName]
+		for name, versions := range workspace.Dependencies {
+			for version, info := range versions {
+				purl := PackageURL(name, version)
+				existing, ok := components[purl]
+				if !ok {
+					existing = &component{Name: name, Version: version, PURL: purl, Info: info}
+					components[purl] = existing
+				} else if info.Prod && !existing.Info.Prod {
+					// Production usage in any workspace wins
+					existing.Info = info
+				}
+				for dependency := range info.Dependencies {
+					if resolved := resolvedVersion(workspace, dependency); resolved != "" {
+						existing.DependsOn = appendUnique(existing.DependsOn, PackageURL(dependency, resolved))
+					}
+				}
+			}
+		}
+
+		if workspaceName != types.DEFAULT_WORKSPACE_CHARACTER {
+			continue
+		}
+		for _, dependencies := range [][]types.WorkSpaceDependency{workspace.Start.Dependencies, workspace.Start.DevDependencies} {
+			for _, dependency := range dependencies {
+				if dependency.Version != "" {
+					rootDependencies = appendUnique(rootDependencies, PackageURL(dependency.Name, dependency.Version))
+				}
+			}
+		}
+	}
+
+	result := make([]component, 0, len(components))
+	for _, c := range components {
+		sort.Strings(c.DependsOn)
+		result = append(result, *c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].Version < result[j].Version
+	})
+	sort.Strings(rootDependencies)
+	return result, rootDependencies
+}
+
+// rootName returns the name of the analyzed project
+func rootName(output types.Output) string {
+	if output.AnalysisInfo.ProjectName != "" {
+		return output.AnalysisInfo.ProjectName
+	}
+	return "root"
+}
+
+// licenseExpression returns the SPDX expression of the licenses of a package
+func licenseExpression(licenses []string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(notices.LicenseExpression(licenses), "("), ")")
+}
+
+func resolvedVersion(workspace types.WorkSpace, name string) string {
+	versions := workspace.Dependencies[name]
+	keys := make([]string, 0, len(versions))
+	for version := range versions {
+		keys = append(keys, version)
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+	sort.Strings(keys)
+	return keys[0]
+}
+
+func sortedWorkspaceNames(workspaces map[string]types.WorkSpace) []string {
+	names := make([]string, 0, len(workspaces))
+	for name := range workspaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
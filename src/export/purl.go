@@ -0,0 +1,23 @@
+package export
+
+import (
+	"net/url"
+	"strings"
+)
+
+// PURL_TYPE is the package URL type of Composer packages
+const PURL_TYPE = "composer"
+
+// PackageURL returns the package URL of a Composer package, such as
+// pkg:composer/symfony/console@6.4.1. The version is omitted when empty.
+func PackageURL(name string, version string) string {
+	var builder strings.Builder
+	builder.WriteString("pkg:" + PURL_TYPE)
+	for _, segment := range strings.Split(strings.ToLower(name), "/") {
+		builder.WriteString("/" + url.PathEscape(segment))
+	}
+	if version != "" {
+		builder.WriteString("@" + url.PathEscape(version))
+	}
+	return builder.String()
+}
@@ -0,0 +1,231 @@
+package export
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/notices"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+	"github.com/google/uuid"
+)
+
+// SPDX_VERSION is the SPDX specification version of exported documents
+const SPDX_VERSION = "SPDX-2.3"
+
+// SPDX_NOASSERTION is used for the fields the SBOM does not know
+const SPDX_NOASSERTION = "NOASSERTION"
+
+// SPDXDocument is an SPDX JSON document
+type SPDXDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      SPDXCreationInfo   `json:"creationInfo"`
+	Packages          []SPDXPackage      `json:"packages"`
+	Relationships     []SPDXRelationship `json:"relationships"`
+	// HasExtractedLicensingInfos declares the LicenseRef- identifiers used
+	// for the licenses that are not on the SPDX License List
+	HasExtractedLicensingInfos []SPDXExtractedLicense `json:"hasExtractedLicensingInfos,omitempty"`
+}
+
+// SPDXExtractedLicense is a license declared by packages that has no SPDX identifier
+type SPDXExtractedLicense struct {
+	LicenseID     string `json:"licenseId"`
+	Name          string `json:"name"`
+	ExtractedText string `json:"extractedText"`
+}
+
+// SPDXCreationInfo tells when and by what the document was created
+type SPDXCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+// SPDXPackage is a package of the document
+type SPDXPackage struct {
+	Name             string            `json:"name"`
+	SPDXID           string            `json:"SPDXID"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	CopyrightText    string            `json:"copyrightText"`
+	Description      string            `json:"description,omitempty"`
+	ExternalRefs     []SPDXExternalRef `json:"externalRefs,omitempty"`
+}
+
+// SPDXExternalRef links a package to an external identifier such as its purl
+type SPDXExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+// SPDXRelationship links two elements of the document
+type SPDXRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+var spdxIDInvalidCharacters = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// SPDX converts the output to an SPDX document
+func SPDX(output types.Output) SPDXDocument {
+	components, rootDependencies := collectComponents(output)
+	name := rootName(output)
+	rootID := spdxID(name, "")
+
+	document := SPDXDocument{
+		SPDXVersion:       SPDX_VERSION,
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              name,
+		DocumentNamespace: "https://spdx.org/spdxdocs/" + spdxIDInvalidCharacters.ReplaceAllString(name, "-") + "-" + uuid.New().String(),
+		CreationInfo: SPDXCreationInfo{
+			Created:  output.AnalysisInfo.Time.AnalysisEndTime,
+			Creators: []string{"Tool: " + TOOL_NAME},
+		},
+		Packages: []SPDXPackage{{
+			Name:             name,
+			SPDXID:           rootID,
+			DownloadLocation: SPDX_NOASSERTION,
+			LicenseConcluded: SPDX_NOASSERTION,
+			LicenseDeclared:  SPDX_NOASSERTION,
+			CopyrightText:    SPDX_NOASSERTION,
+		}},
+		Relationships: []SPDXRelationship{{
+			SPDXElementID:      "SPDXRef-DOCUMENT",
+			RelationshipType:   "DESCRIBES",
+			RelatedSPDXElement: rootID,
+		}},
+	}
+
+	ids := make(map[string]string, len(components))
+	dev := make(map[string]bool, len(components))
+	licenses := newSPDXLicenses()
+	used := map[string]bool{rootID: true}
+	for _, c := range components {
+		id := uniqueSPDXID(spdxID(c.Name, c.Version), used)
+		ids[c.PURL] = id
+		dev[c.PURL] = !c.Info.Prod
+		document.Packages = append(document.Packages, SPDXPackage{
+			Name:             c.Name,
+			SPDXID:           id,
+			VersionInfo:      c.Version,
+			DownloadLocation: SPDX_NOASSERTION,
+			LicenseConcluded: SPDX_NOASSERTION,
+			LicenseDeclared:  licenses.expression(c.Info.Licenses),
+			CopyrightText:    SPDX_NOASSERTION,
+			Description:      c.Info.Description,
+			ExternalRefs: []SPDXExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  c.PURL,
+			}},
+		})
+	}
+
+	for _, purl := range rootDependencies {
+		id, ok := ids[purl]
+		if !ok {
+			continue
+		}
+		if dev[purl] {
+			document.Relationships = append(document.Relationships, SPDXRelationship{SPDXElementID: id, RelationshipType: "DEV_DEPENDENCY_OF", RelatedSPDXElement: rootID})
+		} else {
+			document.Relationships = append(document.Relationships, SPDXRelationship{SPDXElementID: rootID, RelationshipType: "DEPENDS_ON", RelatedSPDXElement: id})
+		}
+	}
+	for _, c := range components {
+		for _, purl := range c.DependsOn {
+			if id, ok := ids[purl]; ok {
+				document.Relationships = append(document.Relationships, SPDXRelationship{SPDXElementID: ids[c.PURL], RelationshipType: "DEPENDS_ON", RelatedSPDXElement: id})
+			}
+		}
+	}
+
+	document.HasExtractedLicensingInfos = licenses.extracted
+	return document
+}
+
+// uniqueSPDXID suffixes an identifier already used by another package, since
+// distinct names such as a/b_c and a/b-c map to the same identifier
+func uniqueSPDXID(id string, used map[string]bool) string {
+	unique := id
+	for i := 2; used[unique]; i++ {
+		unique = fmt.Sprintf("%s-%d", id, i)
+	}
+	used[unique] = true
+	return unique
+}
+
+// spdxLicenses turns the licenses of packages into SPDX license expressions,
+// the licenses that are not on the SPDX License List becoming LicenseRef-
+// identifiers declared in the document
+type spdxLicenses struct {
+	refs      map[string]string
+	used      map[string]bool
+	extracted []SPDXExtractedLicense
+}
+
+func newSPDXLicenses() *spdxLicenses {
+	return &spdxLicenses{refs: make(map[string]string), used: make(map[string]bool)}
+}
+
+// expression returns the license expression of a package, NOASSERTION when
+// it declares no license
+func (l *spdxLicenses) expression(licenses []string) string {
+	var terms []string
+	for _, license := range licenses {
+		if license = strings.TrimSpace(license); license == "" {
+			continue
+		}
+		if id, ok := notices.LicenseID(license); ok {
+			terms = append(terms, id)
+		} else {
+			terms = append(terms, l.ref(license))
+		}
+	}
+	if len(terms) == 0 {
+		return SPDX_NOASSERTION
+	}
+	return licenseExpression(terms)
+}
+
+// ref returns the LicenseRef- identifier of a license, declaring it the first time
+func (l *spdxLicenses) ref(license string) string {
+	if ref, ok := l.refs[license]; ok {
+		return ref
+	}
+	name := strings.Trim(spdxIDInvalidCharacters.ReplaceAllString(license, "-"), "-")
+	if name == "" {
+		name = "unknown"
+	}
+	base := "LicenseRef-" + name
+	ref := base
+	for i := 2; l.used[ref]; i++ {
+		ref = fmt.Sprintf("%s-%d", base, i)
+	}
+	l.refs[license] = ref
+	l.used[ref] = true
+	l.extracted = append(l.extracted, SPDXExtractedLicense{
+		LicenseID:     ref,
+		Name:          license,
+		ExtractedText: fmt.Sprintf("The license declared by the package as %q, which is not on the SPDX License List", license),
+	})
+	return ref
+}
+
+// spdxID builds an SPDX identifier, which only allows letters, digits, . and -
+func spdxID(name string, version string) string {
+	id := "SPDXRef-Package-" + spdxIDInvalidCharacters.ReplaceAllString(name, "-")
+	if version != "" {
+		id += "-" + spdxIDInvalidCharacters.ReplaceAllString(version, "-")
+	}
+	return strings.TrimSuffix(id, "-")
+}
@@ -0,0 +1,90 @@
+package src
+
+import (
+	"path"
+	"strings"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/policy"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+)
+
+// ExcludePackages removes the packages matching one of the globs, such as acme/*
+func ExcludePackages(output types.Output, patterns []string) types.Output {
+	if len(patterns) == 0 {
+		return output
+	}
+	output.WorkSpaces = filterWorkspaces(output.WorkSpaces, func(name string, dev bool) bool {
+		return !isExcluded(name, patterns)
+	})
+	output.Components = aggregateComponents(output.WorkSpaces)
+	return output
+}
+
+// filterWorkspaces keeps the dependencies, direct dependencies and constraint
+// findings accepted by keep, which is given whether the package is dev only
+func filterWorkspaces(workspaces map[string]types.WorkSpace, keep func(name string, dev bool) bool) map[string]types.WorkSpace {
+	filtered := make(map[string]types.WorkSpace, len(workspaces))
+	for workspaceName, workspace := range workspaces {
+		dependencies := make(map[string]map[string]types.Versions)
+		for name, versions := range workspace.Dependencies {
+			kept := make(map[string]types.Versions)
+			for version, info := range versions {
+				if keep(name, !info.Prod) {
+					kept[version] = info
+				}
+			}
+			if len(kept) > 0 {
+				dependencies[name] = kept
+			}
+		}
+		workspace.Dependencies = dependencies
+
+		var start types.Start
+		start.Dependencies = filterStart(workspace.Start.Dependencies, func(name string) bool { return keep(name, false) })
+		start.DevDependencies = filterStart(workspace.Start.DevDependencies, func(name string) bool { return keep(name, true) })
+		workspace.Start = start
+
+		findings := []types.ConstraintFinding{}
+		for _, finding := range workspace.ConstraintFindings {
+			if keep(finding.Dependency, finding.Dev) {
+				findings = append(findings, finding)
+			}
+		}
+		workspace.ConstraintFindings = findings
+
+		filtered[workspaceName] = workspace
+	}
+	return filtered
+}
+
+func filterStart(dependencies []types.WorkSpaceDependency, keep func(string) bool) []types.WorkSpaceDependency {
+	kept := []types.WorkSpaceDependency{}
+	for _, dependency := range dependencies {
+		if keep(dependency.Name) {
+			kept = append(kept, dependency)
+		}
+	}
+	return kept
+}
+
+func isExcluded(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(strings.ToLower(pattern), strings.ToLower(name)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluatePolicy reports the violations of the analysis policy
+func evaluatePolicy(analysisPolicy policy.Policy, output types.Output) []types.PolicyViolation {
+	var violations []types.PolicyViolation
+	for _, violation := range analysisPolicy.Evaluate(output) {
+		violations = append(violations, types.PolicyViolation{
+			Rule:    violation.Rule,
+			Package: violation.Package,
+			Message: violation.Message,
+		})
+	}
+	return violations
+}
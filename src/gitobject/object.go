@@ -0,0 +1,402 @@
+package gitobject
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ObjectType is the type of a git object
+type ObjectType int
+
+const (
+	OBJECT_COMMIT ObjectType = 1
+	OBJECT_TREE   ObjectType = 2
+	OBJECT_BLOB   ObjectType = 3
+	OBJECT_TAG    ObjectType = 4
+)
+
+// maxCachedObjects bounds the number of decoded objects kept in memory
+const maxCachedObjects = 4096
+
+// String returns the name git uses for the type
+func (t ObjectType) String() string {
+	switch t {
+	case OBJECT_COMMIT:
+		return "commit"
+	case OBJECT_TREE:
+		return "tree"
+	case OBJECT_BLOB:
+		return "blob"
+	case OBJECT_TAG:
+		return "tag"
+	default:
+		return "unknown"
+	}
+}
+
+func parseObjectType(name string) (ObjectType, error) {
+	switch name {
+	case "commit":
+		return OBJECT_COMMIT, nil
+	case "tree":
+		return OBJECT_TREE, nil
+	case "blob":
+		return OBJECT_BLOB, nil
+	case "tag":
+		return OBJECT_TAG, nil
+	default:
+		return 0, fmt.Errorf("unknown object type %q", name)
+	}
+}
+
+type object struct {
+	typ  ObjectType
+	data []byte
+}
+
+// Object returns the type and content of an object
+func (r *Repository) Object(h Hash) (ObjectType, []byte, error) {
+	r.cacheMu.Lock()
+	cached, ok := r.cache[h]
+	r.cacheMu.Unlock()
+	if ok {
+		return cached.typ, cached.data, nil
+	}
+
+	obj, err := r.readLoose(h)
+	if err == ErrNotFound {
+		obj, err = r.readPacked(h)
+	}
+	if err != nil {
+		if err == ErrNotFound {
+			return 0, nil, fmt.Errorf("object %s: %w", h, ErrNotFound)
+		}
+		return 0, nil, err
+	}
+
+	r.cacheMu.Lock()
+	if len(r.cache) >= maxCachedObjects {
+		r.cache = make(map[Hash]object)
+	}
+	r.cache[h] = obj
+	r.cacheMu.Unlock()
+	return obj.typ, obj.data, nil
+}
+
+// readLoose reads a zlib compressed object from objects/xx/yyyy
+func (r *Repository) readLoose(h Hash) (object, error) {
+	name := h.String()
+	for _, dir := range r.objectDirs {
+		file, err := os.Open(filepath.Join(dir, name[:2], name[2:]))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return object{}, err
+		}
+		defer file.Close()
+
+		reader, err := zlib.NewReader(file)
+		if err != nil {
+			return object{}, fmt.Errorf("corrupt loose object %s: %w", name, err)
+		}
+		defer reader.Close()
+		// The header is "<type> <size>\x00"
+		data, err := io.ReadAll(io.LimitReader(reader, maxObjectSize+32))
+		if err != nil {
+			return object{}, fmt.Errorf("corrupt loose object %s: %w", name, err)
+		}
+
+		header, content, ok := bytes.Cut(data, []byte{0})
+		if !ok {
+			return object{}, fmt.Errorf("corrupt loose object %s: missing header", name)
+		}
+		typeName, size, _ := strings.Cut(string(header), " ")
+		typ, err := parseObjectType(typeName)
+		if err != nil {
+			return object{}, fmt.Errorf("corrupt loose object %s: %w", name, err)
+		}
+		if expected, err := strconv.Atoi(size); err != nil || expected != len(content) {
+			return object{}, fmt.Errorf("corrupt loose object %s: size mismatch", name)
+		}
+		return object{typ: typ, data: content}, nil
+	}
+	return object{}, ErrNotFound
+}
+
+// Signature is the author or committer of a commit
+type Signature struct {
+	Name  string
+	Email string
+	When  time.Time
+}
+
+// Commit is a parsed commit object
+type Commit struct {
+	Hash      Hash
+	Tree      Hash
+	Parents   []Hash
+	Author    Signature
+	Committer Signature
+	Message   string
+}
+
+// Summary returns the first line of the commit message
+func (c *Commit) Summary() string {
+	summary, _, _ := strings.Cut(strings.TrimSpace(c.Message), "\n")
+	return summary
+}
+
+// Commit reads a commit, peeling annotated tags
+func (r *Repository) Commit(h Hash) (*Commit, error) {
+	h, err := r.peel(h, OBJECT_COMMIT)
+	if err != nil {
+		return nil, err
+	}
+	_, data, err := r.Object(h)
+	if err != nil {
+		return nil, err
+	}
+
+	commit := &Commit{Hash: h}
+	headers, message, _ := bytes.Cut(data, []byte("\n\n"))
+	commit.Message = string(message)
+	for _, line := range strings.Split(string(headers), "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "tree":
+			if commit.Tree, err = ParseHash(value); err != nil {
+				return nil, err
+			}
+		case "parent":
+			parent, err := ParseHash(value)
+			if err != nil {
+				return nil, err
+			}
+			commit.Parents = append(commit.Parents, parent)
+		case "author":
+			commit.Author = parseSignature(value)
+		case "committer":
+			commit.Committer = parseSignature(value)
+		}
+	}
+	if commit.Tree.IsZero() {
+		return nil, fmt.Errorf("commit %s has no tree", h)
+	}
+	return commit, nil
+}
+
+// parseSignature parses "Name <email> 1700000000 +0100"
+func parseSignature(value string) Signature {
+	var signature Signature
+	start, end := strings.Index(value, "<"), strings.LastIndex(value, ">")
+	if start < 0 || end < start {
+		signature.Name = value
+		return signature
+	}
+	signature.Name = strings.TrimSpace(value[:start])
+	signature.Email = value[start+1 : end]
+
+	fields := strings.Fields(value[end+1:])
+	if len(fields) == 0 {
+		return signature
+	}
+	seconds, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return signature
+	}
+	location := time.UTC
+	if len(fields) > 1 && len(fields[1]) == 5 {
+		hours, _ := strconv.Atoi(fields[1][1:3])
+		minutes, _ := strconv.Atoi(fields[1][3:5])
+		offset := hours*3600 + minutes*60
+		if fields[1][0] == '-' {
+			offset = -offset
+		}
+		location = time.FixedZone(fields[1], offset)
+	}
+	signature.When = time.Unix(seconds, 0).In(location)
+	return signature
+}
+
+// peel follows annotated tags until an object of the wanted type
+func (r *Repository) peel(h Hash, want ObjectType) (Hash, error) {
+	for depth := 0; depth < 16; depth++ {
+		typ, data, err := r.Object(h)
+		if err != nil {
+			return h, err
+		}
+		if typ == want {
+			return h, nil
+		}
+		if typ != OBJECT_TAG {
+			return h, fmt.Errorf("object %s is a %s, not a %s", h, typ, want)
+		}
+		target, _, _ := strings.Cut(string(data), "\n")
+		name, ok := strings.CutPrefix(target, "object ")
+		if !ok {
+			return h, fmt.Errorf("corrupt tag %s", h)
+		}
+		if h, err = ParseHash(name); err != nil {
+			return h, err
+		}
+	}
+	return h, fmt.Errorf("too many nested tags")
+}
+
+// TreeEntry is an entry of a tree object
+type TreeEntry struct {
+	Mode uint32
+	Name string
+	Hash Hash
+}
+
+// Tree entry modes
+const (
+	MODE_DIRECTORY  = 0o040000
+	MODE_SYMLINK    = 0o120000
+	MODE_SUBMODULE  = 0o160000
+	modeTypeMask    = 0o170000
+	modeRegularFile = 0o100000
+)
+
+// IsDir reports whether the entry is a subtree
+func (e TreeEntry) IsDir() bool {
+	return e.Mode&modeTypeMask == MODE_DIRECTORY
+}
+
+// IsFile reports whether the entry is a regular file
+func (e TreeEntry) IsFile() bool {
+	return e.Mode&modeTypeMask == modeRegularFile
+}
+
+// Tree reads the entries of a tree, or of the tree of a commit
+func (r *Repository) Tree(h Hash) ([]TreeEntry, error) {
+	typ, data, err := r.Object(h)
+	if err != nil {
+		return nil, err
+	}
+	if typ == OBJECT_COMMIT || typ == OBJECT_TAG {
+		commit, err := r.Commit(h)
+		if err != nil {
+			return nil, err
+		}
+		return r.Tree(commit.Tree)
+	}
+	if typ != OBJECT_TREE {
+		return nil, fmt.Errorf("object %s is a %s, not a tree", h, typ)
+	}
+
+	var entries []TreeEntry
+	for len(data) > 0 {
+		header, rest, ok := bytes.Cut(data, []byte{0})
+		if !ok || len(rest) < HASH_SIZE {
+			return nil, fmt.Errorf("corrupt tree %s", h)
+		}
+		mode, name, _ := strings.Cut(string(header), " ")
+		parsedMode, err := strconv.ParseUint(mode, 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("corrupt tree %s: %w", h, err)
+		}
+		if !validEntryName(name) {
+			return nil, fmt.Errorf("corrupt tree %s: invalid entry name %q", h, name)
+		}
+		entry := TreeEntry{Mode: uint32(parsedMode), Name: name}
+		copy(entry.Hash[:], rest[:HASH_SIZE])
+		entries = append(entries, entry)
+		data = rest[HASH_SIZE:]
+	}
+	return entries, nil
+}
+
+// validEntryName reports whether a tree entry name is a single path element,
+// so that a crafted tree cannot point outside of the directory it is read into
+func validEntryName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, "/\x00")
+}
+
+// Entry returns the tree entry at path in a commit or tree
+func (r *Repository) Entry(h Hash, path string) (TreeEntry, error) {
+	entries, err := r.Tree(h)
+	if err != nil {
+		return TreeEntry{}, err
+	}
+	parts := strings.Split(strings.Trim(filepath.ToSlash(path), "/"), "/")
+	for i, part := range parts {
+		var found *TreeEntry
+		for j := range entries {
+			if entries[j].Name == part {
+				found = &entries[j]
+				break
+			}
+		}
+		if found == nil {
+			return TreeEntry{}, fmt.Errorf("%s: %w", path, ErrNotFound)
+		}
+		if i == len(parts)-1 {
+			return *found, nil
+		}
+		if !found.IsDir() {
+			return TreeEntry{}, fmt.Errorf("%s: %w", path, ErrNotFound)
+		}
+		if entries, err = r.Tree(found.Hash); err != nil {
+			return TreeEntry{}, err
+		}
+	}
+	return TreeEntry{}, fmt.Errorf("%s: %w", path, ErrNotFound)
+}
+
+// File reads the content of the file at path in a commit or tree
+func (r *Repository) File(h Hash, path string) ([]byte, error) {
+	entry, err := r.Entry(h, path)
+	if err != nil {
+		return nil, err
+	}
+	if !entry.IsFile() {
+		return nil, fmt.Errorf("%s is not a file", path)
+	}
+	_, data, err := r.Object(entry.Hash)
+	return data, err
+}
+
+// SkipDir can be returned by a WalkFunc to skip a directory
+var SkipDir = errors.New("skip this directory")
+
+// WalkFunc is called for every entry of a tree walk with its slash separated path
+type WalkFunc func(path string, entry TreeEntry) error
+
+// Walk walks the tree of a commit or tree depth first, in tree order
+func (r *Repository) Walk(h Hash, fn WalkFunc) error {
+	return r.walk(h, "", fn)
+}
+
+func (r *Repository) walk(h Hash, prefix string, fn WalkFunc) error {
+	entries, err := r.Tree(h)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		path := prefix + entry.Name
+		err := fn(path, entry)
+		if err == SkipDir {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if err := r.walk(entry.Hash, path+"/", fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
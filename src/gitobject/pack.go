@@ -0,0 +1,336 @@
+package gitobject
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Packed object types besides the base object types
+const (
+	packOffsetDelta = 6
+	packRefDelta    = 7
+)
+
+// maxDeltaChain bounds the length of the delta chains followed
+const maxDeltaChain = 10000
+
+// maxObjectSize bounds the size of the objects read, as sizes come from the
+// repository: manifests, trees and commits are far smaller
+const maxObjectSize = 256 << 20
+
+// packFile is a packfile and its version 2 index
+type packFile struct {
+	path    string
+	hashes  []Hash
+	offsets []int64
+}
+
+// readPacked reads an object from the packfiles of the repository
+func (r *Repository) readPacked(h Hash) (object, error) {
+	packs, err := r.loadPacks()
+	if err != nil {
+		return object{}, err
+	}
+	for _, pack := range packs {
+		if offset, ok := pack.find(h); ok {
+			return r.readPackObject(pack, offset, 0)
+		}
+	}
+	return object{}, ErrNotFound
+}
+
+func (r *Repository) loadPacks() ([]*packFile, error) {
+	r.packsOnce.Do(func() {
+		for _, dir := range r.objectDirs {
+			indexes, _ := filepath.Glob(filepath.Join(dir, "pack", "pack-*.idx"))
+			for _, index := range indexes {
+				pack, err := readPackIndex(index)
+				if err != nil {
+					r.packsErr = err
+					return
+				}
+				r.packs = append(r.packs, pack)
+			}
+		}
+	})
+	return r.packs, r.packsErr
+}
+
+// readPackIndex reads a version 2 pack index
+func readPackIndex(indexPath string) (*packFile, error) {
+	data, err := os.ReadFile(indexPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 8+256*4 || !bytes.Equal(data[:4], []byte{0xff, 't', 'O', 'c'}) || binary.BigEndian.Uint32(data[4:8]) != 2 {
+		return nil, fmt.Errorf("unsupported pack index %s", indexPath)
+	}
+
+	count := int(binary.BigEndian.Uint32(data[8+255*4:]))
+	hashesStart := 8 + 256*4
+	offsetsStart := hashesStart + count*HASH_SIZE + count*4
+	largeStart := offsetsStart + count*4
+	if len(data) < largeStart {
+		return nil, fmt.Errorf("truncated pack index %s", indexPath)
+	}
+
+	pack := &packFile{
+		path:    strings.TrimSuffix(indexPath, ".idx") + ".pack",
+		hashes:  make([]Hash, count),
+		offsets: make([]int64, count),
+	}
+	for i := 0; i < count; i++ {
+		copy(pack.hashes[i][:], data[hashesStart+i*HASH_SIZE:])
+		offset := binary.BigEndian.Uint32(data[offsetsStart+i*4:])
+		if offset&0x80000000 == 0 {
+			pack.offsets[i] = int64(offset)
+			continue
+		}
+		large := largeStart + int(offset&0x7fffffff)*8
+		if len(data) < large+8 {
+			return nil, fmt.Errorf("truncated pack index %s", indexPath)
+		}
+		pack.offsets[i] = int64(binary.BigEndian.Uint64(data[large:]))
+	}
+	return pack, nil
+}
+
+// find returns the offset of an object in the pack
+func (p *packFile) find(h Hash) (int64, bool) {
+	i := sort.Search(len(p.hashes), func(i int) bool {
+		return bytes.Compare(p.hashes[i][:], h[:]) >= 0
+	})
+	if i < len(p.hashes) && p.hashes[i] == h {
+		return p.offsets[i], true
+	}
+	return 0, false
+}
+
+// withPrefix lists the objects of the pack whose name starts with prefix
+func (p *packFile) withPrefix(prefix string) []Hash {
+	var matches []Hash
+	start := sort.Search(len(p.hashes), func(i int) bool {
+		return p.hashes[i].String() >= prefix
+	})
+	for i := start; i < len(p.hashes) && strings.HasPrefix(p.hashes[i].String(), prefix); i++ {
+		matches = append(matches, p.hashes[i])
+	}
+	return matches
+}
+
+// baseKey identifies a packed object by position, as delta bases are found
+type baseKey struct {
+	pack   *packFile
+	offset int64
+}
+
+// readPackObject reads the object at offset, resolving deltas. Delta bases
+// are cached since they are shared by many objects.
+func (r *Repository) readPackObject(pack *packFile, offset int64, depth int) (object, error) {
+	if depth > 0 {
+		r.cacheMu.Lock()
+		cached, ok := r.bases[baseKey{pack, offset}]
+		r.cacheMu.Unlock()
+		if ok {
+			return cached, nil
+		}
+		obj, err := r.inflatePackObject(pack, offset, depth)
+		if err == nil {
+			r.cacheMu.Lock()
+			if len(r.bases) >= maxCachedObjects {
+				r.bases = make(map[baseKey]object)
+			}
+			r.bases[baseKey{pack, offset}] = obj
+			r.cacheMu.Unlock()
+		}
+		return obj, err
+	}
+	return r.inflatePackObject(pack, offset, depth)
+}
+
+func (r *Repository) inflatePackObject(pack *packFile, offset int64, depth int) (object, error) {
+	if depth > maxDeltaChain {
+		return object{}, fmt.Errorf("delta chain too long in %s", pack.path)
+	}
+
+	file, err := os.Open(pack.path)
+	if err != nil {
+		return object{}, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(io.NewSectionReader(file, offset, 1<<62))
+	typ, size, err := readPackHeader(reader)
+	if err != nil {
+		return object{}, fmt.Errorf("corrupt pack %s at %d: %w", pack.path, offset, err)
+	}
+
+	var base object
+	switch typ {
+	case packOffsetDelta:
+		distance, err := readOffsetDistance(reader)
+		if err != nil || distance <= 0 || distance > offset {
+			return object{}, fmt.Errorf("corrupt delta in %s at %d", pack.path, offset)
+		}
+		if base, err = r.readPackObject(pack, offset-distance, depth+1); err != nil {
+			return object{}, err
+		}
+	case packRefDelta:
+		var baseHash Hash
+		if _, err := io.ReadFull(reader, baseHash[:]); err != nil {
+			return object{}, fmt.Errorf("corrupt delta in %s at %d", pack.path, offset)
+		}
+		if baseOffset, ok := pack.find(baseHash); ok {
+			base, err = r.readPackObject(pack, baseOffset, depth+1)
+		} else {
+			base.typ, base.data, err = r.Object(baseHash)
+		}
+		if err != nil {
+			return object{}, err
+		}
+	case int(OBJECT_COMMIT), int(OBJECT_TREE), int(OBJECT_BLOB), int(OBJECT_TAG):
+	default:
+		return object{}, fmt.Errorf("unknown object type %d in %s at %d", typ, pack.path, offset)
+	}
+
+	data, err := inflate(reader, size)
+	if err != nil {
+		return object{}, fmt.Errorf("corrupt pack %s at %d: %w", pack.path, offset, err)
+	}
+	if typ != packOffsetDelta && typ != packRefDelta {
+		return object{typ: ObjectType(typ), data: data}, nil
+	}
+
+	patched, err := applyDelta(base.data, data)
+	if err != nil {
+		return object{}, fmt.Errorf("corrupt delta in %s at %d: %w", pack.path, offset, err)
+	}
+	return object{typ: base.typ, data: patched}, nil
+}
+
+// readPackHeader reads the type and inflated size of a packed object
+func readPackHeader(reader io.ByteReader) (int, int64, error) {
+	b, err := reader.ReadByte()
+	if err != nil {
+		return 0, 0, err
+	}
+	typ := int(b>>4) & 0x7
+	size := int64(b & 0x0f)
+	shift := uint(4)
+	for b&0x80 != 0 {
+		if b, err = reader.ReadByte(); err != nil {
+			return 0, 0, err
+		}
+		size |= int64(b&0x7f) << shift
+		shift += 7
+	}
+	return typ, size, nil
+}
+
+// readOffsetDistance reads the distance to the base of an offset delta
+func readOffsetDistance(reader io.ByteReader) (int64, error) {
+	b, err := reader.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	distance := int64(b & 0x7f)
+	for b&0x80 != 0 {
+		if b, err = reader.ReadByte(); err != nil {
+			return 0, err
+		}
+		distance = ((distance + 1) << 7) | int64(b&0x7f)
+	}
+	return distance, nil
+}
+
+func inflate(reader io.Reader, size int64) ([]byte, error) {
+	inflater, err := zlib.NewReader(reader)
+	if err != nil {
+		return nil, err
+	}
+	defer inflater.Close()
+	if size < 0 || size > maxObjectSize {
+		return nil, fmt.Errorf("object size %d out of range", size)
+	}
+	data, err := io.ReadAll(io.LimitReader(inflater, size+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) != size {
+		return nil, fmt.Errorf("inflated size mismatch")
+	}
+	return data, nil
+}
+
+// applyDelta rebuilds an object from its base and a git delta
+func applyDelta(base []byte, delta []byte) ([]byte, error) {
+	reader := bytes.NewReader(delta)
+	baseSize, err := binary.ReadUvarint(reader)
+	if err != nil || baseSize != uint64(len(base)) {
+		return nil, fmt.Errorf("base size mismatch")
+	}
+	resultSize, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return nil, err
+	}
+	if resultSize > maxObjectSize {
+		return nil, fmt.Errorf("result size %d out of range", resultSize)
+	}
+
+	result := make([]byte, 0, resultSize)
+	for reader.Len() > 0 {
+		if uint64(len(result)) > resultSize {
+			return nil, fmt.Errorf("result size mismatch")
+		}
+		command, _ := reader.ReadByte()
+		switch {
+		case command&0x80 != 0:
+			var offset, size uint64
+			for i := uint(0); i < 4; i++ {
+				if command&(1<<i) != 0 {
+					b, err := reader.ReadByte()
+					if err != nil {
+						return nil, err
+					}
+					offset |= uint64(b) << (8 * i)
+				}
+			}
+			for i := uint(0); i < 3; i++ {
+				if command&(0x10<<i) != 0 {
+					b, err := reader.ReadByte()
+					if err != nil {
+						return nil, err
+					}
+					size |= uint64(b) << (8 * i)
+				}
+			}
+			if size == 0 {
+				size = 0x10000
+			}
+			if offset+size > uint64(len(base)) {
+				return nil, fmt.Errorf("copy outside of the base")
+			}
+			result = append(result, base[offset:offset+size]...)
+		case command != 0:
+			start := len(result)
+			result = append(result, make([]byte, command)...)
+			if _, err := io.ReadFull(reader, result[start:]); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("reserved delta command")
+		}
+	}
+	if uint64(len(result)) != resultSize {
+		return nil, fmt.Errorf("result size mismatch")
+	}
+	return result, nil
+}
@@ -0,0 +1,266 @@
+package gitobject
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxSymbolicDepth bounds the symbolic reference chains followed
+const maxSymbolicDepth = 10
+
+// revisionSuffix matches the ~N and ^N ancestry suffixes of a revision
+var revisionSuffix = regexp.MustCompile(`([~^])(\d*)$`)
+
+var hexPattern = regexp.MustCompile(`^[0-9a-fA-F]{4,40}$`)
+
+// ResolveRevision resolves a revision to a commit. It accepts full and
+// abbreviated object names, HEAD, branch, tag and remote names, full
+// reference names, and the ~N and ^N ancestry suffixes, e.g. main~2.
+func (r *Repository) ResolveRevision(revision string) (Hash, error) {
+	revision = strings.TrimSpace(revision)
+	if revision == "" {
+		return Hash{}, fmt.Errorf("empty revision")
+	}
+
+	if matches := revisionSuffix.FindStringSubmatchIndex(revision); matches != nil && matches[0] > 0 {
+		base, err := r.ResolveRevision(revision[:matches[0]])
+		if err != nil {
+			return Hash{}, err
+		}
+		operator := revision[matches[2]:matches[3]]
+		count := 1
+		if digits := revision[matches[4]:matches[5]]; digits != "" {
+			count, _ = strconv.Atoi(digits)
+		}
+		return r.ancestor(base, operator, count)
+	}
+
+	if h, err := r.resolveName(revision); err == nil {
+		return r.peel(h, OBJECT_COMMIT)
+	}
+	if hexPattern.MatchString(revision) {
+		h, err := r.resolvePrefix(strings.ToLower(revision))
+		if err != nil {
+			return Hash{}, err
+		}
+		return r.peel(h, OBJECT_COMMIT)
+	}
+	return Hash{}, fmt.Errorf("revision %s: %w", revision, ErrNotFound)
+}
+
+// ancestor follows the first parent count times (~N) or picks the Nth parent (^N)
+func (r *Repository) ancestor(h Hash, operator string, count int) (Hash, error) {
+	if operator == "^" {
+		if count == 0 {
+			return h, nil
+		}
+		commit, err := r.Commit(h)
+		if err != nil {
+			return Hash{}, err
+		}
+		if count > len(commit.Parents) {
+			return Hash{}, fmt.Errorf("commit %s has no parent %d: %w", h, count, ErrNotFound)
+		}
+		return commit.Parents[count-1], nil
+	}
+
+	for i := 0; i < count; i++ {
+		commit, err := r.Commit(h)
+		if err != nil {
+			return Hash{}, err
+		}
+		if len(commit.Parents) == 0 {
+			return Hash{}, fmt.Errorf("commit %s has no parent: %w", h, ErrNotFound)
+		}
+		h = commit.Parents[0]
+	}
+	return h, nil
+}
+
+// resolveName resolves a reference name the way git rev-parse looks them up
+func (r *Repository) resolveName(name string) (Hash, error) {
+	if len(name) == 2*HASH_SIZE {
+		if h, err := ParseHash(name); err == nil {
+			return h, nil
+		}
+	}
+	candidates := []string{
+		name,
+		"refs/" + name,
+		"refs/tags/" + name,
+		"refs/heads/" + name,
+		"refs/remotes/" + name,
+		"refs/remotes/" + name + "/HEAD",
+	}
+	for _, candidate := range candidates {
+		if h, err := r.ResolveReference(candidate); err == nil {
+			return h, nil
+		}
+	}
+	return Hash{}, ErrNotFound
+}
+
+// ResolveReference resolves a full reference name such as HEAD or
+// refs/heads/main, following symbolic references
+func (r *Repository) ResolveReference(name string) (Hash, error) {
+	for depth := 0; depth < maxSymbolicDepth; depth++ {
+		value, err := r.readReference(name)
+		if err != nil {
+			return Hash{}, err
+		}
+		target, symbolic := strings.CutPrefix(value, "ref:")
+		if !symbolic {
+			return ParseHash(value)
+		}
+		name = strings.TrimSpace(target)
+	}
+	return Hash{}, fmt.Errorf("reference %s: too many symbolic references", name)
+}
+
+// Head returns the reference HEAD points to, empty when detached
+func (r *Repository) Head() (string, error) {
+	value, err := r.readReference("HEAD")
+	if err != nil {
+		return "", err
+	}
+	if target, symbolic := strings.CutPrefix(value, "ref:"); symbolic {
+		return strings.TrimSpace(target), nil
+	}
+	return "", nil
+}
+
+// readReference reads a loose reference, then the packed references
+func (r *Repository) readReference(name string) (string, error) {
+	if strings.Contains(name, "..") || strings.HasPrefix(name, "/") {
+		return "", fmt.Errorf("invalid reference name %q", name)
+	}
+
+	// HEAD and the other pseudo references live in the worktree git directory
+	dirs := []string{r.commonDir}
+	if !strings.HasPrefix(name, "refs/") || strings.HasPrefix(name, "refs/bisect/") || strings.HasPrefix(name, "refs/worktree/") {
+		dirs = []string{r.gitDir, r.commonDir}
+	}
+	for _, dir := range dirs {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err == nil {
+			return strings.TrimSpace(string(data)), nil
+		}
+	}
+
+	packed, err := r.packedReferences()
+	if err != nil {
+		return "", err
+	}
+	if h, ok := packed[name]; ok {
+		return h, nil
+	}
+	return "", fmt.Errorf("reference %s: %w", name, ErrNotFound)
+}
+
+// packedReferences reads packed-refs, mapping reference names to object names
+func (r *Repository) packedReferences() (map[string]string, error) {
+	references := make(map[string]string)
+	file, err := os.Open(filepath.Join(r.commonDir, "packed-refs"))
+	if os.IsNotExist(err) {
+		return references, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		// Comments and the peeled values of annotated tags
+		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "^") {
+			continue
+		}
+		h, name, ok := strings.Cut(line, " ")
+		if ok {
+			references[strings.TrimSpace(name)] = h
+		}
+	}
+	return references, scanner.Err()
+}
+
+// References lists the branches, tags and remote branches of the repository
+func (r *Repository) References() (map[string]Hash, error) {
+	references := make(map[string]Hash)
+	packed, err := r.packedReferences()
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range packed {
+		if h, err := ParseHash(value); err == nil {
+			references[name] = h
+		}
+	}
+
+	root := filepath.Join(r.commonDir, "refs")
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		relative, err := filepath.Rel(r.commonDir, path)
+		if err != nil {
+			return nil
+		}
+		name := filepath.ToSlash(relative)
+		if h, err := r.ResolveReference(name); err == nil {
+			references[name] = h
+		}
+		return nil
+	})
+	return references, err
+}
+
+// resolvePrefix resolves an abbreviated object name
+func (r *Repository) resolvePrefix(prefix string) (Hash, error) {
+	if len(prefix) == 2*HASH_SIZE {
+		return ParseHash(prefix)
+	}
+
+	matches := make(map[Hash]bool)
+	for _, dir := range r.objectDirs {
+		entries, err := os.ReadDir(filepath.Join(dir, prefix[:2]))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name := prefix[:2] + entry.Name()
+			if strings.HasPrefix(name, prefix) && len(name) == 2*HASH_SIZE {
+				var h Hash
+				if _, err := hex.Decode(h[:], []byte(name)); err == nil {
+					matches[h] = true
+				}
+			}
+		}
+	}
+
+	packs, err := r.loadPacks()
+	if err != nil {
+		return Hash{}, err
+	}
+	for _, pack := range packs {
+		for _, h := range pack.withPrefix(prefix) {
+			matches[h] = true
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return Hash{}, fmt.Errorf("object %s: %w", prefix, ErrNotFound)
+	case 1:
+		for h := range matches {
+			return h, nil
+		}
+	}
+	return Hash{}, fmt.Errorf("object name %s is ambiguous", prefix)
+}
@@ -0,0 +1,221 @@
+// Package gitobject reads objects and references straight from a local .git
+// directory: loose objects, packfiles with their deltas, loose and packed refs.
+// It never runs git and never touches the network.
+package gitobject
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// HASH_SIZE is the size of a SHA-1 object name; SHA-256 repositories are not supported
+const HASH_SIZE = 20
+
+// ErrNotFound is returned for objects, references and paths that do not exist
+var ErrNotFound = errors.New("not found")
+
+// Hash is the name of an object
+type Hash [HASH_SIZE]byte
+
+// String returns the hexadecimal form of the hash
+func (h Hash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+// IsZero reports whether the hash is unset
+func (h Hash) IsZero() bool {
+	return h == Hash{}
+}
+
+// ParseHash parses a full hexadecimal object name
+func ParseHash(s string) (Hash, error) {
+	var h Hash
+	if len(s) != 2*HASH_SIZE {
+		return h, fmt.Errorf("invalid object name %q", s)
+	}
+	if _, err := hex.Decode(h[:], []byte(s)); err != nil {
+		return h, fmt.Errorf("invalid object name %q", s)
+	}
+	return h, nil
+}
+
+// Repository is a local git repository opened for reading
+type Repository struct {
+	// gitDir holds HEAD and the per-worktree refs
+	gitDir string
+	// commonDir holds the objects and the shared refs; it differs from gitDir in linked worktrees
+	commonDir  string
+	workTree   string
+	objectDirs []string
+
+	packsOnce sync.Once
+	packs     []*packFile
+	packsErr  error
+
+	cacheMu sync.Mutex
+	cache   map[Hash]object
+	bases   map[baseKey]object
+
+	shallowOnce sync.Once
+	shallow     map[Hash]bool
+}
+
+// Open opens the repository containing dir. dir may be a working tree or one
+// of its subdirectories, a .git directory, a bare repository or a linked worktree.
+func Open(dir string) (*Repository, error) {
+	gitDir, workTree, err := findGitDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	commonDir := gitDir
+	if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		commonDir = strings.TrimSpace(string(data))
+		if !filepath.IsAbs(commonDir) {
+			commonDir = filepath.Join(gitDir, commonDir)
+		}
+	}
+
+	repository := &Repository{
+		gitDir:    gitDir,
+		commonDir: commonDir,
+		workTree:  workTree,
+		cache:     make(map[Hash]object),
+		bases:     make(map[baseKey]object),
+	}
+	repository.objectDirs = append([]string{filepath.Join(commonDir, "objects")}, readAlternates(filepath.Join(commonDir, "objects"))...)
+	return repository, nil
+}
+
+// WorkTree returns the root of the working tree, empty for bare repositories
+func (r *Repository) WorkTree() string {
+	return r.workTree
+}
+
+// IsShallow reports whether a commit is a boundary of a shallow clone, listed
+// in the shallow file: its parents are recorded but their objects are missing
+func (r *Repository) IsShallow(h Hash) bool {
+	r.shallowOnce.Do(func() {
+		r.shallow = make(map[Hash]bool)
+		data, err := os.ReadFile(filepath.Join(r.commonDir, "shallow"))
+		if err != nil {
+			return
+		}
+		for _, line := range strings.Fields(string(data)) {
+			if hash, err := ParseHash(line); err == nil {
+				r.shallow[hash] = true
+			}
+		}
+	})
+	return r.shallow[h]
+}
+
+// RelativePath returns the slash separated path of dir inside the working
+// tree, empty for the root of the working tree or for bare repositories
+func (r *Repository) RelativePath(dir string) (string, error) {
+	if r.workTree == "" {
+		return "", nil
+	}
+	absolute, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	relative, err := filepath.Rel(r.workTree, absolute)
+	if err != nil {
+		return "", err
+	}
+	if relative == "." {
+		return "", nil
+	}
+	if relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside of the working tree %s", dir, r.workTree)
+	}
+	return filepath.ToSlash(relative), nil
+}
+
+// findGitDir locates the git directory of dir and its working tree, looking
+// in the parent directories and following .git files
+func findGitDir(dir string) (string, string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", "", err
+	}
+	if isGitDir(dir) {
+		return dir, "", nil
+	}
+
+	for current := dir; ; current = filepath.Dir(current) {
+		dotGit := filepath.Join(current, ".git")
+		stat, err := os.Stat(dotGit)
+		if err == nil && stat.IsDir() && isGitDir(dotGit) {
+			return dotGit, current, nil
+		}
+		if err == nil && !stat.IsDir() {
+			// Linked worktrees and submodules use a file pointing to the git directory
+			gitDir, err := readGitFile(current, dotGit)
+			return gitDir, current, err
+		}
+		if filepath.Dir(current) == current {
+			return "", "", fmt.Errorf("no git repository in %s: %w", dir, ErrNotFound)
+		}
+	}
+}
+
+func readGitFile(dir string, dotGit string) (string, error) {
+	data, err := os.ReadFile(dotGit)
+	if err != nil {
+		return "", err
+	}
+	target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return "", fmt.Errorf("invalid .git file in %s", dir)
+	}
+	target = strings.TrimSpace(target)
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(dir, target)
+	}
+	if !isGitDir(target) {
+		return "", fmt.Errorf("invalid git directory %s", target)
+	}
+	return target, nil
+}
+
+func isGitDir(dir string) bool {
+	if _, err := os.Stat(filepath.Join(dir, "HEAD")); err != nil {
+		return false
+	}
+	if _, err := os.Stat(filepath.Join(dir, "objects")); err == nil {
+		return true
+	}
+	_, err := os.Stat(filepath.Join(dir, "commondir"))
+	return err == nil
+}
+
+// readAlternates lists the object directories borrowed from other repositories
+func readAlternates(objectDir string) []string {
+	file, err := os.Open(filepath.Join(objectDir, "info", "alternates"))
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var dirs []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !filepath.IsAbs(line) {
+			line = filepath.Join(objectDir, line)
+		}
+		dirs = append(dirs, line)
+	}
+	return dirs
+}
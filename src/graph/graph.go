@@ -0,0 +1,250 @@
+package graph
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/parser"
+)
+
+// Node represents a locked package in the dependency graph
+type Node struct {
+	Name    string
+	Version string
+	// Requires contains the normalized names of the locked packages this package depends on
+	Requires []string
+	// LockedAsDev is true when the package is listed in packages-dev
+	LockedAsDev bool
+	// Replaces and Provides contain the normalized names this package stands in for
+	Replaces []string
+	Provides []string
+
+	// Direct is true when the root requires the package in require
+	Direct bool
+	// DirectDev is true when the root requires the package in require-dev
+	DirectDev bool
+	// RequiredByPackage is true when at least one reachable package requires it
+	RequiredByPackage bool
+	// ProdReachable is true when the package is reachable from the root require edges
+	ProdReachable bool
+	// DevReachable is true when the package is reachable from the root require-dev edges
+	DevReachable bool
+	// ProdDepth and DevDepth are the shortest distances from the root, 1 being a
+	// direct dependency and 0 meaning unreachable
+	ProdDepth int
+	DevDepth  int
+}
+
+// Reachable reports whether the package can be reached from the root
+func (n *Node) Reachable() bool {
+	return n.ProdReachable || n.DevReachable
+}
+
+// DevOnly reports whether the package is only needed for development
+func (n *Node) DevOnly() bool {
+	return n.DevReachable && !n.ProdReachable
+}
+
+// Depth returns the shortest distance from the root over prod and dev edges
+func (n *Node) Depth() int {
+	switch {
+	case n.ProdDepth == 0:
+		return n.DevDepth
+	case n.DevDepth == 0:
+		return n.ProdDepth
+	case n.ProdDepth < n.DevDepth:
+		return n.ProdDepth
+	default:
+		return n.DevDepth
+	}
+}
+
+// Graph is the dependency graph of a Composer project built from its lock file
+type Graph struct {
+	Nodes map[string]*Node
+	// replacedBy and providedBy map replaced and virtual names to the packages satisfying them
+	replacedBy map[string][]string
+	providedBy map[string][]string
+	// RootRequires and RootRequiresDev contain the normalized names of the
+	// locked packages required by the root
+	RootRequires    []string
+	RootRequiresDev []string
+}
+
+// NormalizeName normalizes a package name the way Composer compares them
+func NormalizeName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// Build builds the dependency graph of a project and computes the reachability
+// of every locked package from the root require and require-dev edges
+func Build(composerJSON *parser.ComposerJSON, composerLock *parser.ComposerLock) *Graph {
+	g := &Graph{
+		Nodes:      make(map[string]*Node),
+		replacedBy: make(map[string][]string),
+		providedBy: make(map[string][]string),
+	}
+	if composerLock == nil {
+		return g
+	}
+
+	requires := make(map[string]map[string]string)
+	g.addPackages(composerLock.Packages, false, requires)
+	g.addPackages(composerLock.PackagesDev, true, requires)
+	for name, node := range g.Nodes {
+		edges := g.resolveEdges(requires[name])
+		node.Requires = edges[:0]
+		for _, edge := range edges {
+			// A package replacing a name it also requires does not depend on itself
+			if edge != name {
+				node.Requires = append(node.Requires, edge)
+			}
+		}
+	}
+
+	if composerJSON != nil {
+		g.RootRequires = g.resolveEdges(composerJSON.Require)
+		g.RootRequiresDev = g.resolveEdges(composerJSON.RequireDev)
+	}
+
+	for _, name := range g.RootRequires {
+		g.Nodes[name].Direct = true
+	}
+	for _, name := range g.RootRequiresDev {
+		g.Nodes[name].DirectDev = true
+	}
+
+	g.walk(g.RootRequires, func(node *Node, depth int) {
+		node.ProdReachable = true
+		node.ProdDepth = depth
+	})
+	g.walk(g.RootRequiresDev, func(node *Node, depth int) {
+		node.DevReachable = true
+		node.DevDepth = depth
+	})
+
+	for _, node := range g.Nodes {
+		if !node.Reachable() {
+			continue
+		}
+		for _, name := range node.Requires {
+			g.Nodes[name].RequiredByPackage = true
+		}
+	}
+
+	return g
+}
+
+// Node returns the node of a package, or nil if the package is not locked
+func (g *Graph) Node(name string) *Node {
+	return g.Nodes[NormalizeName(name)]
+}
+
+// Resolve returns the normalized names of the locked packages satisfying a
+// requirement: the package itself when it is locked, otherwise the packages
+// replacing it, otherwise the packages providing it
+func (g *Graph) Resolve(name string) []string {
+	normalized := NormalizeName(name)
+	if _, ok := g.Nodes[normalized]; ok {
+		return []string{normalized}
+	}
+	if replacers := g.replacedBy[normalized]; len(replacers) > 0 {
+		return replacers
+	}
+	return g.providedBy[normalized]
+}
+
+// ProvidersOf returns the locked packages replacing or providing a name,
+// e.g. the polyfills providing a PHP extension
+func (g *Graph) ProvidersOf(name string) []string {
+	normalized := NormalizeName(name)
+	var providers []string
+	for _, provider := range g.replacedBy[normalized] {
+		providers = append(providers, g.Nodes[provider].Name)
+	}
+	for _, provider := range g.providedBy[normalized] {
+		providers = append(providers, g.Nodes[provider].Name)
+	}
+	return providers
+}
+
+// addPackages adds a node per locked package and records its require map
+func (g *Graph) addPackages(packages []parser.PackageInfo, dev bool, requires map[string]map[string]string) {
+	for _, pkg := range packages {
+		name := NormalizeName(pkg.Name)
+		node := &Node{
+			Name:        pkg.Name,
+			Version:     pkg.Version,
+			LockedAsDev: dev,
+		}
+		for replaced := range pkg.Replace {
+			replaced = NormalizeName(replaced)
+			node.Replaces = append(node.Replaces, replaced)
+			g.replacedBy[replaced] = append(g.replacedBy[replaced], name)
+		}
+		for provided := range pkg.Provide {
+			provided = NormalizeName(provided)
+			node.Provides = append(node.Provides, provided)
+			g.providedBy[provided] = append(g.providedBy[provided], name)
+		}
+		sort.Strings(node.Replaces)
+		sort.Strings(node.Provides)
+		g.Nodes[name] = node
+		requires[name] = pkg.Require
+	}
+	for _, providers := range g.replacedBy {
+		sort.Strings(providers)
+	}
+	for _, providers := range g.providedBy {
+		sort.Strings(providers)
+	}
+}
+
+// resolveEdges returns the sorted, normalized names of the locked packages
+// satisfying a require map. Replaced and virtual names resolve to the packages
+// replacing or providing them. Platform requirements and unknown names are skipped.
+func (g *Graph) resolveEdges(requires map[string]string) []string {
+	seen := make(map[string]bool)
+	edges := make([]string, 0, len(requires))
+	for name := range requires {
+		if parser.IsPlatformPackage(name) {
+			continue
+		}
+		for _, resolved := range g.Resolve(name) {
+			if !seen[resolved] {
+				seen[resolved] = true
+				edges = append(edges, resolved)
+			}
+		}
+	}
+	sort.Strings(edges)
+	return edges
+}
+
+// walk does a breadth first traversal from the given roots, calling visit once
+// per reachable node with its shortest depth
+func (g *Graph) walk(roots []string, visit func(node *Node, depth int)) {
+	visited := make(map[string]bool)
+	queue := make([]string, 0, len(roots))
+	for _, name := range roots {
+		if !visited[name] {
+			visited[name] = true
+			queue = append(queue, name)
+		}
+	}
+
+	for depth := 1; len(queue) > 0; depth++ {
+		var next []string
+		for _, name := range queue {
+			node := g.Nodes[name]
+			visit(node, depth)
+			for _, child := range node.Requires {
+				if !visited[child] {
+					visited[child] = true
+					next = append(next, child)
+				}
+			}
+		}
+		queue = next
+	}
+}
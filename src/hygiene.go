@@ -0,0 +1,249 @@
+package src
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/constraint"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/diagnostics"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/parser"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+)
+
+// Constraint hygiene rules
+const (
+	RULE_WILDCARD           = "wildcard"
+	RULE_UNBOUNDED          = "unbounded"
+	RULE_DEV_BRANCH         = "dev-branch"
+	RULE_OVERLAPPING_RANGES = "overlapping-ranges"
+	RULE_INVALID_CONSTRAINT = "invalid-constraint"
+)
+
+// Finding severities
+const (
+	SEVERITY_HIGH   = "high"
+	SEVERITY_MEDIUM = "medium"
+	SEVERITY_LOW    = "low"
+)
+
+// manifestFiles are the manifests of a workspace and where they are on disk
+type manifestFiles struct {
+	composerJSON     *parser.ComposerJSON
+	composerLock     *parser.ComposerLock
+	composerJSONPath string
+	composerLockPath string
+	// Relative paths are the ones reported in the findings
+	relativeComposerJSON string
+	relativeComposerLock string
+	// writtenLock is the lock file on disk when composerLock is only a part of
+	// it, so that the packages are located at their index in the file
+	writtenLock *parser.ComposerLock
+}
+
+// checkConstraintHygiene reports the risky constraints of the root requirements
+// and of the requirements of every locked package. Platform requirements are
+// skipped: "ext-json": "*" and "php": ">=8.1" are the usual way to write them.
+func checkConstraintHygiene(ctx context.Context, manifests manifestFiles) []types.ConstraintFinding {
+	findings := []types.ConstraintFinding{}
+
+	if manifests.composerJSON != nil {
+		positions := locateKeys(ctx, manifests.composerJSONPath, manifests.relativeComposerJSON)
+		rootName := getRootPackageName(manifests.composerJSON)
+		sections := []struct {
+			key      string
+			requires map[string]string
+			dev      bool
+		}{
+			{"require", manifests.composerJSON.Require, false},
+			{"require-dev", manifests.composerJSON.RequireDev, true},
+		}
+		for _, section := range sections {
+			for name, requirement := range section.requires {
+				for _, finding := range constraintFindings(name, requirement, section.dev) {
+					finding.RequiredBy = rootName
+					finding.File = manifests.relativeComposerJSON
+					setPosition(&finding, positions, parser.JSONPointer(section.key, name))
+					findings = append(findings, finding)
+				}
+			}
+		}
+	}
+
+	if manifests.composerLock != nil {
+		positions := locateKeys(ctx, manifests.composerLockPath, manifests.relativeComposerLock)
+		writtenLock := manifests.writtenLock
+		if writtenLock == nil {
+			writtenLock = manifests.composerLock
+		}
+		sections := []struct {
+			key      string
+			packages []parser.PackageInfo
+			written  []parser.PackageInfo
+			dev      bool
+		}{
+			{"packages", manifests.composerLock.Packages, writtenLock.Packages, false},
+			{"packages-dev", manifests.composerLock.PackagesDev, writtenLock.PackagesDev, true},
+		}
+		for _, section := range sections {
+			indexes := make(map[string]int, len(section.written))
+			for i, pkg := range section.written {
+				indexes[pkg.Name] = i
+			}
+			for _, pkg := range section.packages {
+				i := indexes[pkg.Name]
+				for name, requirement := range pkg.Require {
+					for _, finding := range constraintFindings(name, requirement, section.dev) {
+						finding.RequiredBy = pkg.Name
+						finding.File = manifests.relativeComposerLock
+						setPosition(&finding, positions, parser.JSONPointer(section.key, strconv.Itoa(i), "require", name))
+						findings = append(findings, finding)
+					}
+				}
+			}
+		}
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.File != b.File {
+			// The manifest first
+			if a.File == manifests.relativeComposerJSON || b.File == manifests.relativeComposerJSON {
+				return a.File == manifests.relativeComposerJSON
+			}
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.RequiredBy != b.RequiredBy {
+			return a.RequiredBy < b.RequiredBy
+		}
+		if a.Dependency != b.Dependency {
+			return a.Dependency < b.Dependency
+		}
+		return a.Rule < b.Rule
+	})
+	return findings
+}
+
+// constraintFindings checks a single requirement against the hygiene rules
+func constraintFindings(name string, requirement string, dev bool) []types.ConstraintFinding {
+	if parser.IsPlatformPackage(name) || strings.TrimSpace(requirement) == "self.version" {
+		return nil
+	}
+
+	var findings []types.ConstraintFinding
+	add := func(rule string, severity string, message string) {
+		if dev {
+			severity = lowerSeverity(severity)
+		}
+		findings = append(findings, types.ConstraintFinding{
+			Rule:       rule,
+			Severity:   severity,
+			Message:    message,
+			Dependency: name,
+			Constraint: requirement,
+			Dev:        dev,
+		})
+	}
+
+	alternatives, err := constraint.Alternatives(requirement)
+	if err != nil {
+		add(RULE_INVALID_CONSTRAINT, SEVERITY_LOW, fmt.Sprintf("Constraint cannot be parsed: %v", err))
+		return findings
+	}
+	set := constraint.Empty()
+	for _, alternative := range alternatives {
+		set = set.Union(alternative)
+	}
+
+	switch {
+	case set.IsAny():
+		add(RULE_WILDCARD, SEVERITY_HIGH, "Any version is accepted, including future major versions")
+	case !set.HasUpperBound():
+		add(RULE_UNBOUNDED, SEVERITY_HIGH, "No upper bound, future major versions are accepted")
+	}
+
+	if len(set.Branches) > 0 {
+		add(RULE_DEV_BRANCH, SEVERITY_HIGH, fmt.Sprintf("Requires the dev-%s branch, which has no release guarantees", set.Branches[0]))
+	}
+
+	if overlapping, majors := overlappingAlternatives(alternatives); overlapping {
+		add(RULE_OVERLAPPING_RANGES, SEVERITY_MEDIUM, fmt.Sprintf("Overlapping || alternatives span major versions %s", majors))
+	}
+
+	return findings
+}
+
+// overlappingAlternatives reports whether two || alternatives overlap while the
+// constraint allows more than one major version, along with those majors
+func overlappingAlternatives(alternatives []constraint.Set) (bool, string) {
+	overlapping := false
+	for i := range alternatives {
+		for j := i + 1; j < len(alternatives); j++ {
+			if !alternatives[i].Intersect(alternatives[j]).IsEmpty() {
+				overlapping = true
+			}
+		}
+	}
+	if !overlapping {
+		return false, ""
+	}
+
+	set := constraint.Empty()
+	for _, alternative := range alternatives {
+		set = set.Union(alternative)
+	}
+	majors, bounded := set.Majors()
+	if !bounded {
+		return true, "without upper bound"
+	}
+	if len(majors) < 2 {
+		return false, ""
+	}
+	names := make([]string, len(majors))
+	for i, major := range majors {
+		names[i] = strconv.Itoa(major)
+	}
+	return true, strings.Join(names, ", ")
+}
+
+func lowerSeverity(severity string) string {
+	switch severity {
+	case SEVERITY_HIGH:
+		return SEVERITY_MEDIUM
+	default:
+		return SEVERITY_LOW
+	}
+}
+
+// locateKeys reads a manifest and returns the positions of its keys. The
+// findings of a manifest that cannot be located are reported without position.
+func locateKeys(ctx context.Context, filePath string, relativePath string) parser.KeyPositions {
+	if filePath == "" {
+		return nil
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		diagnostics.Logger(ctx).Printf("PHP SBOM Debug - cannot read %s to locate constraints: %v", filePath, err)
+		diagnostics.FromContext(ctx).Info(diagnostics.INFO_CONSTRAINTS_UNLOCATED, fmt.Sprintf("Cannot read the manifest to locate constraints: %v", err), diagnostics.Location{File: relativePath})
+		return nil
+	}
+	positions, err := parser.LocateKeys(data)
+	if err != nil {
+		diagnostics.Logger(ctx).Printf("PHP SBOM Debug - cannot locate constraints in %s: %v", filePath, err)
+		diagnostics.FromContext(ctx).Info(diagnostics.INFO_CONSTRAINTS_UNLOCATED, fmt.Sprintf("Cannot locate constraints: %v", err), diagnostics.Location{File: relativePath})
+	}
+	return positions
+}
+
+func setPosition(finding *types.ConstraintFinding, positions parser.KeyPositions, pointer string) {
+	if position, ok := positions[pointer]; ok {
+		finding.Line = position.Line
+		finding.Column = position.Column
+	}
+}
@@ -0,0 +1,242 @@
+package notices
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+)
+
+// UNKNOWN_LICENSE is the group used for packages that do not declare a license
+const UNKNOWN_LICENSE = "NOASSERTION"
+
+// Notices contains the third-party attribution data of a project
+type Notices struct {
+	ProjectName string
+	Groups      []LicenseGroup
+}
+
+// LicenseGroup groups the production packages sharing the same license expression
+type LicenseGroup struct {
+	License string
+	// StandardText is the SPDX reference text of the license, used for
+	// packages that do not ship their own license file
+	StandardText string
+	// ReferenceURL points to the SPDX license page
+	ReferenceURL string
+	Packages     []PackageNotice
+}
+
+// PackageNotice contains the attribution data of a single package
+type PackageNotice struct {
+	Name        string
+	Version     string
+	Licenses    []string
+	Authors     []types.Author
+	Copyrights  []string
+	LicenseFile string
+	LicenseText string
+}
+
+// NeedsStandardText reports whether at least one package of the group has no
+// license text of its own
+func (g LicenseGroup) NeedsStandardText() bool {
+	for _, pkg := range g.Packages {
+		if pkg.LicenseText == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// licenseFilePattern matches the file names packages use to ship their license
+var licenseFilePattern = regexp.MustCompile(`(?i)^(licen[cs]e|copying|notice)([.-].*)?$`)
+
+// copyrightPattern matches copyright statements inside license files
+var copyrightPattern = regexp.MustCompile(`(?i)^\s*(copyright\b|\(c\)|©)`)
+
+// placeholderPattern matches the unfilled placeholders of license templates
+var placeholderPattern = regexp.MustCompile(`(?i)<(year|owner|copyright holders?)>`)
+
+// Collect gathers the attribution data of every production package in the output.
+// License files and copyright lines are read from vendorDir when it exists.
+// If vendorDir is empty, the vendor directory of the analyzed project is used.
+func Collect(output types.Output, vendorDir string) Notices {
+	if vendorDir == "" && output.AnalysisInfo.WorkingDirectory != "" {
+		vendorDir = filepath.Join(output.AnalysisInfo.WorkingDirectory, "vendor")
+	}
+
+	groups := make(map[string]*LicenseGroup)
+	seen := make(map[string]bool)
+
+	for _, workspaceName := range sortedWorkspaceNames(output.WorkSpaces) {
+		workspace := output.WorkSpaces[workspaceName]
+		for name, versions := range workspace.Dependencies {
+			for version, info := range versions {
+				if !info.Prod {
+					continue
+				}
+				key := name + types.VERSION_SEPARATOR + version
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+
+				notice := PackageNotice{
+					Name:     name,
+					Version:  version,
+					Licenses: info.Licenses,
+					Authors:  info.Authors,
+				}
+				readVendorLicense(vendorDir, &notice)
+
+				license := LicenseExpression(info.Licenses)
+				group, ok := groups[license]
+				if !ok {
+					group = &LicenseGroup{
+						License:      license,
+						StandardText: StandardLicenseText(license),
+						ReferenceURL: referenceURL(license),
+					}
+					groups[license] = group
+				}
+				group.Packages = append(group.Packages, notice)
+			}
+		}
+	}
+
+	result := Notices{
+		ProjectName: output.AnalysisInfo.ProjectName,
+		Groups:      make([]LicenseGroup, 0, len(groups)),
+	}
+	for _, group := range groups {
+		sort.Slice(group.Packages, func(i, j int) bool {
+			if group.Packages[i].Name == group.Packages[j].Name {
+				return group.Packages[i].Version < group.Packages[j].Version
+			}
+			return group.Packages[i].Name < group.Packages[j].Name
+		})
+		result.Groups = append(result.Groups, *group)
+	}
+	sort.Slice(result.Groups, func(i, j int) bool {
+		return result.Groups[i].License < result.Groups[j].License
+	})
+
+	return result
+}
+
+// LicenseExpression joins the licenses of a package into an SPDX expression.
+// Composer treats multiple licenses as a disjunction.
+func LicenseExpression(licenses []string) string {
+	var cleaned []string
+	for _, license := range licenses {
+		if license = strings.TrimSpace(license); license != "" {
+			cleaned = append(cleaned, license)
+		}
+	}
+	if len(cleaned) == 0 {
+		return UNKNOWN_LICENSE
+	}
+	if len(cleaned) == 1 {
+		return cleaned[0]
+	}
+	return "(" + strings.Join(cleaned, " OR ") + ")"
+}
+
+// readVendorLicense looks for a license file in the installed package and
+// extracts its text and copyright lines
+func readVendorLicense(vendorDir string, notice *PackageNotice) {
+	if vendorDir == "" {
+		return
+	}
+
+	packageDir := filepath.Join(vendorDir, filepath.FromSlash(notice.Name))
+	entries, err := os.ReadDir(packageDir)
+	if err != nil {
+		return
+	}
+
+	var candidates []string
+	for _, entry := range entries {
+		if !entry.IsDir() && licenseFilePattern.MatchString(entry.Name()) {
+			candidates = append(candidates, entry.Name())
+		}
+	}
+	if len(candidates) == 0 {
+		return
+	}
+	// Prefer LICENSE over COPYING over NOTICE
+	sort.Slice(candidates, func(i, j int) bool {
+		if licenseFileRank(candidates[i]) == licenseFileRank(candidates[j]) {
+			return candidates[i] < candidates[j]
+		}
+		return licenseFileRank(candidates[i]) < licenseFileRank(candidates[j])
+	})
+
+	for _, candidate := range candidates {
+		path := filepath.Join(packageDir, candidate)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if notice.LicenseText == "" {
+			notice.LicenseFile = path
+			notice.LicenseText = strings.TrimSpace(string(data))
+		}
+		notice.Copyrights = appendUnique(notice.Copyrights, extractCopyrights(string(data))...)
+	}
+}
+
+func licenseFileRank(name string) int {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasPrefix(name, "licen"):
+		return 0
+	case strings.HasPrefix(name, "copying"):
+		return 1
+	default:
+		return 2
+	}
+}
+
+// extractCopyrights returns the copyright statements found in a license text
+func extractCopyrights(text string) []string {
+	var copyrights []string
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if copyrightPattern.MatchString(line) && !placeholderPattern.MatchString(line) {
+			copyrights = append(copyrights, line)
+		}
+	}
+	return copyrights
+}
+
+func appendUnique(values []string, candidates ...string) []string {
+	for _, candidate := range candidates {
+		found := false
+		for _, value := range values {
+			if value == candidate {
+				found = true
+				break
+			}
+		}
+		if !found {
+			values = append(values, candidate)
+		}
+	}
+	return values
+}
+
+func sortedWorkspaceNames(workspaces map[string]types.WorkSpace) []string {
+	names := make([]string, 0, len(workspaces))
+	for name := range workspaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,150 @@
+package notices
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+)
+
+// Format is the output format of the third-party notices
+type Format string
+
+const (
+	FORMAT_MARKDOWN Format = "markdown"
+	FORMAT_TEXT     Format = "text"
+	FORMAT_HTML     Format = "html"
+)
+
+// Generate collects the notices of the output and renders them in the given format
+func Generate(output types.Output, vendorDir string, format Format) (string, error) {
+	return Collect(output, vendorDir).Render(format)
+}
+
+// Render renders the notices in the given format
+func (n Notices) Render(format Format) (string, error) {
+	var buffer bytes.Buffer
+	var err error
+
+	switch format {
+	case FORMAT_MARKDOWN:
+		err = markdownTemplate.Execute(&buffer, n)
+	case FORMAT_TEXT:
+		err = textTemplate.Execute(&buffer, n)
+	case FORMAT_HTML:
+		err = htmlTemplate.Execute(&buffer, n)
+	default:
+		return "", fmt.Errorf("unsupported notices format: %s", format)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to render notices: %w", err)
+	}
+
+	return buffer.String(), nil
+}
+
+// formatAuthor renders an author as "Name <email>"
+func formatAuthor(author types.Author) string {
+	if author.Email == "" {
+		return author.Name
+	}
+	return fmt.Sprintf("%s <%s>", author.Name, author.Email)
+}
+
+// formatAuthors renders a comma separated list of authors
+func formatAuthors(authors []types.Author) string {
+	names := make([]string, 0, len(authors))
+	for _, author := range authors {
+		if author.Name != "" {
+			names = append(names, formatAuthor(author))
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// indent prefixes every line of a text, used for Markdown code blocks
+func indent(prefix string, text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = prefix + line
+	}
+	return strings.Join(lines, "\n")
+}
+
+var templateFuncs = map[string]any{
+	"authors": formatAuthors,
+	"indent":  indent,
+}
+
+var markdownTemplate = texttemplate.Must(texttemplate.New("markdown").Funcs(templateFuncs).Parse(
+	`# Third-Party Notices{{if .ProjectName}} for {{.ProjectName}}{{end}}
+
+This product includes the following third-party software.
+{{range .Groups}}
+## {{.License}}
+{{range .Packages}}
+### {{.Name}} {{.Version}}
+{{with authors .Authors}}
+Authors: {{.}}
+{{end}}{{range .Copyrights}}
+{{.}}
+{{end}}{{if .LicenseText}}
+{{indent "    " .LicenseText}}
+{{end}}{{end}}{{if .NeedsStandardText}}{{if .StandardText}}
+### {{.License}} license text
+
+{{indent "    " .StandardText}}
+{{else if .ReferenceURL}}
+The full license text is available at <{{.ReferenceURL}}>.
+{{end}}{{end}}{{end}}`))
+
+var textTemplate = texttemplate.Must(texttemplate.New("text").Funcs(templateFuncs).Parse(
+	`THIRD-PARTY NOTICES{{if .ProjectName}} FOR {{.ProjectName}}{{end}}
+
+This product includes the following third-party software.
+{{range .Groups}}
+================================================================================
+{{.License}}
+================================================================================
+{{range .Packages}}
+{{.Name}} {{.Version}}
+{{with authors .Authors}}Authors: {{.}}
+{{end}}{{range .Copyrights}}{{.}}
+{{end}}{{if .LicenseText}}
+{{.LicenseText}}
+{{end}}{{end}}{{if .NeedsStandardText}}{{if .StandardText}}
+--- {{.License}} license text ---
+
+{{.StandardText}}
+{{else if .ReferenceURL}}
+The full license text is available at {{.ReferenceURL}}
+{{end}}{{end}}{{end}}`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Funcs(templateFuncs).Parse(
+	`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Third-Party Notices{{if .ProjectName}} for {{.ProjectName}}{{end}}</title>
+</head>
+<body>
+<h1>Third-Party Notices{{if .ProjectName}} for {{.ProjectName}}{{end}}</h1>
+<p>This product includes the following third-party software.</p>
+{{range .Groups}}<section>
+<h2>{{.License}}</h2>
+{{range .Packages}}<article>
+<h3>{{.Name}} {{.Version}}</h3>
+{{with authors .Authors}}<p>Authors: {{.}}</p>
+{{end}}{{range .Copyrights}}<p>{{.}}</p>
+{{end}}{{if .LicenseText}}<pre>{{.LicenseText}}</pre>
+{{end}}</article>
+{{end}}{{if .NeedsStandardText}}{{if .StandardText}}<h3>{{.License}} license text</h3>
+<pre>{{.StandardText}}</pre>
+{{else if .ReferenceURL}}<p>The full license text is available at <a href="{{.ReferenceURL}}">{{.ReferenceURL}}</a>.</p>
+{{end}}{{end}}</section>
+{{end}}</body>
+</html>
+`))
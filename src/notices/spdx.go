@@ -0,0 +1,72 @@
+package notices
+
+import (
+	"embed"
+	"strings"
+)
+
+// licenseTexts holds the SPDX standard texts of the most common licenses
+// found on Packagist, the copyleft ones included since they require shipping
+// the text itself. The GNU licenses are stored once for their -only and
+// -or-later identifiers.
+//
+//go:embed licenses/*.txt
+var licenseTexts embed.FS
+
+// licenseIDList is the SPDX License List, deprecated identifiers excepted
+//
+//go:embed license_ids.txt
+var licenseIDList string
+
+// licenseIDs maps the lower case SPDX license identifiers to their canonical form
+var licenseIDs = func() map[string]string {
+	ids := make(map[string]string)
+	for _, id := range strings.Fields(licenseIDList) {
+		ids[strings.ToLower(id)] = id
+	}
+	return ids
+}()
+
+// LicenseID returns the SPDX identifier of a license in its canonical case,
+// or false when the license is not on the SPDX License List, such as
+// proprietary. The deprecated GNU identifiers map to their current form:
+// GPL-2.0+ is GPL-2.0-or-later and GPL-2.0 is GPL-2.0-only.
+func LicenseID(license string) (string, bool) {
+	name := strings.ToLower(strings.TrimSpace(license))
+	if id, ok := licenseIDs[name]; ok {
+		return id, true
+	}
+	if base, ok := strings.CutSuffix(name, "+"); ok {
+		id, ok := licenseIDs[base+"-or-later"]
+		return id, ok
+	}
+	id, ok := licenseIDs[name+"-only"]
+	return id, ok
+}
+
+// StandardLicenseText returns the SPDX standard text of a license identifier.
+// An empty string is returned for expressions and licenses that are not bundled.
+func StandardLicenseText(license string) string {
+	if strings.ContainsAny(license, " ()/") {
+		return ""
+	}
+	// GPL-2.0+ is the deprecated form of GPL-2.0-or-later
+	name := strings.TrimSuffix(license, "+")
+	if id, ok := LicenseID(name); ok {
+		name = id
+	}
+	name = strings.TrimSuffix(strings.TrimSuffix(name, "-only"), "-or-later")
+	data, err := licenseTexts.ReadFile("licenses/" + name + ".txt")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// referenceURL returns the SPDX page of a license identifier
+func referenceURL(license string) string {
+	if license == UNKNOWN_LICENSE || strings.ContainsAny(license, " ()/") {
+		return ""
+	}
+	return "https://spdx.org/licenses/" + license + ".html"
+}
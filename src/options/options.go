@@ -0,0 +1,333 @@
+// Package options holds the per-analysis configuration read from the
+// analysis document, so that each project can be analyzed differently.
+package options
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/export"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/policy"
+)
+
+// Configuration keys, as declared in config.json
+const (
+	KEY_INCLUDE_DEV       = "include_dev"
+	KEY_EXCLUDE_PATHS     = "exclude_paths"
+	KEY_SCAN_PHARS        = "scan_phars"
+	KEY_OUTPUT_FORMATS    = "output_formats"
+	KEY_POLICY            = "policy"
+	KEY_MAX_DEPTH         = "max_depth"
+	KEY_FOLLOW_SYMLINKS   = "follow_symlinks"
+	KEY_ROOT_MANIFEST     = "root_manifest"
+	KEY_COMPOSER_FILENAME = "composer_filename"
+	KEY_TIMEOUT           = "timeout"
+	KEY_RESPECT_GITIGNORE = "respect_gitignore"
+	KEY_MULTI_PROJECT     = "multi_project"
+	KEY_SHARED_LOCK       = "shared_lock"
+)
+
+// DEFAULT_COMPOSER_FILENAME is the manifest Composer reads when COMPOSER is not set
+const DEFAULT_COMPOSER_FILENAME = "composer.json"
+
+// UNLIMITED_DEPTH searches every directory under the project
+const UNLIMITED_DEPTH = -1
+
+// Options control how a project is searched and what the SBOM contains
+type Options struct {
+	// IncludeDev keeps the packages only required by require-dev
+	IncludeDev bool
+	// ExcludePaths are globs of paths, relative to the project directory and
+	// separated by /, that are not searched. A glob without / matches a file
+	// or directory name at any depth.
+	ExcludePaths []string
+	// ScanPHARs searches the project for PHAR archives
+	ScanPHARs bool
+	// OutputFormats are the documents produced in addition to the native output
+	OutputFormats []export.Format
+	// Policy is evaluated against the SBOM, its violations are reported
+	Policy policy.Policy
+	// MaxDepth is how many directories below the project directory are
+	// searched, 0 being the project directory only
+	MaxDepth int
+	// FollowSymlinks descends into symlinked directories
+	FollowSymlinks bool
+	// RespectGitignore skips the paths listed in the .gitignore files of the
+	// project, lock files excepted
+	RespectGitignore bool
+	// RootManifest is the path of the root manifest relative to the project
+	// directory, instead of the manifest closest to it
+	RootManifest string
+	// ComposerFilename is the name of the manifests, like the COMPOSER
+	// environment variable of Composer
+	ComposerFilename string
+	// MultiProject analyzes each project of the directory with its own lock
+	// file on its own, instead of a single root project and its workspaces
+	MultiProject bool
+	// SharedLock resolves the requirements of the workspaces without their
+	// own lock file against the lock file of the root manifest
+	SharedLock bool
+	// Timeout bounds the duration of the analysis, the SBOM built when it
+	// expires is returned as truncated. Zero means no timeout.
+	Timeout time.Duration
+}
+
+// Default returns the options used when the analysis document sets none
+func Default() Options {
+	return Options{
+		IncludeDev:       true,
+		ExcludePaths:     []string{},
+		ScanPHARs:        true,
+		RespectGitignore: true,
+		OutputFormats:    []export.Format{export.FORMAT_JSON},
+		MaxDepth:         UNLIMITED_DEPTH,
+		ComposerFilename: DEFAULT_COMPOSER_FILENAME,
+	}
+}
+
+// Parse reads the options from the plugin configuration of an analysis
+// document. Missing keys keep their default value, and keys that are not
+// options, such as project or branch, are ignored.
+func Parse(config map[string]any) (Options, error) {
+	opts := Default()
+	var err error
+
+	if opts.IncludeDev, err = boolOption(config, KEY_INCLUDE_DEV, opts.IncludeDev); err != nil {
+		return Options{}, err
+	}
+	if opts.ScanPHARs, err = boolOption(config, KEY_SCAN_PHARS, opts.ScanPHARs); err != nil {
+		return Options{}, err
+	}
+	if opts.FollowSymlinks, err = boolOption(config, KEY_FOLLOW_SYMLINKS, opts.FollowSymlinks); err != nil {
+		return Options{}, err
+	}
+	if opts.RespectGitignore, err = boolOption(config, KEY_RESPECT_GITIGNORE, opts.RespectGitignore); err != nil {
+		return Options{}, err
+	}
+	if opts.MultiProject, err = boolOption(config, KEY_MULTI_PROJECT, opts.MultiProject); err != nil {
+		return Options{}, err
+	}
+	if opts.SharedLock, err = boolOption(config, KEY_SHARED_LOCK, opts.SharedLock); err != nil {
+		return Options{}, err
+	}
+
+	if opts.ExcludePaths, err = stringsOption(config, KEY_EXCLUDE_PATHS, opts.ExcludePaths); err != nil {
+		return Options{}, err
+	}
+	for i, pattern := range opts.ExcludePaths {
+		pattern = strings.Trim(filepath.ToSlash(strings.TrimSpace(pattern)), "/")
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return Options{}, fmt.Errorf("invalid %s pattern %q", KEY_EXCLUDE_PATHS, opts.ExcludePaths[i])
+		}
+		opts.ExcludePaths[i] = pattern
+	}
+
+	formats, err := stringsOption(config, KEY_OUTPUT_FORMATS, nil)
+	if err != nil {
+		return Options{}, err
+	}
+	if formats != nil {
+		opts.OutputFormats = []export.Format{}
+		for _, name := range formats {
+			format, err := export.ParseFormat(name)
+			if err != nil {
+				return Options{}, fmt.Errorf("invalid %s: %w", KEY_OUTPUT_FORMATS, err)
+			}
+			if !containsFormat(opts.OutputFormats, format) {
+				opts.OutputFormats = append(opts.OutputFormats, format)
+			}
+		}
+	}
+
+	spec, err := stringOption(config, KEY_POLICY, "")
+	if err != nil {
+		return Options{}, err
+	}
+	if opts.Policy, err = policy.Parse(spec); err != nil {
+		return Options{}, fmt.Errorf("invalid %s: %w", KEY_POLICY, err)
+	}
+
+	if opts.MaxDepth, err = intOption(config, KEY_MAX_DEPTH, opts.MaxDepth); err != nil {
+		return Options{}, err
+	}
+	if opts.MaxDepth < UNLIMITED_DEPTH {
+		return Options{}, fmt.Errorf("invalid %s: %d", KEY_MAX_DEPTH, opts.MaxDepth)
+	}
+
+	if opts.RootManifest, err = stringOption(config, KEY_ROOT_MANIFEST, ""); err != nil {
+		return Options{}, err
+	}
+	if opts.RootManifest != "" {
+		cleaned := path.Clean(filepath.ToSlash(opts.RootManifest))
+		if path.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+			return Options{}, fmt.Errorf("invalid %s %q, expected a path inside the project", KEY_ROOT_MANIFEST, opts.RootManifest)
+		}
+		opts.RootManifest = cleaned
+		if opts.MultiProject {
+			return Options{}, fmt.Errorf("%s cannot be combined with %s, each project has its own root manifest", KEY_ROOT_MANIFEST, KEY_MULTI_PROJECT)
+		}
+	}
+
+	if opts.ComposerFilename, err = stringOption(config, KEY_COMPOSER_FILENAME, opts.ComposerFilename); err != nil {
+		return Options{}, err
+	}
+	if opts.ComposerFilename == "" || strings.ContainsAny(opts.ComposerFilename, `/\`) || !strings.HasSuffix(opts.ComposerFilename, ".json") {
+		return Options{}, fmt.Errorf("invalid %s %q, expected a file name ending with .json", KEY_COMPOSER_FILENAME, opts.ComposerFilename)
+	}
+
+	if opts.Timeout, err = durationOption(config, KEY_TIMEOUT, opts.Timeout); err != nil {
+		return Options{}, err
+	}
+
+	return opts, nil
+}
+
+// LockFilename is the name of the lock file matching ComposerFilename,
+// composer.lock for composer.json and other.lock for other.json
+func (o Options) LockFilename() string {
+	name := o.ComposerFilename
+	if name == "" {
+		name = DEFAULT_COMPOSER_FILENAME
+	}
+	return strings.TrimSuffix(name, ".json") + ".lock"
+}
+
+// IsExcluded reports whether a path relative to the project directory
+// matches one of ExcludePaths
+func (o Options) IsExcluded(relativePath string) bool {
+	relativePath = filepath.ToSlash(relativePath)
+	name := path.Base(relativePath)
+	for _, pattern := range o.ExcludePaths {
+		target := relativePath
+		if !strings.Contains(pattern, "/") {
+			target = name
+		}
+		if matched, _ := path.Match(pattern, target); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// HasFormat reports whether the format is one of OutputFormats
+func (o Options) HasFormat(format export.Format) bool {
+	return containsFormat(o.OutputFormats, format)
+}
+
+func containsFormat(formats []export.Format, format export.Format) bool {
+	for _, f := range formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+func boolOption(config map[string]any, key string, fallback bool) (bool, error) {
+	value, ok := config[key]
+	if !ok || value == nil {
+		return fallback, nil
+	}
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("invalid %s: expected a boolean, got %v", key, value)
+}
+
+func intOption(config map[string]any, key string, fallback int) (int, error) {
+	value, ok := config[key]
+	if !ok || value == nil {
+		return fallback, nil
+	}
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case float64:
+		// JSON numbers are decoded as float64
+		if v == math.Trunc(v) && math.Abs(v) < math.MaxInt32 {
+			return int(v), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid %s: expected an integer, got %v", key, value)
+}
+
+// durationOption accepts a Go duration such as "90s" or "10m", or a number of seconds
+func durationOption(config map[string]any, key string, fallback time.Duration) (time.Duration, error) {
+	value, ok := config[key]
+	if !ok || value == nil {
+		return fallback, nil
+	}
+	var duration time.Duration
+	switch v := value.(type) {
+	case string:
+		parsed, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s: %w", key, err)
+		}
+		duration = parsed
+	case float64:
+		duration = time.Duration(v * float64(time.Second))
+	case int:
+		duration = time.Duration(v) * time.Second
+	default:
+		return 0, fmt.Errorf("invalid %s: expected a duration, got %v", key, value)
+	}
+	if duration < 0 {
+		return 0, fmt.Errorf("invalid %s: %v", key, value)
+	}
+	return duration, nil
+}
+
+func stringOption(config map[string]any, key string, fallback string) (string, error) {
+	value, ok := config[key]
+	if !ok || value == nil {
+		return fallback, nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("invalid %s: expected a string, got %v", key, value)
+	}
+	return strings.TrimSpace(s), nil
+}
+
+// stringsOption accepts a list of strings or a comma separated string
+func stringsOption(config map[string]any, key string, fallback []string) ([]string, error) {
+	value, ok := config[key]
+	if !ok || value == nil {
+		return fallback, nil
+	}
+	values := []string{}
+	switch v := value.(type) {
+	case string:
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+	case []string:
+		values = append(values, v...)
+	case []any:
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid %s: expected a list of strings, got %v", key, item)
+			}
+			values = append(values, s)
+		}
+	default:
+		return nil, fmt.Errorf("invalid %s: expected a list of strings, got %v", key, value)
+	}
+	return values, nil
+}
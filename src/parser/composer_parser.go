@@ -1,12 +1,17 @@
 package parser
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/diagnostics"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/options"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/scanner"
 )
 
 // ComposerJSON represents the structure of composer.json
@@ -18,9 +23,21 @@ type ComposerJSON struct {
 	License     any            `json:"license"` // Can be string or array
 	Require     map[string]string      `json:"require"`
 	RequireDev  map[string]string      `json:"require-dev"`
+	Replace     map[string]string      `json:"replace"`
+	Provide     map[string]string      `json:"provide"`
+	Repositories Repositories          `json:"repositories"`
+	MinimumStability string            `json:"minimum-stability"`
 	Autoload    map[string]any `json:"autoload"`
 	Authors     []Author               `json:"authors"`
 	Extra       map[string]any `json:"extra"`
+	Config      ComposerConfig         `json:"config"`
+}
+
+// ComposerConfig represents the config section of composer.json
+type ComposerConfig struct {
+	// Platform fakes platform packages, e.g. {"php": "8.2"} or {"ext-foo": false}
+	Platform  PlatformMap `json:"platform"`
+	VendorDir string      `json:"vendor-dir"`
 }
 
 // Author represents a package author
@@ -36,16 +53,26 @@ type ComposerLock struct {
 	ContentHash     string           `json:"content-hash"`
 	Packages        []PackageInfo    `json:"packages"`
 	PackagesDev     []PackageInfo    `json:"packages-dev"`
-	Aliases         []any    `json:"aliases"`
+	Aliases         []LockAlias      `json:"aliases"`
 	MinimumStability string          `json:"minimum-stability"`
-	StabilityFlags  map[string]int   `json:"stability-flags"`
+	StabilityFlags  StabilityFlags   `json:"stability-flags"`
 	PreferStable    bool             `json:"prefer-stable"`
 	PreferLowest    bool             `json:"prefer-lowest"`
-	Platform        map[string]string `json:"platform"`
-	PlatformDev     []any    `json:"platform-dev"` // Can be array or map
+	Platform        PlatformMap      `json:"platform"`
+	PlatformDev     PlatformMap      `json:"platform-dev"`
+	PlatformOverrides PlatformMap    `json:"platform-overrides"`
 	PluginAPIVersion string          `json:"plugin-api-version"`
 }
 
+// LockAlias represents an inline alias recorded in composer.lock,
+// e.g. dev-main as 2.1.x-dev
+type LockAlias struct {
+	Package         string `json:"package"`
+	Version         string `json:"version"`
+	Alias           string `json:"alias"`
+	AliasNormalized string `json:"alias_normalized"`
+}
+
 // PackageInfo represents a package in composer.lock
 type PackageInfo struct {
 	Name            string                 `json:"name"`
@@ -54,6 +81,8 @@ type PackageInfo struct {
 	Dist            Dist                   `json:"dist"`
 	Require         map[string]string      `json:"require"`
 	RequireDev      map[string]string      `json:"require-dev"`
+	Replace         map[string]string      `json:"replace"`
+	Provide         map[string]string      `json:"provide"`
 	Type            string                 `json:"type"`
 	License         any            `json:"license"`
 	Authors         []Author               `json:"authors"`
@@ -65,6 +94,160 @@ type PackageInfo struct {
 	Extra           map[string]any `json:"extra"`
 }
 
+// Repository represents an entry of the repositories section of composer.json
+type Repository struct {
+	Type    string         `json:"type"`
+	URL     string         `json:"url"`
+	Options map[string]any `json:"options"`
+}
+
+// Repositories is the repositories section of composer.json. Composer accepts
+// both a list and an object keyed by repository name, where a false value
+// disables a repository such as packagist.org.
+type Repositories []Repository
+
+// UnmarshalJSON decodes repositories written either as a list or an object
+func (r *Repositories) UnmarshalJSON(data []byte) error {
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	var entries []any
+	switch v := raw.(type) {
+	case nil:
+	case []any:
+		entries = v
+	case map[string]any:
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			entries = append(entries, v[name])
+		}
+	default:
+		return fmt.Errorf("repositories must be a list or an object, got %T", raw)
+	}
+
+	result := Repositories{}
+	for _, entry := range entries {
+		object, ok := entry.(map[string]any)
+		if !ok {
+			// Disabled repositories such as {"packagist.org": false}
+			continue
+		}
+		encoded, err := json.Marshal(object)
+		if err != nil {
+			return err
+		}
+		var repository Repository
+		if err := json.Unmarshal(encoded, &repository); err != nil {
+			return err
+		}
+		result = append(result, repository)
+	}
+
+	*r = result
+	return nil
+}
+
+// StabilityFlags maps package names to the stability Composer allows for them
+// (0 stable, 5 RC, 10 beta, 15 alpha, 20 dev). An empty map is written as [].
+type StabilityFlags map[string]int
+
+// UnmarshalJSON decodes stability flags written either as an object or an empty array
+func (f *StabilityFlags) UnmarshalJSON(data []byte) error {
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	result := make(StabilityFlags)
+	switch v := raw.(type) {
+	case nil:
+	case []any:
+		if len(v) != 0 {
+			return fmt.Errorf("stability flags must be an object, got a non-empty array")
+		}
+	case map[string]any:
+		for name, value := range v {
+			flag, ok := value.(float64)
+			if !ok {
+				return fmt.Errorf("invalid stability flag for %s: %v", name, value)
+			}
+			result[name] = int(flag)
+		}
+	default:
+		return fmt.Errorf("stability flags must be an object, got %T", raw)
+	}
+
+	*f = result
+	return nil
+}
+
+// Stability flag values used in composer.lock
+const (
+	STABILITY_FLAG_STABLE = 0
+	STABILITY_FLAG_RC     = 5
+	STABILITY_FLAG_BETA   = 10
+	STABILITY_FLAG_ALPHA  = 15
+	STABILITY_FLAG_DEV    = 20
+)
+
+// StabilityFlagName returns the stability name of a stability flag value
+func StabilityFlagName(flag int) string {
+	switch flag {
+	case STABILITY_FLAG_RC:
+		return "RC"
+	case STABILITY_FLAG_BETA:
+		return "beta"
+	case STABILITY_FLAG_ALPHA:
+		return "alpha"
+	case STABILITY_FLAG_DEV:
+		return "dev"
+	default:
+		return "stable"
+	}
+}
+
+// PlatformMap maps platform package names to version constraints.
+// Composer serializes an empty map as [] and disabled overrides as false,
+// so both are accepted when decoding.
+type PlatformMap map[string]string
+
+// UnmarshalJSON decodes a platform map written either as an object or an array
+func (p *PlatformMap) UnmarshalJSON(data []byte) error {
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	result := make(PlatformMap)
+	switch v := raw.(type) {
+	case nil:
+	case []any:
+		if len(v) != 0 {
+			return fmt.Errorf("platform map must be an object, got a non-empty array")
+		}
+	case map[string]any:
+		for name, value := range v {
+			switch constraint := value.(type) {
+			case string:
+				result[name] = constraint
+			default:
+				result[name] = fmt.Sprint(constraint)
+			}
+		}
+	default:
+		return fmt.Errorf("platform map must be an object, got %T", raw)
+	}
+
+	*p = result
+	return nil
+}
+
 // Source represents the source control info
 type Source struct {
 	Type      string `json:"type"`
@@ -89,7 +272,7 @@ func ParseComposerJSON(filePath string) (*ComposerJSON, error) {
 
 	var composerJSON ComposerJSON
 	if err := json.Unmarshal(data, &composerJSON); err != nil {
-		return nil, fmt.Errorf("failed to parse composer.json: %w", err)
+		return nil, withFile(decodeError("composer.json", data, err), filePath)
 	}
 
 	return &composerJSON, nil
@@ -102,78 +285,44 @@ func ParseComposerLock(filePath string) (*ComposerLock, error) {
 		return nil, fmt.Errorf("failed to read composer.lock: %w", err)
 	}
 
+	composerLock, err := ParseComposerLockData(data)
+	if err != nil {
+		return nil, withFile(err, filePath)
+	}
+	return composerLock, nil
+}
+
+// ParseComposerLockData parses the content of a composer.lock file
+func ParseComposerLockData(data []byte) (*ComposerLock, error) {
 	var composerLock ComposerLock
 	if err := json.Unmarshal(data, &composerLock); err != nil {
-		return nil, fmt.Errorf("failed to parse composer.lock: %w", err)
+		return nil, decodeError("composer.lock", data, err)
 	}
 
 	return &composerLock, nil
 }
 
-// FindComposerFiles searches for composer.json and composer.lock in a directory
-func FindComposerFiles(rootDir string) ([]string, []string, error) {
-	var composerJSONFiles []string
-	var composerLockFiles []string
+// FindComposerFiles searches for the manifests and lock files named by the
+// options in a directory. When ctx is done, the files found so far are
+// returned with its error.
+func FindComposerFiles(ctx context.Context, rootDir string, opts options.Options) ([]string, []string, error) {
+	composerFilename := opts.ComposerFilename
+	if composerFilename == "" {
+		composerFilename = options.DEFAULT_COMPOSER_FILENAME
+	}
 
-	log.Printf("FindComposerFiles Debug - searching in: %s", rootDir)
+	diagnostics.Logger(ctx).Printf("FindComposerFiles Debug - searching in: %s", rootDir)
 	
-	err := filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// Skip vendor directories
-		if info.IsDir() && info.Name() == "vendor" {
-			return filepath.SkipDir
-		}
-
-		// Skip node_modules directories (in case of mixed projects)
-		if info.IsDir() && info.Name() == "node_modules" {
-			return filepath.SkipDir
-		}
-
-		if !info.IsDir() {
-			if info.Name() == "composer.json" {
-				composerJSONFiles = append(composerJSONFiles, path)
-			} else if info.Name() == "composer.lock" {
-				composerLockFiles = append(composerLockFiles, path)
-			}
-		}
-
-		return nil
-	})
-
-	return composerJSONFiles, composerLockFiles, err
+	result, err := scanner.New(opts, scanner.ComposerJSON(composerFilename), scanner.ComposerLock(opts.LockFilename())).Scan(ctx, rootDir)
+	return result.Get(scanner.KIND_COMPOSER_JSON), result.Get(scanner.KIND_COMPOSER_LOCK), err
 }
 
 // FindPHARFiles searches for PHAR archives in a directory
-func FindPHARFiles(rootDir string) ([]string, error) {
-	var pharFiles []string
-
-	log.Printf("FindPHARFiles Debug - searching for PHAR archives in: %s", rootDir)
+func FindPHARFiles(ctx context.Context, rootDir string, opts options.Options) ([]string, error) {
+	diagnostics.Logger(ctx).Printf("FindPHARFiles Debug - searching for PHAR archives in: %s", rootDir)
 	
-	err := filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// Skip vendor and node_modules directories
-		if info.IsDir() && (info.Name() == "vendor" || info.Name() == "node_modules") {
-			return filepath.SkipDir
-		}
-
-		if !info.IsDir() {
-			// Check for .phar files
-			if strings.HasSuffix(strings.ToLower(info.Name()), ".phar") {
-				pharFiles = append(pharFiles, path)
-				log.Printf("Found PHAR file: %s", path)
-			}
-		}
-
-		return nil
-	})
-
-	return pharFiles, err
+	result, err := scanner.New(opts, scanner.PHAR()).Scan(ctx, rootDir)
+	return result.Get(scanner.KIND_PHAR), err
 }
 
 // PHARInfo represents information about a PHAR archive
@@ -207,7 +356,6 @@ func AnalyzePHARFile(pharPath string) (*PHARInfo, error) {
 	// Try to extract basic information about the PHAR
 	// Note: This is a simplified implementation. In production, you might want
 	// to use actual PHAR reading libraries or external tools
-	log.Printf("Analyzing PHAR file: %s (size: %d bytes)", pharPath, info.Size())
 
 	return pharInfo, nil
 }
@@ -230,6 +378,35 @@ func GetPackageName(fullName string) (vendor string, pkg string) {
 	return "", fullName
 }
 
+// ParseInlineAlias splits an inline alias constraint such as
+// "dev-main as 2.1.x-dev" into the actual version and its alias. The commit
+// a branch is pinned to, as in "dev-main#abc123 as 2.1.x-dev", is not part
+// of the version.
+func ParseInlineAlias(constraint string) (version string, alias string, ok bool) {
+	version, alias, ok = strings.Cut(strings.Join(strings.Fields(constraint), " "), " as ")
+	if !ok || version == "" || alias == "" || strings.Contains(alias, " ") {
+		return constraint, "", false
+	}
+	version, _, _ = strings.Cut(version, "#")
+	return version, alias, true
+}
+
+// BranchAliases returns the extra.branch-alias map of a package,
+// e.g. {"dev-main": "2.1.x-dev"}
+func BranchAliases(extra map[string]any) map[string]string {
+	raw, ok := extra["branch-alias"].(map[string]any)
+	if !ok {
+		return nil
+	}
+	aliases := make(map[string]string, len(raw))
+	for branch, alias := range raw {
+		if aliasString, ok := alias.(string); ok {
+			aliases[branch] = aliasString
+		}
+	}
+	return aliases
+}
+
 // NormalizeLicense converts license field to string array
 func NormalizeLicense(license any) []string {
 	switch v := license.(type) {
@@ -246,4 +423,38 @@ func NormalizeLicense(license any) []string {
 	default:
 		return []string{}
 	}
-}
\ No newline at end of file
+}
+// Platform package types
+const (
+	PLATFORM_PHP       = "php"
+	PLATFORM_EXTENSION = "extension"
+	PLATFORM_LIBRARY   = "library"
+	PLATFORM_COMPOSER  = "composer"
+)
+
+// PlatformPackageType returns the kind of platform package a requirement targets
+func PlatformPackageType(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasPrefix(name, "ext-"):
+		return PLATFORM_EXTENSION
+	case strings.HasPrefix(name, "lib-"):
+		return PLATFORM_LIBRARY
+	case strings.HasPrefix(name, "composer"):
+		return PLATFORM_COMPOSER
+	default:
+		return PLATFORM_PHP
+	}
+}
+
+// IsPlatformPackage reports whether a requirement targets the platform (PHP,
+// extensions, system libraries or Composer itself) instead of a package
+func IsPlatformPackage(name string) bool {
+	name = strings.ToLower(name)
+	switch name {
+	case "php", "php-64bit", "php-ipv6", "php-zts", "php-debug", "hhvm",
+		"composer", "composer-plugin-api", "composer-runtime-api":
+		return true
+	}
+	return strings.HasPrefix(name, "ext-") || strings.HasPrefix(name, "lib-")
+}
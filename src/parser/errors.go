@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// SyntaxError is a manifest that is not valid JSON, or whose values do not
+// have the expected types, with the position where decoding stopped
+type SyntaxError struct {
+	// Name is the kind of manifest, such as composer.lock
+	Name string
+	// File is the path of the manifest, when it was read from disk
+	File string
+	Position
+	Err error
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("failed to parse %s at line %d, column %d: %v", e.Name, e.Line, e.Column, e.Err)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// decodeError locates a json.Unmarshal error in data. Errors without an
+// offset are wrapped as they are.
+func decodeError(name string, data []byte, err error) error {
+	var offset int64
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxError):
+		offset = syntaxError.Offset
+	case errors.As(err, &typeError):
+		offset = typeError.Offset
+	default:
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+
+	// The offset is the number of bytes read when the error occurred, the
+	// position is the one of the last byte read
+	if offset > 0 {
+		offset--
+	}
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return &SyntaxError{
+		Name:     name,
+		Position: newLineIndex(data).position(int(offset)),
+		Err:      err,
+	}
+}
+
+// withFile records the path of the manifest in a SyntaxError
+func withFile(err error, filePath string) error {
+	var syntaxError *SyntaxError
+	if errors.As(err, &syntaxError) {
+		syntaxError.File = filePath
+	}
+	return err
+}
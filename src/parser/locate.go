@@ -0,0 +1,132 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Position is a 1-based line and column in a file
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// KeyPositions maps JSON pointers (RFC 6901) of object keys, such as
+// /require/symfony~1console, to the position of the key in the document
+type KeyPositions map[string]Position
+
+// JSONPointer builds the JSON pointer of a path, escaping ~ and /
+func JSONPointer(parts ...string) string {
+	var builder strings.Builder
+	for _, part := range parts {
+		builder.WriteByte('/')
+		part = strings.ReplaceAll(part, "~", "~0")
+		builder.WriteString(strings.ReplaceAll(part, "/", "~1"))
+	}
+	return builder.String()
+}
+
+// LocateKeys records the position of every object key of a JSON document
+func LocateKeys(data []byte) (KeyPositions, error) {
+	type frame struct {
+		object bool
+		key    string
+		index  int
+		// expectKey is true when the next token of an object is a key
+		expectKey bool
+	}
+
+	positions := make(KeyPositions)
+	lines := newLineIndex(data)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var stack []*frame
+	path := func() []string {
+		parts := make([]string, 0, len(stack))
+		for _, f := range stack {
+			if f.object {
+				parts = append(parts, f.key)
+			} else {
+				parts = append(parts, strconv.Itoa(f.index))
+			}
+		}
+		return parts
+	}
+	// valueDone advances the parent frame once one of its values is read
+	valueDone := func() {
+		if len(stack) == 0 {
+			return
+		}
+		parent := stack[len(stack)-1]
+		if parent.object {
+			parent.expectKey = true
+		} else {
+			parent.index++
+		}
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return positions, nil
+		}
+		if err != nil {
+			return positions, err
+		}
+
+		if len(stack) > 0 {
+			top := stack[len(stack)-1]
+			if key, ok := token.(string); ok && top.object && top.expectKey {
+				top.key = key
+				top.expectKey = false
+				positions[JSONPointer(path()...)] = lines.position(keyStart(data, int(decoder.InputOffset())))
+				continue
+			}
+		}
+
+		switch token {
+		case json.Delim('{'):
+			stack = append(stack, &frame{object: true, expectKey: true})
+		case json.Delim('['):
+			stack = append(stack, &frame{})
+		case json.Delim('}'), json.Delim(']'):
+			stack = stack[:len(stack)-1]
+			valueDone()
+		default:
+			valueDone()
+		}
+	}
+}
+
+// keyStart returns the offset of the opening quote of the key ending at end
+func keyStart(data []byte, end int) int {
+	for i := end - 2; i >= 0; i-- {
+		if data[i] == '"' && (i == 0 || data[i-1] != '\\') {
+			return i
+		}
+	}
+	return 0
+}
+
+// lineIndex converts byte offsets to line and column positions
+type lineIndex []int
+
+func newLineIndex(data []byte) lineIndex {
+	starts := lineIndex{0}
+	for i, b := range data {
+		if b == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+func (l lineIndex) position(offset int) Position {
+	line := sort.Search(len(l), func(i int) bool { return l[i] > offset }) - 1
+	return Position{Line: line + 1, Column: offset - l[line] + 1}
+}
@@ -0,0 +1,37 @@
+package parser
+
+import (
+	"context"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/diagnostics"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/options"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/scanner"
+)
+
+// ProjectMatchers are the files of a project the analysis reads: manifests,
+// lock files, installed.json, Phive and Dockerfiles, and PHAR archives when
+// the options scan them
+func ProjectMatchers(opts options.Options) []scanner.Matcher {
+	composerFilename := opts.ComposerFilename
+	if composerFilename == "" {
+		composerFilename = options.DEFAULT_COMPOSER_FILENAME
+	}
+	matchers := []scanner.Matcher{
+		scanner.ComposerJSON(composerFilename),
+		scanner.ComposerLock(opts.LockFilename()),
+		scanner.InstalledJSON(),
+		scanner.Phive(),
+		scanner.Dockerfile(),
+	}
+	if opts.ScanPHARs {
+		matchers = append(matchers, scanner.PHAR())
+	}
+	return matchers
+}
+
+// ScanProject finds the files of ProjectMatchers in a single walk of rootDir.
+// When ctx is done, the files found so far are returned with its error.
+func ScanProject(ctx context.Context, rootDir string, opts options.Options) (scanner.Result, error) {
+	diagnostics.Logger(ctx).Printf("PHP SBOM Debug - scanning %s", rootDir)
+	return scanner.New(opts, ProjectMatchers(opts)...).Scan(ctx, rootDir)
+}
@@ -0,0 +1,112 @@
+package src
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/constraint"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/diagnostics"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/project_finder"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+)
+
+// Sources a PHP constraint can conflict with
+const (
+	CONFLICTS_WITH_ROOT     = "root"
+	CONFLICTS_WITH_PLATFORM = "platform"
+)
+
+// computePHPCompatibility intersects the PHP constraints of the root and of
+// every package of the workspace to compute the PHP range the application
+// supports, and flags the packages conflicting with the root constraint or
+// with config.platform.php. Invalid constraints are left out and reported as
+// warnings. Only the root workspace is checked: the other workspaces of a
+// monorepo are installed with it, or on their own with their own PHP.
+func computePHPCompatibility(ctx context.Context, projectInfo *project_finder.ProjectInfo, workspace types.WorkSpace) *types.PHPCompatibility {
+	collector := diagnostics.FromContext(ctx)
+	compatibility := &types.PHPCompatibility{
+		Satisfiable: true,
+	}
+	composerJSON := projectInfo.ComposerJSON
+	rootName := "the root package"
+
+	effective := constraint.Any()
+	rootSet := constraint.Any()
+	if composerJSON != nil {
+		compatibility.RootConstraint = composerJSON.Require["php"]
+		compatibility.PlatformVersion = composerJSON.Config.Platform["php"]
+		if composerJSON.Name != "" {
+			rootName = composerJSON.Name
+		}
+	}
+	if compatibility.RootConstraint != "" {
+		set, err := constraint.Parse(compatibility.RootConstraint)
+		if err != nil {
+			diagnostics.Logger(ctx).Printf("Warning: invalid root PHP constraint %q: %v", compatibility.RootConstraint, err)
+			collector.Warn(diagnostics.WARNING_INVALID_PHP_CONSTRAINT, fmt.Sprintf("Invalid PHP constraint %q of %s ignored: %v", compatibility.RootConstraint, rootName, err), diagnostics.Location{File: projectInfo.RelativeComposerJSON})
+		} else {
+			rootSet = set
+			effective = set
+		}
+	}
+
+	var platformVersion *constraint.Version
+	if compatibility.PlatformVersion != "" {
+		version, err := constraint.ParseVersion(compatibility.PlatformVersion)
+		if err != nil {
+			diagnostics.Logger(ctx).Printf("Warning: invalid config.platform.php version %q: %v", compatibility.PlatformVersion, err)
+			collector.Warn(diagnostics.WARNING_INVALID_PHP_CONSTRAINT, fmt.Sprintf("Invalid config.platform.php version %q of %s ignored: %v", compatibility.PlatformVersion, rootName, err), diagnostics.Location{File: projectInfo.RelativeComposerJSON})
+		} else {
+			platformVersion = &version
+		}
+	}
+
+	for _, name := range sortedKeys(workspace.Dependencies) {
+		versions := workspace.Dependencies[name]
+		for _, version := range sortedKeys(versions) {
+			info := versions[version]
+			if info.PHPVersion == "" {
+				continue
+			}
+			set, err := constraint.Parse(info.PHPVersion)
+			if err != nil {
+				diagnostics.Logger(ctx).Printf("Warning: invalid PHP constraint %q in %s: %v", info.PHPVersion, name, err)
+				collector.Warn(diagnostics.WARNING_INVALID_PHP_CONSTRAINT, fmt.Sprintf("Invalid PHP constraint %q of %s@%s ignored: %v", info.PHPVersion, name, version, err), diagnostics.Location{File: projectInfo.RelativeComposerLock})
+				continue
+			}
+
+			conflict := types.PHPConflict{
+				Package:    name,
+				Version:    version,
+				Constraint: info.PHPVersion,
+				Dev:        !info.Prod,
+			}
+			if rootSet.Intersect(set).IsEmpty() {
+				conflict.ConflictsWith = CONFLICTS_WITH_ROOT
+				compatibility.Conflicts = append(compatibility.Conflicts, conflict)
+			}
+			if platformVersion != nil && !set.Contains(*platformVersion) {
+				conflict.ConflictsWith = CONFLICTS_WITH_PLATFORM
+				compatibility.Conflicts = append(compatibility.Conflicts, conflict)
+			}
+
+			// Dev packages are not installed in production
+			if info.Prod {
+				effective = effective.Intersect(set)
+			}
+		}
+	}
+
+	sort.Slice(compatibility.Conflicts, func(i, j int) bool {
+		a, b := compatibility.Conflicts[i], compatibility.Conflicts[j]
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		return a.ConflictsWith < b.ConflictsWith
+	})
+
+	compatibility.Satisfiable = !effective.IsEmpty()
+	compatibility.EffectiveRange = effective.String()
+	return compatibility
+}
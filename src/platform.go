@@ -0,0 +1,138 @@
+package src
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/graph"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/parser"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+)
+
+// ROOT_PACKAGE_NAME is the name Composer gives to a root package without a name
+const ROOT_PACKAGE_NAME = "__root__"
+
+// buildPlatformRequirements collects the platform requirements of the root
+// package and of every locked package, along with the platform values recorded
+// in the lock, the overrides configured in composer.json and the packages
+// providing them
+func buildPlatformRequirements(composerJSON *parser.ComposerJSON, composerLock *parser.ComposerLock, dependencies map[string]map[string]types.Versions, dependencyGraph *graph.Graph) map[string]types.PlatformRequirement {
+	requirements := make(map[string]*types.PlatformRequirement)
+
+	add := func(name string, constraint types.PlatformConstraint) {
+		key := strings.ToLower(name)
+		requirement, ok := requirements[key]
+		if !ok {
+			requirement = &types.PlatformRequirement{
+				Name: key,
+				Type: parser.PlatformPackageType(key),
+			}
+			requirements[key] = requirement
+		}
+		requirement.Constraints = append(requirement.Constraints, constraint)
+		if constraint.Dev {
+			requirement.Dev = true
+		} else {
+			requirement.Prod = true
+		}
+	}
+
+	if composerJSON != nil {
+		rootName := getRootPackageName(composerJSON)
+		for name, constraint := range composerJSON.Require {
+			if parser.IsPlatformPackage(name) {
+				add(name, types.PlatformConstraint{RequiredBy: rootName, Constraint: constraint})
+			}
+		}
+		for name, constraint := range composerJSON.RequireDev {
+			if parser.IsPlatformPackage(name) {
+				add(name, types.PlatformConstraint{RequiredBy: rootName, Constraint: constraint, Dev: true})
+			}
+		}
+	}
+
+	for packageName, versions := range dependencies {
+		for version, info := range versions {
+			for name, constraint := range info.Requires {
+				if parser.IsPlatformPackage(name) {
+					add(name, types.PlatformConstraint{
+						RequiredBy: packageName,
+						Version:    version,
+						Constraint: constraint,
+						Dev:        !info.Prod,
+					})
+				}
+			}
+		}
+	}
+
+	// Values recorded in the lock and configured overrides
+	overrides := make(map[string]string)
+	if composerJSON != nil {
+		for name, value := range composerJSON.Config.Platform {
+			overrides[strings.ToLower(name)] = value
+		}
+	}
+	if composerLock != nil {
+		for name, value := range composerLock.PlatformOverrides {
+			overrides[strings.ToLower(name)] = value
+		}
+		for name, constraint := range composerLock.Platform {
+			if requirement, ok := requirements[strings.ToLower(name)]; ok {
+				requirement.LockConstraint = constraint
+			}
+		}
+		for name, constraint := range composerLock.PlatformDev {
+			if requirement, ok := requirements[strings.ToLower(name)]; ok && requirement.LockConstraint == "" {
+				requirement.LockConstraint = constraint
+			}
+		}
+	}
+	for name, value := range overrides {
+		if requirement, ok := requirements[name]; ok {
+			requirement.Override = value
+		}
+	}
+	for name, requirement := range requirements {
+		requirement.ProvidedBy = dependencyGraph.ProvidersOf(name)
+	}
+
+	result := make(map[string]types.PlatformRequirement, len(requirements))
+	for name, requirement := range requirements {
+		constraints := requirement.Constraints
+		sort.Slice(constraints, func(i, j int) bool {
+			if constraints[i].RequiredBy != constraints[j].RequiredBy {
+				return constraints[i].RequiredBy < constraints[j].RequiredBy
+			}
+			if constraints[i].Dev != constraints[j].Dev {
+				return !constraints[i].Dev
+			}
+			return constraints[i].Constraint < constraints[j].Constraint
+		})
+		result[name] = *requirement
+	}
+	return result
+}
+
+// requiredExtensions lists the PHP extensions needed in production by any workspace
+func requiredExtensions(workspaces map[string]types.WorkSpace) []string {
+	seen := make(map[string]bool)
+	extensions := []string{}
+	for _, workspace := range workspaces {
+		for name, requirement := range workspace.Platform {
+			if requirement.Type == parser.PLATFORM_EXTENSION && requirement.Prod && !seen[name] {
+				seen[name] = true
+				extensions = append(extensions, name)
+			}
+		}
+	}
+	sort.Strings(extensions)
+	return extensions
+}
+
+func getRootPackageName(composerJSON *parser.ComposerJSON) string {
+	if composerJSON != nil && composerJSON.Name != "" {
+		return composerJSON.Name
+	}
+	return ROOT_PACKAGE_NAME
+}
@@ -0,0 +1,166 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/diagnostics"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+)
+
+// Kinds of policy rules
+const (
+	// RULE_SEVERITY fails on constraint findings at or above a severity, e.g. severity:high
+	RULE_SEVERITY = "severity"
+	// RULE_CONSTRAINT fails on constraint findings of a rule, e.g. constraint:wildcard
+	RULE_CONSTRAINT = "constraint"
+	// RULE_STABILITY fails on production packages with a stability risk, e.g. stability:fork
+	RULE_STABILITY = "stability"
+	// RULE_LICENSE fails on production packages under a license, e.g. license:GPL-3.0-only
+	RULE_LICENSE = "license"
+	// RULE_PHP_CONFLICT fails when no PHP version satisfies every production package
+	RULE_PHP_CONFLICT = "php-conflict"
+)
+
+var severityRanks = map[string]int{"low": 1, "medium": 2, "high": 3}
+
+// Rule is a single fail-on condition
+type Rule struct {
+	Kind  string
+	Value string
+}
+
+// String renders the rule as written on the command line
+func (r Rule) String() string {
+	if r.Value == "" {
+		return r.Kind
+	}
+	return r.Kind + ":" + r.Value
+}
+
+// Policy is a set of fail-on conditions evaluated against an SBOM
+type Policy struct {
+	Rules []Rule
+}
+
+// Violation is a condition of the policy met by the SBOM
+type Violation struct {
+	Rule    string `json:"rule"`
+	Package string `json:"package,omitempty"`
+	Message string `json:"message"`
+}
+
+// Parse parses a comma separated list of rules such as
+// "severity:high,stability:fork,license:AGPL-3.0-only,php-conflict"
+func Parse(spec string) (Policy, error) {
+	var policy Policy
+	for _, raw := range strings.Split(spec, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		kind, value, _ := strings.Cut(raw, ":")
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		value = strings.TrimSpace(value)
+
+		switch kind {
+		case RULE_SEVERITY:
+			value = strings.ToLower(value)
+			if _, ok := severityRanks[value]; !ok {
+				return Policy{}, fmt.Errorf("invalid severity in %q, expected low, medium or high", raw)
+			}
+		case RULE_CONSTRAINT, RULE_STABILITY, RULE_LICENSE:
+			if value == "" {
+				return Policy{}, fmt.Errorf("missing value in %q", raw)
+			}
+		case RULE_PHP_CONFLICT:
+			if value != "" {
+				return Policy{}, fmt.Errorf("%s does not take a value", RULE_PHP_CONFLICT)
+			}
+		default:
+			return Policy{}, fmt.Errorf("unknown policy rule %q", raw)
+		}
+		policy.Rules = append(policy.Rules, Rule{Kind: kind, Value: value})
+	}
+	return policy, nil
+}
+
+// Evaluate returns the violations of the policy, sorted by rule, package and message
+func (p Policy) Evaluate(output types.Output) []Violation {
+	violations := []Violation{}
+	for _, rule := range p.Rules {
+		violations = append(violations, rule.evaluate(output)...)
+	}
+	sort.Slice(violations, func(i, j int) bool {
+		a, b := violations[i], violations[j]
+		if a.Rule != b.Rule {
+			return a.Rule < b.Rule
+		}
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		return a.Message < b.Message
+	})
+	return violations
+}
+
+func (r Rule) evaluate(output types.Output) []Violation {
+	var violations []Violation
+	seen := make(map[string]bool)
+	add := func(pkg string, message string) {
+		if key := pkg + "\x00" + message; !seen[key] {
+			seen[key] = true
+			violations = append(violations, Violation{Rule: r.String(), Package: pkg, Message: message})
+		}
+	}
+
+	switch r.Kind {
+	case RULE_SEVERITY, RULE_CONSTRAINT:
+		for _, workspace := range output.WorkSpaces {
+			for _, finding := range workspace.ConstraintFindings {
+				matches := finding.Rule == r.Value
+				if r.Kind == RULE_SEVERITY {
+					matches = severityRanks[finding.Severity] >= severityRanks[r.Value]
+				}
+				if matches {
+					location := diagnostics.Location{File: finding.File, Line: finding.Line}
+					add(finding.Dependency, fmt.Sprintf("%s: %s requires %q (%s)", finding.Rule, finding.RequiredBy, finding.Constraint, location))
+				}
+			}
+		}
+	case RULE_STABILITY, RULE_LICENSE:
+		for _, workspace := range output.WorkSpaces {
+			for name, versions := range workspace.Dependencies {
+				for version, info := range versions {
+					if !info.Prod {
+						continue
+					}
+					values := info.StabilityRisks
+					if r.Kind == RULE_LICENSE {
+						values = info.Licenses
+					}
+					for _, value := range values {
+						if strings.EqualFold(value, r.Value) {
+							add(name, fmt.Sprintf("%s@%s is %s %s", name, version, r.Kind, value))
+						}
+					}
+				}
+			}
+		}
+	case RULE_PHP_CONFLICT:
+		compatibility := output.AnalysisInfo.Extra.PHPCompatibility
+		if compatibility == nil {
+			break
+		}
+		if !compatibility.Satisfiable {
+			add("php", "no PHP version satisfies the root and every production package")
+		}
+		for _, conflict := range compatibility.Conflicts {
+			if !conflict.Dev {
+				add(conflict.Package, fmt.Sprintf("%s@%s requires php %s, which conflicts with the %s", conflict.Package, conflict.Version, conflict.Constraint, conflict.ConflictsWith))
+			}
+		}
+	}
+	return violations
+}
@@ -0,0 +1,248 @@
+package project_finder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/diagnostics"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/parser"
+)
+
+// WorkspaceSource is how the root manifest declares a workspace
+type WorkspaceSource string
+
+const (
+	// SOURCE_PATH_REPOSITORY is a repository of type path, e.g. {"type": "path", "url": "packages/*"}
+	SOURCE_PATH_REPOSITORY WorkspaceSource = "path-repository"
+	// SOURCE_MERGE_PLUGIN is a manifest included by wikimedia/composer-merge-plugin
+	SOURCE_MERGE_PLUGIN WorkspaceSource = "merge-plugin"
+	// SOURCE_MONOREPO_BUILDER is a package of a symplify/monorepo-builder configuration
+	SOURCE_MONOREPO_BUILDER WorkspaceSource = "monorepo-builder"
+)
+
+// MONOREPO_BUILDER_CONFIGS are the configuration files of symplify/monorepo-builder
+var MONOREPO_BUILDER_CONFIGS = []string{"monorepo-builder.php", "monorepo-builder.yml", "monorepo-builder.yaml"}
+
+// DEFAULT_MONOREPO_BUILDER_DIRECTORY holds the packages when the
+// configuration does not set packageDirectories
+const DEFAULT_MONOREPO_BUILDER_DIRECTORY = "packages"
+
+// workspaceDeclarations are the workspaces the root manifest declares
+type workspaceDeclarations struct {
+	// directories are the absolute directories of path repositories and merged manifests
+	directories map[string]WorkspaceSource
+	// packageDirectories are the absolute directories monorepo-builder finds packages under
+	packageDirectories []string
+}
+
+// source returns how the manifest of a directory is declared, or "" when the
+// root manifest does not declare it
+func (d workspaceDeclarations) source(dir string) WorkspaceSource {
+	dir = absolutePath(dir)
+	if source, ok := d.directories[dir]; ok {
+		return source
+	}
+	for _, packageDirectory := range d.packageDirectories {
+		if strings.HasPrefix(dir, packageDirectory+string(os.PathSeparator)) {
+			return SOURCE_MONOREPO_BUILDER
+		}
+	}
+	return ""
+}
+
+// findWorkspaceDeclarations reads the path repositories and merge-plugin
+// includes of the root manifest, and the monorepo-builder configuration next to it
+func findWorkspaceDeclarations(ctx context.Context, rootComposerDir string, root *parser.ComposerJSON) workspaceDeclarations {
+	declarations := workspaceDeclarations{directories: make(map[string]WorkspaceSource)}
+	declare := func(dir string, source WorkspaceSource) {
+		dir = absolutePath(dir)
+		if _, ok := declarations.directories[dir]; !ok {
+			declarations.directories[dir] = source
+		}
+	}
+
+	for _, repository := range root.Repositories {
+		if repository.Type != "path" || repository.URL == "" {
+			continue
+		}
+		for _, dir := range expandGlob(rootComposerDir, repository.URL) {
+			if info, err := os.Stat(dir); err == nil && info.IsDir() {
+				declare(dir, SOURCE_PATH_REPOSITORY)
+			}
+		}
+	}
+
+	for _, pattern := range mergePluginIncludes(root) {
+		for _, file := range expandGlob(rootComposerDir, pattern) {
+			declare(filepath.Dir(file), SOURCE_MERGE_PLUGIN)
+		}
+	}
+
+	for _, name := range MONOREPO_BUILDER_CONFIGS {
+		data, err := os.ReadFile(filepath.Join(rootComposerDir, name))
+		if err != nil {
+			continue
+		}
+		directories := monorepoBuilderDirectories(name, string(data))
+		if len(directories) == 0 {
+			directories = []string{DEFAULT_MONOREPO_BUILDER_DIRECTORY}
+		}
+		for _, dir := range directories {
+			declarations.packageDirectories = append(declarations.packageDirectories, absolutePath(filepath.Join(rootComposerDir, filepath.FromSlash(dir))))
+		}
+		diagnostics.Logger(ctx).Printf("PHP SBOM Debug - %s declares the package directories %v", name, directories)
+		break
+	}
+
+	return declarations
+}
+
+// mergePluginIncludes returns the include and require globs of extra.merge-plugin
+func mergePluginIncludes(root *parser.ComposerJSON) []string {
+	config, ok := root.Extra["merge-plugin"].(map[string]any)
+	if !ok {
+		return nil
+	}
+	var patterns []string
+	for _, key := range []string{"include", "require"} {
+		switch value := config[key].(type) {
+		case string:
+			patterns = append(patterns, value)
+		case []any:
+			for _, item := range value {
+				if pattern, ok := item.(string); ok {
+					patterns = append(patterns, pattern)
+				}
+			}
+		}
+	}
+	return patterns
+}
+
+var (
+	monorepoBuilderCall     = regexp.MustCompile(`(?s)packageDirectories\s*\(\s*\[(.*?)\]`)
+	phpStringLiteral        = regexp.MustCompile(`'([^']*)'|"([^"]*)"`)
+	monorepoBuilderListItem = regexp.MustCompile(`^\s*-\s*['"]?([^'"#]+?)['"]?\s*$`)
+)
+
+// monorepoBuilderDirectories reads the package directories of a monorepo-builder
+// configuration, relative to its directory. The PHP configuration calls
+// $mbConfig->packageDirectories([__DIR__ . '/packages']), the legacy YAML one
+// lists them under package_directories.
+func monorepoBuilderDirectories(name string, content string) []string {
+	var directories []string
+	if strings.HasSuffix(name, ".php") {
+		call := monorepoBuilderCall.FindStringSubmatch(content)
+		if call == nil {
+			return nil
+		}
+		for _, literal := range phpStringLiteral.FindAllStringSubmatch(call[1], -1) {
+			dir := literal[1] + literal[2]
+			if dir = strings.Trim(dir, "/"); dir != "" {
+				directories = append(directories, dir)
+			}
+		}
+		return directories
+	}
+
+	inList := false
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "package_directories:") {
+			inList = true
+			continue
+		}
+		if !inList || trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		item := monorepoBuilderListItem.FindStringSubmatch(line)
+		if item == nil {
+			break
+		}
+		dir := strings.TrimPrefix(strings.TrimSpace(item[1]), "%kernel.project_dir%")
+		if dir = strings.Trim(dir, "/"); dir != "" {
+			directories = append(directories, dir)
+		}
+	}
+	return directories
+}
+
+// expandGlob returns the paths matching a glob relative to dir. Like
+// Composer, {a,b} alternatives are expanded before matching.
+func expandGlob(dir string, pattern string) []string {
+	var matches []string
+	for _, expanded := range expandBraces(filepath.FromSlash(pattern)) {
+		if !filepath.IsAbs(expanded) {
+			expanded = filepath.Join(dir, expanded)
+		}
+		found, err := filepath.Glob(expanded)
+		if err != nil {
+			continue
+		}
+		matches = append(matches, found...)
+	}
+	return matches
+}
+
+// expandBraces expands the first {a,b} group of a pattern, then the others
+func expandBraces(pattern string) []string {
+	open := strings.IndexByte(pattern, '{')
+	if open < 0 {
+		return []string{pattern}
+	}
+	end := strings.IndexByte(pattern[open:], '}')
+	if end < 0 {
+		return []string{pattern}
+	}
+	end += open
+	var expanded []string
+	for _, alternative := range strings.Split(pattern[open+1:end], ",") {
+		expanded = append(expanded, expandBraces(pattern[:open]+alternative+pattern[end+1:])...)
+	}
+	return expanded
+}
+
+// findPathLockEntry returns the entry of the root lock file installing a
+// workspace from its directory, with dist type path, and whether it is a dev
+// package. The entry is matched by its path, then by name for lock files
+// written on another machine.
+func findPathLockEntry(rootComposerDir string, lock *parser.ComposerLock, workspaceDir string, name string) (*parser.PackageInfo, bool) {
+	if lock == nil {
+		return nil, false
+	}
+	workspaceDir = absolutePath(workspaceDir)
+	var byName *parser.PackageInfo
+	byNameDev := false
+	for _, group := range []struct {
+		packages []parser.PackageInfo
+		dev      bool
+	}{{lock.Packages, false}, {lock.PackagesDev, true}} {
+		for i := range group.packages {
+			pkg := &group.packages[i]
+			if pkg.Dist.Type != "path" {
+				continue
+			}
+			url := filepath.FromSlash(pkg.Dist.URL)
+			if !filepath.IsAbs(url) {
+				url = filepath.Join(rootComposerDir, url)
+			}
+			if absolutePath(url) == workspaceDir {
+				return pkg, group.dev
+			}
+			if byName == nil && name != "" && pkg.Name == name {
+				byName, byNameDev = pkg, group.dev
+			}
+		}
+	}
+	return byName, byNameDev
+}
+
+func absolutePath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
@@ -1,12 +1,17 @@
 package project_finder
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/CodeClarityCE/plugin-php-sbom/src/diagnostics"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/options"
 	"github.com/CodeClarityCE/plugin-php-sbom/src/parser"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/scanner"
 )
 
 // ProjectInfo contains information about a PHP project
@@ -24,12 +29,17 @@ type ProjectInfo struct {
 	Framework            string // Laravel, Symfony, WordPress, etc.
 	IsMonorepo           bool
 	Workspaces           []WorkspaceInfo
+	UnrelatedManifests   []WorkspaceInfo   // Manifests found that the root manifest does not declare as workspaces
 	PHARFiles            []parser.PHARInfo // PHAR archives found in project
 	HasVendorDirectory   bool              // Whether project includes vendor dependencies
+	InstalledJSONFiles   []string          // vendor/composer/installed.json written by Composer
+	PhiveFiles           []string          // phive.xml and .phive/phars.xml
+	Dockerfiles          []string
 }
 
 // WorkspaceInfo represents a workspace in a monorepo
 type WorkspaceInfo struct {
+	ID                   string // Manifest path relative to the root manifest directory, separated by /
 	Name                 string
 	Path                 string
 	ComposerJSONPath     string
@@ -38,28 +48,47 @@ type WorkspaceInfo struct {
 	RelativeComposerLock string
 	ComposerJSON         *parser.ComposerJSON
 	ComposerLock         *parser.ComposerLock
+	Source               WorkspaceSource     // How the root manifest declares it, empty for unrelated manifests
+	LockEntry            *parser.PackageInfo // Root lock entry installing it from its path
+	LockedAsDev          bool                // Whether LockEntry is a dev package
 }
 
 // FindPHPProjects finds all PHP projects in the given directory
-func FindPHPProjects(rootDir string) (*ProjectInfo, error) {
-	composerJSONFiles, composerLockFiles, err := parser.FindComposerFiles(rootDir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to find composer files: %w", err)
-	}
+func FindPHPProjects(ctx context.Context, rootDir string, opts options.Options) (*ProjectInfo, error) {
+	// A single walk finds the manifests, lock files and PHAR archives, an
+	// interrupted one keeps the files found so far
+	files, err := parser.ScanProject(ctx, rootDir, opts)
+	return FindPHPProjectsInFiles(ctx, rootDir, opts, files, err)
+}
 
-	// Also search for PHAR files
-	pharFilePaths, err := parser.FindPHARFiles(rootDir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to find PHAR files: %w", err)
+// FindPHPProjectsInFiles finds the PHP project of the directory among the
+// files of a scan, scanErr being the error the scan returned
+func FindPHPProjectsInFiles(ctx context.Context, rootDir string, opts options.Options, files scanner.Result, scanErr error) (*ProjectInfo, error) {
+	collector := diagnostics.FromContext(ctx)
+	err := scanErr
+
+	composerJSONFiles := files.Get(scanner.KIND_COMPOSER_JSON)
+	composerLockFiles := files.Get(scanner.KIND_COMPOSER_LOCK)
+	pharFilePaths := files.Get(scanner.KIND_PHAR)
+	if diagnostics.IsInterruption(err) && len(composerJSONFiles) > 0 {
+		collector.Interrupted(err, "the search for manifests did not complete, workspaces and PHAR archives may be missing")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to find composer files: %w", err)
 	}
 
 	if len(composerJSONFiles) == 0 {
-		return nil, fmt.Errorf("no composer.json files found")
+		return nil, fmt.Errorf("no %s files found", opts.ComposerFilename)
 	}
 
-	// Find the root project (closest to rootDir)
+	// Find the root project (the configured one, or else the closest to rootDir)
 	rootComposerJSON := findRootComposerFile(rootDir, composerJSONFiles)
-	rootComposerLock := findMatchingLockFile(rootComposerJSON, composerLockFiles)
+	if opts.RootManifest != "" {
+		rootComposerJSON, err = findConfiguredRootManifest(rootDir, opts.RootManifest, composerJSONFiles)
+		if err != nil {
+			return nil, err
+		}
+	}
+	rootComposerLock := findMatchingLockFile(rootComposerJSON, composerLockFiles, opts.LockFilename())
 
 	// Parse root composer.json
 	composerData, err := parser.ParseComposerJSON(rootComposerJSON)
@@ -82,6 +111,9 @@ func FindPHPProjects(rootDir string) (*ProjectInfo, error) {
 		Workspaces:           []WorkspaceInfo{},
 		PHARFiles:            []parser.PHARInfo{},
 		HasVendorDirectory:   checkVendorDirectory(rootDir),
+		InstalledJSONFiles:   files.Get(scanner.KIND_INSTALLED_JSON),
+		PhiveFiles:           files.Get(scanner.KIND_PHIVE),
+		Dockerfiles:          files.Get(scanner.KIND_DOCKERFILE),
 	}
 
 	// Parse composer.lock if it exists
@@ -89,21 +121,29 @@ func FindPHPProjects(rootDir string) (*ProjectInfo, error) {
 		lockData, err := parser.ParseComposerLock(rootComposerLock)
 		if err == nil {
 			projectInfo.ComposerLock = lockData
+		} else {
+			collector.Warn(diagnostics.WARNING_INVALID_LOCK_FILE, fmt.Sprintf("Lock file ignored, the project is analyzed from composer.json only: %v", err), errorLocation(projectInfo.RelativeComposerLock, err))
 		}
 	}
 
-	// Check for monorepo/workspaces
+	// Check for monorepo/workspaces, the other manifests are unrelated projects
 	if len(composerJSONFiles) > 1 {
-		projectInfo.IsMonorepo = true
-		projectInfo.Workspaces = findWorkspaces(rootComposerJSON, composerJSONFiles, composerLockFiles)
+		declarations := findWorkspaceDeclarations(ctx, filepath.Dir(rootComposerJSON), composerData)
+		projectInfo.Workspaces, projectInfo.UnrelatedManifests = findWorkspaces(ctx, projectInfo, declarations, composerJSONFiles, composerLockFiles, opts.LockFilename())
+		projectInfo.IsMonorepo = len(projectInfo.Workspaces) > 0
 	}
 
 	// Process PHAR files
-	for _, pharPath := range pharFilePaths {
+	for i, pharPath := range pharFilePaths {
+		if err := ctx.Err(); err != nil {
+			collector.Interrupted(err, fmt.Sprintf("%d PHAR archives not analyzed", len(pharFilePaths)-i))
+			break
+		}
+		diagnostics.Logger(ctx).Printf("Analyzing PHAR file: %s", pharPath)
 		pharInfo, err := parser.AnalyzePHARFile(pharPath)
 		if err != nil {
-			// Log error but continue processing
-			fmt.Printf("Warning: Failed to analyze PHAR file %s: %v\n", pharPath, err)
+			// Report the error but continue processing
+			collector.Warn(diagnostics.WARNING_INVALID_PHAR, fmt.Sprintf("Failed to analyze PHAR file: %v", err), diagnostics.Location{File: getRelativePath(rootDir, pharPath)})
 			continue
 		}
 		projectInfo.PHARFiles = append(projectInfo.PHARFiles, *pharInfo)
@@ -129,10 +169,22 @@ func findRootComposerFile(rootDir string, composerFiles []string) string {
 	return rootFile
 }
 
-// findMatchingLockFile finds the composer.lock file in the same directory as composer.json
-func findMatchingLockFile(composerJSONPath string, lockFiles []string) string {
+// findConfiguredRootManifest returns the found manifest at the path
+// configured relative to the root directory
+func findConfiguredRootManifest(rootDir string, rootManifest string, composerFiles []string) (string, error) {
+	expected, _ := filepath.Abs(filepath.Join(rootDir, filepath.FromSlash(rootManifest)))
+	for _, file := range composerFiles {
+		if absFile, _ := filepath.Abs(file); absFile == expected {
+			return file, nil
+		}
+	}
+	return "", fmt.Errorf("root manifest %s not found", rootManifest)
+}
+
+// findMatchingLockFile finds the lock file in the same directory as composer.json
+func findMatchingLockFile(composerJSONPath string, lockFiles []string, lockFilename string) string {
 	dir := filepath.Dir(composerJSONPath)
-	expectedLockPath := filepath.Join(dir, "composer.lock")
+	expectedLockPath := filepath.Join(dir, lockFilename)
 
 	for _, lockFile := range lockFiles {
 		if lockFile == expectedLockPath {
@@ -219,12 +271,20 @@ func detectFramework(composerData *parser.ComposerJSON) string {
 	return "Generic PHP"
 }
 
-// findWorkspaces finds all workspace projects in a monorepo
-func findWorkspaces(rootComposerPath string, composerFiles, lockFiles []string) []WorkspaceInfo {
+// findWorkspaces splits the manifests other than the root one into the
+// workspaces the root declares and the unrelated manifests
+func findWorkspaces(ctx context.Context, project *ProjectInfo, declarations workspaceDeclarations, composerFiles, lockFiles []string, lockFilename string) ([]WorkspaceInfo, []WorkspaceInfo) {
 	var workspaces []WorkspaceInfo
+	var unrelated []WorkspaceInfo
+	rootComposerPath := project.ComposerJSONPath
 	rootComposerDir := filepath.Dir(rootComposerPath)
 
-	for _, composerFile := range composerFiles {
+	for i, composerFile := range composerFiles {
+		if err := ctx.Err(); err != nil {
+			diagnostics.FromContext(ctx).Interrupted(err, fmt.Sprintf("%d manifests not analyzed", len(composerFiles)-i))
+			break
+		}
+
 		// Skip the root composer.json
 		if composerFile == rootComposerPath {
 			continue
@@ -235,18 +295,26 @@ func findWorkspaces(rootComposerPath string, composerFiles, lockFiles []string)
 			continue
 		}
 
+		source := declarations.source(filepath.Dir(composerFile))
 		composerData, err := parser.ParseComposerJSON(composerFile)
 		if err != nil {
+			skipped := "Workspace skipped"
+			if source == "" {
+				skipped = "Manifest skipped"
+			}
+			diagnostics.FromContext(ctx).Warn(diagnostics.WARNING_INVALID_MANIFEST, fmt.Sprintf("%s: %v", skipped, err), errorLocation(getRelativePath(rootComposerDir, composerFile), err))
 			continue
 		}
 
 		workspace := WorkspaceInfo{
+			ID:                   filepath.ToSlash(getRelativePath(rootComposerDir, composerFile)),
 			Name:                 composerData.Name,
 			Path:                 filepath.Dir(composerFile),
 			ComposerJSONPath:     composerFile,
-			ComposerLockPath:     findMatchingLockFile(composerFile, lockFiles),
+			ComposerLockPath:     findMatchingLockFile(composerFile, lockFiles, lockFilename),
 			RelativeComposerJSON: getRelativePath(rootComposerDir, composerFile),
 			ComposerJSON:         composerData,
+			Source:               source,
 		}
 
 		// Parse workspace composer.lock if it exists
@@ -255,13 +323,33 @@ func findWorkspaces(rootComposerPath string, composerFiles, lockFiles []string)
 			lockData, err := parser.ParseComposerLock(workspace.ComposerLockPath)
 			if err == nil {
 				workspace.ComposerLock = lockData
+			} else {
+				diagnostics.FromContext(ctx).Warn(diagnostics.WARNING_INVALID_LOCK_FILE, fmt.Sprintf("Lock file ignored, the workspace is analyzed from composer.json only: %v", err), errorLocation(workspace.RelativeComposerLock, err))
 			}
 		}
 
+		if source == "" {
+			diagnostics.Logger(ctx).Printf("PHP SBOM Debug - %s is not declared by the root manifest, not a workspace", workspace.RelativeComposerJSON)
+			unrelated = append(unrelated, workspace)
+			continue
+		}
+		workspace.LockEntry, workspace.LockedAsDev = findPathLockEntry(rootComposerDir, project.ComposerLock, workspace.Path, workspace.Name)
 		workspaces = append(workspaces, workspace)
 	}
 
-	return workspaces
+	return workspaces, unrelated
+}
+
+// errorLocation is the position of the syntax error of a manifest, or the
+// manifest alone for other errors
+func errorLocation(relativePath string, err error) diagnostics.Location {
+	location := diagnostics.Location{File: relativePath}
+	var syntaxError *parser.SyntaxError
+	if errors.As(err, &syntaxError) {
+		location.Line = syntaxError.Line
+		location.Column = syntaxError.Column
+	}
+	return location
 }
 
 // getRelativePath gets the relative path from base to target
@@ -0,0 +1,101 @@
+package project_finder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/diagnostics"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/options"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/parser"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/scanner"
+)
+
+// FindProjectRoots returns the root manifests of the independent projects
+// among the files of a scan: the manifests with their own lock file, except
+// the workspaces another of them declares. Without any lock file, the
+// manifest closest to rootDir is the only root.
+func FindProjectRoots(ctx context.Context, rootDir string, files scanner.Result, opts options.Options) []string {
+	composerJSONFiles := files.Get(scanner.KIND_COMPOSER_JSON)
+	composerLockFiles := files.Get(scanner.KIND_COMPOSER_LOCK)
+
+	var candidates []string
+	for _, composerFile := range composerJSONFiles {
+		if findMatchingLockFile(composerFile, composerLockFiles, opts.LockFilename()) != "" {
+			candidates = append(candidates, composerFile)
+		}
+	}
+	if len(candidates) == 0 {
+		if root := findRootComposerFile(rootDir, composerJSONFiles); root != "" {
+			return []string{root}
+		}
+		return nil
+	}
+
+	// A workspace with its own lock belongs to the project declaring it
+	declared := map[string]bool{}
+	for _, candidate := range candidates {
+		composerData, err := parser.ParseComposerJSON(candidate)
+		if err != nil {
+			// Reported by the analysis of the project
+			continue
+		}
+		declarations := findWorkspaceDeclarations(ctx, filepath.Dir(candidate), composerData)
+		for _, other := range candidates {
+			if other != candidate && declarations.source(filepath.Dir(other)) != "" {
+				diagnostics.Logger(ctx).Printf("PHP SBOM Debug - %s is a workspace of %s, not a project", getRelativePath(rootDir, other), getRelativePath(rootDir, candidate))
+				declared[other] = true
+			}
+		}
+	}
+
+	var roots []string
+	for _, candidate := range candidates {
+		if !declared[candidate] {
+			roots = append(roots, candidate)
+		}
+	}
+	sort.Strings(roots)
+	return roots
+}
+
+// ProjectFiles returns the files of a scan that belong to the project of a
+// root manifest: those under its directory, except the ones under the
+// directory of another root
+func ProjectFiles(files scanner.Result, rootManifest string, roots []string) scanner.Result {
+	projectDir := filepath.Dir(rootManifest)
+	var nestedDirs []string
+	for _, root := range roots {
+		if dir := filepath.Dir(root); root != rootManifest && isWithin(dir, projectDir) {
+			nestedDirs = append(nestedDirs, dir)
+		}
+	}
+
+	result := scanner.Result{Files: make(map[scanner.Kind][]string), Skipped: files.Skipped}
+	for kind, paths := range files.Files {
+		for _, file := range paths {
+			if !isWithin(filepath.Dir(file), projectDir) {
+				continue
+			}
+			nested := false
+			for _, dir := range nestedDirs {
+				if isWithin(filepath.Dir(file), dir) {
+					nested = true
+					break
+				}
+			}
+			if !nested {
+				result.Files[kind] = append(result.Files[kind], file)
+			}
+		}
+	}
+	return result
+}
+
+// isWithin reports whether dir is parent or one of its subdirectories
+func isWithin(dir string, parent string) bool {
+	dir, parent = absolutePath(dir), absolutePath(parent)
+	return dir == parent || strings.HasPrefix(dir, parent+string(os.PathSeparator))
+}
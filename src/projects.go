@@ -0,0 +1,211 @@
+package src
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/diagnostics"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/options"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/parser"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/project_finder"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/scanner"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+	codeclarity "github.com/CodeClarityCE/utility-types/codeclarity_db"
+	exceptionManager "github.com/CodeClarityCE/utility-types/exceptions"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// StartProjects analyzes each independent PHP project of a directory, the
+// manifests with their own lock file, as its own project. It is the
+// multi-project mode of StartContext.
+func StartProjects(ctx context.Context, sourceCodeDir string, analysisId uuid.UUID, knowledge_db *bun.DB, opts options.Options) types.ProjectsOutput {
+	start := time.Now()
+	ctx, cancel := withTimeout(ctx, opts)
+	defer cancel()
+	ctx, _ = diagnostics.NewContext(ctx)
+	return analyzeProjects(ctx, start, sourceCodeDir, opts)
+}
+
+// StartProjectsAtRevision is StartProjects on the manifests of a commit,
+// branch or tag, as StartAtRevision reads them
+func StartProjectsAtRevision(ctx context.Context, sourceCodeDir string, revision string, analysisId uuid.UUID, knowledge_db *bun.DB, opts options.Options) types.ProjectsOutput {
+	start := time.Now()
+	ctx, cancel := withTimeout(ctx, opts)
+	defer cancel()
+	ctx, collector := diagnostics.NewContext(ctx)
+
+	snapshotDir, commit, failure := snapshotRevision(ctx, sourceCodeDir, revision, opts)
+	if failure != nil {
+		diagnostics.Logger(ctx).Printf("PHP SBOM Error - %s", failure.private)
+		collector.AddError(failure.public, exceptionManager.GENERIC_ERROR, failure.private, failure.kind)
+		return generateProjectsFailureOutput(ctx, start)
+	}
+	defer os.RemoveAll(snapshotDir)
+
+	output := analyzeProjects(ctx, start, snapshotDir, opts)
+	for id, project := range output.Projects {
+		relocateOutput(&project, snapshotDir, sourceCodeDir)
+		project.AnalysisInfo.Extra.Revision = revision
+		project.AnalysisInfo.Extra.Commit = commit.String()
+		output.Projects[id] = project
+	}
+	return output
+}
+
+// analyzeProjects scans sourceCodeDir once and analyzes each project found
+// with the files under its directory, and its own errors and warnings
+func analyzeProjects(ctx context.Context, start time.Time, sourceCodeDir string, opts options.Options) types.ProjectsOutput {
+	collector := diagnostics.FromContext(ctx)
+
+	if _, err := os.Stat(sourceCodeDir); os.IsNotExist(err) {
+		collector.AddError(
+			"Source directory not found",
+			exceptionManager.GENERIC_ERROR,
+			fmt.Sprintf("The source directory does not exist: %s", sourceCodeDir),
+			"SourceCodeDirDoesNotExist",
+		)
+		return generateProjectsFailureOutput(ctx, start)
+	}
+
+	files, err := parser.ScanProject(ctx, sourceCodeDir, opts)
+	if diagnostics.IsInterruption(err) && len(files.Get(scanner.KIND_COMPOSER_JSON)) > 0 {
+		collector.Interrupted(err, "the search for projects did not complete, projects may be missing")
+	} else if diagnostics.IsInterruption(err) {
+		collector.AddError(
+			"The analysis did not complete in time",
+			exceptionManager.GENERIC_ERROR,
+			fmt.Sprintf("Analysis interrupted before a project was found: %v", err),
+			diagnostics.ERROR_ANALYSIS_INTERRUPTED,
+		)
+		return generateProjectsFailureOutput(ctx, start)
+	} else if err != nil {
+		collector.AddError(
+			"No PHP project found in the source directory",
+			exceptionManager.UNSUPPORTED_LANGUAGE_REQUESTED,
+			fmt.Sprintf("Error finding PHP projects: %v", err),
+			exceptionManager.UNSUPPORTED_LANGUAGE_REQUESTED,
+		)
+		return generateProjectsFailureOutput(ctx, start)
+	}
+
+	roots := project_finder.FindProjectRoots(ctx, sourceCodeDir, files, opts)
+	if len(roots) == 0 {
+		collector.AddError(
+			"No PHP project found in the source directory",
+			exceptionManager.UNSUPPORTED_LANGUAGE_REQUESTED,
+			fmt.Sprintf("Error finding PHP projects: no %s files found", opts.ComposerFilename),
+			exceptionManager.UNSUPPORTED_LANGUAGE_REQUESTED,
+		)
+		return generateProjectsFailureOutput(ctx, start)
+	}
+	diagnostics.Logger(ctx).Printf("PHP SBOM Debug - %d projects found", len(roots))
+
+	output := types.ProjectsOutput{Projects: make(map[string]types.Output)}
+	summaries := []types.ProjectSummary{}
+	assigned := map[string]bool{}
+	for i, root := range roots {
+		if err := ctx.Err(); err != nil {
+			collector.Interrupted(err, fmt.Sprintf("%d projects not analyzed", len(roots)-i))
+			break
+		}
+
+		relativeRoot, _ := filepath.Rel(sourceCodeDir, root)
+		id := filepath.ToSlash(filepath.Dir(relativeRoot))
+		projectOpts := opts
+		projectOpts.RootManifest = filepath.ToSlash(relativeRoot)
+		projectFiles := project_finder.ProjectFiles(files, root, roots)
+		for _, manifest := range projectFiles.Get(scanner.KIND_COMPOSER_JSON) {
+			assigned[manifest] = true
+		}
+
+		// Each project has its own errors and warnings
+		projectCtx, _ := diagnostics.NewContext(ctx)
+		project := analyzeFiles(projectCtx, time.Now(), sourceCodeDir, projectOpts, projectFiles, nil)
+		output.Projects[id] = project
+		summaries = append(summaries, types.ProjectSummary{
+			ID:                  id,
+			Name:                project.AnalysisInfo.ProjectName,
+			Framework:           project.AnalysisInfo.Extra.Framework,
+			RelativePackageFile: project.AnalysisInfo.Paths.RelativePackageFile,
+			RelativeLockFile:    project.AnalysisInfo.Paths.RelativeLockFile,
+			Status:              project.AnalysisInfo.Status,
+			Workspaces:          len(project.WorkSpaces),
+			Dependencies:        getTotalDependencyCount(project.WorkSpaces),
+		})
+	}
+
+	var unrelated []string
+	for _, manifest := range files.Get(scanner.KIND_COMPOSER_JSON) {
+		if !assigned[manifest] {
+			relativePath, _ := filepath.Rel(sourceCodeDir, manifest)
+			unrelated = append(unrelated, relativePath)
+		}
+	}
+
+	end := time.Now()
+	output.Index = types.ProjectsIndex{
+		Status: projectsStatus(collector, summaries),
+		Time: types.Time{
+			AnalysisStartTime: start.Format(time.RFC3339),
+			AnalysisEndTime:   end.Format(time.RFC3339),
+			AnalysisDeltaTime: float64(end.Sub(start).Nanoseconds()) / 1e9,
+		},
+		Errors:             collector.Errors(),
+		Warnings:           collector.Warnings(),
+		Truncated:          collector.Truncated(),
+		Projects:           summaries,
+		UnrelatedManifests: unrelated,
+	}
+	for _, project := range output.Projects {
+		output.Index.Truncated = output.Index.Truncated || project.AnalysisInfo.Truncated
+	}
+	return output
+}
+
+// projectsStatus is a failure when no project could be analyzed, and a
+// success with warnings when a project failed or has warnings
+func projectsStatus(collector *diagnostics.Collector, summaries []types.ProjectSummary) codeclarity.AnalysisStatus {
+	failed := 0
+	status := codeclarity.SUCCESS
+	if collector.HasWarnings() {
+		status = types.SUCCESS_WITH_WARNINGS
+	}
+	for _, summary := range summaries {
+		switch summary.Status {
+		case codeclarity.FAILURE:
+			failed++
+			status = types.SUCCESS_WITH_WARNINGS
+		case types.SUCCESS_WITH_WARNINGS:
+			status = types.SUCCESS_WITH_WARNINGS
+		}
+	}
+	if failed == len(summaries) {
+		return codeclarity.FAILURE
+	}
+	return status
+}
+
+// generateProjectsFailureOutput generates the output of a multi-project
+// analysis that found no project
+func generateProjectsFailureOutput(ctx context.Context, start time.Time) types.ProjectsOutput {
+	end := time.Now()
+
+	return types.ProjectsOutput{
+		Index: types.ProjectsIndex{
+			Status: codeclarity.FAILURE,
+			Time: types.Time{
+				AnalysisStartTime: start.Format(time.RFC3339),
+				AnalysisEndTime:   end.Format(time.RFC3339),
+				AnalysisDeltaTime: float64(end.Sub(start).Nanoseconds()) / 1e9,
+			},
+			Errors:   diagnostics.FromContext(ctx).Errors(),
+			Warnings: diagnostics.FromContext(ctx).Warnings(),
+			Projects: []types.ProjectSummary{},
+		},
+		Projects: make(map[string]types.Output),
+	}
+}
@@ -0,0 +1,173 @@
+package src
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/diagnostics"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/gitobject"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/options"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/project_finder"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/scanner"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+	exceptionManager "github.com/CodeClarityCE/utility-types/exceptions"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// StartAtRevision analyzes the composer.json and composer.lock files of
+// sourceCodeDir as they are at a commit, branch or tag of the git repository
+// containing it. The files are read from the .git object database, so the
+// result does not depend on the state of the checkout. Files only found in
+// the working tree, such as vendor/ and PHAR archives, are not analyzed.
+func StartAtRevision(ctx context.Context, sourceCodeDir string, revision string, analysisId uuid.UUID, knowledge_db *bun.DB, opts options.Options) types.Output {
+	start := time.Now()
+	ctx, cancel := withTimeout(ctx, opts)
+	defer cancel()
+	ctx, _ = diagnostics.NewContext(ctx)
+
+	snapshotDir, commit, failure := snapshotRevision(ctx, sourceCodeDir, revision, opts)
+	if failure != nil {
+		return revisionFailure(ctx, start, failure.public, failure.private, failure.kind)
+	}
+	defer os.RemoveAll(snapshotDir)
+	diagnostics.Logger(ctx).Printf("PHP SBOM Debug - analyzing %s (%s) from the git object database", revision, commit)
+
+	output := analyze(ctx, start, snapshotDir, opts)
+	relocateOutput(&output, snapshotDir, sourceCodeDir)
+	output.AnalysisInfo.Extra.Revision = revision
+	output.AnalysisInfo.Extra.Commit = commit.String()
+	return output
+}
+
+// revisionError is a failure to read the manifests of a revision
+type revisionError struct {
+	public  string
+	private string
+	kind    exceptionManager.ERROR_TYPE
+}
+
+// snapshotRevision writes the manifests of sourceCodeDir at a revision to a
+// temporary directory, which the caller removes
+func snapshotRevision(ctx context.Context, sourceCodeDir string, revision string, opts options.Options) (string, gitobject.Hash, *revisionError) {
+	repository, err := gitobject.Open(sourceCodeDir)
+	if err != nil {
+		return "", gitobject.Hash{}, &revisionError{"No git repository found", fmt.Sprintf("Cannot open the git repository of %s: %v", sourceCodeDir, err), "GitRepositoryNotFound"}
+	}
+	commit, err := repository.ResolveRevision(revision)
+	if err != nil {
+		return "", gitobject.Hash{}, &revisionError{"Revision not found", fmt.Sprintf("Cannot resolve %s: %v", revision, err), "GitRevisionNotFound"}
+	}
+
+	subtree, err := repository.RelativePath(sourceCodeDir)
+	if err != nil {
+		return "", gitobject.Hash{}, &revisionError{"Failed to read the revision", err.Error(), "GitRevisionReadFailed"}
+	}
+	snapshotDir, err := os.MkdirTemp("", "php-sbom-revision-")
+	if err != nil {
+		return "", gitobject.Hash{}, &revisionError{"Failed to read the revision", err.Error(), "GitRevisionReadFailed"}
+	}
+	if err := materializeManifests(ctx, repository, commit, subtree, snapshotDir, opts); err != nil {
+		os.RemoveAll(snapshotDir)
+		return "", gitobject.Hash{}, &revisionError{"Failed to read the revision", fmt.Sprintf("Cannot read the manifests of %s: %v", commit, err), "GitRevisionReadFailed"}
+	}
+	return snapshotDir, commit, nil
+}
+
+// materializeManifests writes the files the analysis reads under subtree in a
+// commit to dir: the manifests, the ignore files and the monorepo-builder
+// configurations. Directories the scanner ignores are not walked.
+func materializeManifests(ctx context.Context, repository *gitobject.Repository, commit gitobject.Hash, subtree string, dir string, opts options.Options) error {
+	names := map[string]bool{opts.ComposerFilename: true, opts.LockFilename(): true, scanner.GITIGNORE: true, scanner.IGNORE_FILE: true}
+	for _, name := range project_finder.MONOREPO_BUILDER_CONFIGS {
+		names[name] = true
+	}
+
+	root := commit
+	if subtree != "" {
+		entry, err := repository.Entry(commit, subtree)
+		if errors.Is(err, gitobject.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		root = entry.Hash
+	}
+
+	ignores := scanner.NewIgnores(ctx, opts)
+	ignores.Load("", treeFileOpener(repository, root))
+	return repository.Walk(root, func(entryPath string, entry gitobject.TreeEntry) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if ignored, reason := ignores.Ignored(entryPath, true); ignored {
+				diagnostics.Logger(ctx).Printf("PHP SBOM Debug - skipped %s/: %s", entryPath, reason)
+				return gitobject.SkipDir
+			}
+			ignores.Load(entryPath, treeFileOpener(repository, entry.Hash))
+			return nil
+		}
+		// Ignored manifests are written too, the scan of the snapshot skips them
+		if !entry.IsFile() || !names[entry.Name] {
+			return nil
+		}
+
+		_, data, err := repository.Object(entry.Hash)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dir, filepath.FromSlash(path.Clean(entryPath)))
+		if relative, err := filepath.Rel(dir, target); err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("tree entry %s is outside of the snapshot", entryPath)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, 0o644)
+	})
+}
+
+// treeFileOpener opens the files of a tree by name
+func treeFileOpener(repository *gitobject.Repository, tree gitobject.Hash) func(name string) (io.ReadCloser, error) {
+	return func(name string) (io.ReadCloser, error) {
+		data, err := repository.File(tree, name)
+		if errors.Is(err, gitobject.ErrNotFound) {
+			return nil, fs.ErrNotExist
+		}
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+}
+
+// relocateOutput points the paths of an output produced in snapshotDir to sourceCodeDir
+func relocateOutput(output *types.Output, snapshotDir string, sourceCodeDir string) {
+	output.RelocatePaths(snapshotDir, func(p string) string {
+		relative, err := filepath.Rel(snapshotDir, p)
+		if err != nil {
+			return p
+		}
+		return filepath.Join(sourceCodeDir, relative)
+	})
+}
+
+func revisionFailure(ctx context.Context, start time.Time, publicDescription string, privateDescription string, privateType exceptionManager.ERROR_TYPE) types.Output {
+	diagnostics.Logger(ctx).Printf("PHP SBOM Error - %s", privateDescription)
+	diagnostics.FromContext(ctx).AddError(publicDescription, exceptionManager.GENERIC_ERROR, privateDescription, privateType)
+	return generateFailureOutput(ctx, start, "")
+}
@@ -1,14 +1,22 @@
 package src
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/CodeClarityCE/plugin-php-sbom/src/diagnostics"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/graph"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/options"
 	"github.com/CodeClarityCE/plugin-php-sbom/src/parser"
 	"github.com/CodeClarityCE/plugin-php-sbom/src/project_finder"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/scanner"
 	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
 	codeclarity "github.com/CodeClarityCE/utility-types/codeclarity_db"
 	exceptionManager "github.com/CodeClarityCE/utility-types/exceptions"
@@ -19,73 +27,178 @@ import (
 // Start is the entrypoint for the PHP SBOM plugin
 // Compatible with js-sbom Start function signature
 func Start(sourceCodeDir string, analysisId uuid.UUID, knowledge_db *bun.DB) types.Output {
+	return StartWithOptions(sourceCodeDir, analysisId, knowledge_db, options.Default())
+}
+
+// StartWithOptions runs the analysis with the options of the analysis document
+func StartWithOptions(sourceCodeDir string, analysisId uuid.UUID, knowledge_db *bun.DB, opts options.Options) types.Output {
+	return StartContext(context.Background(), sourceCodeDir, analysisId, knowledge_db, opts)
+}
+
+// StartContext runs the analysis until ctx is done or the timeout of the
+// options expires. An analysis stopped after the root manifest was read
+// returns the partial SBOM built so far, marked as truncated.
+func StartContext(ctx context.Context, sourceCodeDir string, analysisId uuid.UUID, knowledge_db *bun.DB, opts options.Options) types.Output {
 	start := time.Now()
+	ctx, cancel := withTimeout(ctx, opts)
+	defer cancel()
+	ctx, _ = diagnostics.NewContext(ctx)
+	return analyze(ctx, start, sourceCodeDir, opts)
+}
+
+// withTimeout bounds ctx by the timeout of the options, when there is one
+func withTimeout(ctx context.Context, opts options.Options) (context.Context, context.CancelFunc) {
+	if opts.Timeout > 0 {
+		return context.WithTimeout(ctx, opts.Timeout)
+	}
+	return ctx, func() {}
+}
+
+// analyze runs the analysis, reporting to the diagnostics collector of ctx
+func analyze(ctx context.Context, start time.Time, sourceCodeDir string, opts options.Options) types.Output {
+	collector := diagnostics.FromContext(ctx)
 	
-	log.Println("Starting PHP SBOM analysis...")
-	log.Printf("PHP SBOM Debug - sourceCodeDir: %s", sourceCodeDir)
+	diagnostics.Logger(ctx).Println("Starting PHP SBOM analysis...")
+	diagnostics.Logger(ctx).Printf("PHP SBOM Debug - sourceCodeDir: %s", sourceCodeDir)
 	
 	// Check if directory exists
 	if _, err := os.Stat(sourceCodeDir); os.IsNotExist(err) {
-		log.Printf("PHP SBOM Error - Directory does not exist: %s", sourceCodeDir)
-		exceptionManager.AddError(
+		diagnostics.Logger(ctx).Printf("PHP SBOM Error - Directory does not exist: %s", sourceCodeDir)
+		collector.AddError(
 			"Source directory not found",
 			exceptionManager.GENERIC_ERROR,
 			fmt.Sprintf("The source directory does not exist: %s", sourceCodeDir),
 			"SourceCodeDirDoesNotExist",
 		)
-		return generateFailureOutput(start, "")
+		return generateFailureOutput(ctx, start, "")
 	}
 	
+	files, err := parser.ScanProject(ctx, sourceCodeDir, opts)
+	return analyzeFiles(ctx, start, sourceCodeDir, opts, files, err)
+}
+
+// analyzeFiles analyzes the project of sourceCodeDir among the files of a
+// scan, scanErr being the error the scan returned
+func analyzeFiles(ctx context.Context, start time.Time, sourceCodeDir string, opts options.Options, files scanner.Result, scanErr error) types.Output {
+	collector := diagnostics.FromContext(ctx)
+	
 	// Find PHP projects in the source directory
-	projectInfo, err := project_finder.FindPHPProjects(sourceCodeDir)
+	projectInfo, err := project_finder.FindPHPProjectsInFiles(ctx, sourceCodeDir, opts, files, scanErr)
+	var syntaxError *parser.SyntaxError
+	if errors.As(err, &syntaxError) {
+		// The root manifest exists but cannot be read
+		relativePath, _ := filepath.Rel(sourceCodeDir, syntaxError.File)
+		collector.Add(diagnostics.Diagnostic{
+			Severity: diagnostics.SEVERITY_ERROR,
+			Public:   exceptionManager.ErrorContent{Description: "The root composer.json is invalid", Type: exceptionManager.GENERIC_ERROR},
+			Private:  exceptionManager.ErrorContent{Description: err.Error(), Type: diagnostics.ERROR_INVALID_ROOT_MANIFEST},
+			Location: diagnostics.Location{File: relativePath, Line: syntaxError.Line, Column: syntaxError.Column},
+		})
+		return generateFailureOutput(ctx, start, "")
+	}
+	if diagnostics.IsInterruption(err) {
+		collector.AddError(
+			"The analysis did not complete in time",
+			exceptionManager.GENERIC_ERROR,
+			fmt.Sprintf("Analysis interrupted before the root manifest was found: %v", err),
+			diagnostics.ERROR_ANALYSIS_INTERRUPTED,
+		)
+		return generateFailureOutput(ctx, start, "")
+	}
 	if err != nil {
-		exceptionManager.AddError(
+		collector.AddError(
 			"No PHP project found in the source directory",
 			exceptionManager.UNSUPPORTED_LANGUAGE_REQUESTED,
 			fmt.Sprintf("Error finding PHP projects: %v", err),
 			exceptionManager.UNSUPPORTED_LANGUAGE_REQUESTED,
 		)
-		return generateFailureOutput(start, "")
+		return generateFailureOutput(ctx, start, "")
 	}
 	
-	log.Printf("Found PHP project: %s (Framework: %s)", projectInfo.Name, projectInfo.Framework)
+	diagnostics.Logger(ctx).Printf("Found PHP project: %s (Framework: %s)", projectInfo.Name, projectInfo.Framework)
 	
-	// Check if composer.lock exists
-	if projectInfo.ComposerLock == nil {
-		log.Println("Warning: No composer.lock file found. Analysis will be based on composer.json only")
+	// Check if composer.lock exists, an invalid one is reported by the project finder
+	if projectInfo.ComposerLockPath == "" {
+		diagnostics.Logger(ctx).Println("Warning: No composer.lock file found. Analysis will be based on composer.json only")
+		collector.Warn(
+			diagnostics.WARNING_MISSING_LOCK_FILE,
+			"No composer.lock file found, the analysis is based on composer.json only",
+			diagnostics.Location{File: projectInfo.RelativeComposerJSON},
+		)
 	}
 	
 	// Build workspaces in js-sbom compatible format
-	workspaces := buildCompatibleWorkspaces(projectInfo)
+	workspaces := buildCompatibleWorkspaces(ctx, projectInfo, opts)
+	if !opts.IncludeDev {
+		workspaces = filterWorkspaces(workspaces, func(name string, dev bool) bool { return !dev })
+	}
 	
 	// Generate analysis info in js-sbom compatible format
-	analysisInfo := generateCompatibleAnalysisInfo(projectInfo, start)
+	analysisInfo := generateCompatibleAnalysisInfo(ctx, projectInfo, workspaces, start)
 	
 	// Success output
 	output := types.Output{
 		WorkSpaces:   workspaces,
 		AnalysisInfo: analysisInfo,
+		Components:   aggregateComponents(workspaces),
+	}
+	output.AnalysisInfo.Extra.PolicyViolations = evaluatePolicy(opts.Policy, output)
+	output.AnalysisInfo.Truncated = collector.Truncated()
+	if collector.HasWarnings() {
+		// Partial results: some manifests or archives were skipped
+		output.AnalysisInfo.Status = types.SUCCESS_WITH_WARNINGS
 	}
 	
-	log.Printf("PHP SBOM analysis completed successfully. Found %d dependencies", 
+	diagnostics.Logger(ctx).Printf("PHP SBOM analysis completed successfully. Found %d dependencies", 
 		getTotalDependencyCount(workspaces))
 	
 	return output
 }
 
 // buildCompatibleWorkspaces builds workspaces in js-sbom compatible format
-func buildCompatibleWorkspaces(projectInfo *project_finder.ProjectInfo) map[string]types.WorkSpace {
+func buildCompatibleWorkspaces(ctx context.Context, projectInfo *project_finder.ProjectInfo, opts options.Options) map[string]types.WorkSpace {
 	workspaces := make(map[string]types.WorkSpace)
 	
 	// Main workspace
 	mainWorkspace := buildCompatibleWorkspace(projectInfo.ComposerJSON, projectInfo.ComposerLock)
+	mainWorkspace.ConstraintFindings = checkConstraintHygiene(ctx, manifestFiles{
+		composerJSON:         projectInfo.ComposerJSON,
+		composerLock:         projectInfo.ComposerLock,
+		composerJSONPath:     projectInfo.ComposerJSONPath,
+		composerLockPath:     projectInfo.ComposerLockPath,
+		relativeComposerJSON: projectInfo.RelativeComposerJSON,
+		relativeComposerLock: projectInfo.RelativeComposerLock,
+	})
 	workspaces[types.DEFAULT_WORKSPACE_CHARACTER] = mainWorkspace
 	
 	// Additional workspaces if monorepo
 	if projectInfo.IsMonorepo {
-		for _, ws := range projectInfo.Workspaces {
-			workspace := buildCompatibleWorkspace(ws.ComposerJSON, ws.ComposerLock)
-			workspaces[ws.RelativeComposerJSON] = workspace
+		for i, ws := range projectInfo.Workspaces {
+			if err := ctx.Err(); err != nil {
+				diagnostics.FromContext(ctx).Interrupted(err, fmt.Sprintf("%d workspaces not analyzed", len(projectInfo.Workspaces)-i))
+				break
+			}
+			files := manifestFiles{
+				composerJSON:         ws.ComposerJSON,
+				composerLock:         ws.ComposerLock,
+				composerJSONPath:     ws.ComposerJSONPath,
+				composerLockPath:     ws.ComposerLockPath,
+				relativeComposerJSON: ws.RelativeComposerJSON,
+				relativeComposerLock: ws.RelativeComposerLock,
+			}
+			shared := ws.ComposerLock == nil && opts.SharedLock && projectInfo.ComposerLock != nil
+			if shared {
+				// Resolve the workspace against the part of the root lock it requires
+				diagnostics.Logger(ctx).Printf("PHP SBOM Debug - resolving %s against %s", ws.RelativeComposerJSON, projectInfo.RelativeComposerLock)
+				files.composerLock = sharedLock(ws.ComposerJSON, projectInfo.ComposerLock)
+				files.composerLockPath = projectInfo.ComposerLockPath
+				files.relativeComposerLock = projectInfo.RelativeComposerLock
+				files.writtenLock = projectInfo.ComposerLock
+			}
+			workspace := buildCompatibleWorkspace(files.composerJSON, files.composerLock)
+			workspace.SharedLock = shared
+			workspace.ConstraintFindings = checkConstraintHygiene(ctx, files)
+			workspaces[ws.ID] = workspace
 		}
 	}
 	
@@ -98,6 +211,14 @@ func buildCompatibleWorkspace(composerJSON *parser.ComposerJSON, composerLock *p
 	directDeps := []types.WorkSpaceDependency{}
 	directDevDeps := []types.WorkSpaceDependency{}
 	
+	// Build the dependency graph to compute reachability from the root
+	dependencyGraph := graph.Build(composerJSON, composerLock)
+	lockCtx := lockContext{
+		graph:     dependencyGraph,
+		aliases:   buildAliasIndex(composerJSON, composerLock),
+		stability: newStabilityContext(composerJSON),
+	}
+	
 	if composerLock != nil {
 		// Process production packages from composer.lock
 		for _, pkg := range composerLock.Packages {
@@ -106,23 +227,7 @@ func buildCompatibleWorkspace(composerJSON *parser.ComposerJSON, composerLock *p
 			
 			// Create versions map for this dependency
 			versions := make(map[string]types.Versions)
-			versions[versionKey] = types.Versions{
-				Key:          pkg.Name + VERSION_SEPARATOR + pkg.Version,
-				Requires:     pkg.Require,
-				Dependencies: pkg.Require, // In PHP, requires and dependencies are similar
-				Optional:     false,
-				Bundled:      false,
-				Dev:          false,
-				Prod:         true,
-				Direct:       isDirectDependency(pkg.Name, composerJSON, false),
-				Transitive:   !isDirectDependency(pkg.Name, composerJSON, false),
-				Licenses:     parser.NormalizeLicense(pkg.License),
-				// PHP-specific fields
-				PHPVersion:  "",
-				Type:        pkg.Type,
-				Authors:     convertAuthors(pkg.Authors),
-				Description: pkg.Description,
-			}
+			versions[versionKey] = buildVersions(pkg, lockCtx, false)
 			
 			dependencies[pkg.Name] = versions
 		}
@@ -132,23 +237,7 @@ func buildCompatibleWorkspace(composerJSON *parser.ComposerJSON, composerLock *p
 			versionKey := pkg.Version
 			
 			versions := make(map[string]types.Versions)
-			versions[versionKey] = types.Versions{
-				Key:          pkg.Name + VERSION_SEPARATOR + pkg.Version,
-				Requires:     pkg.Require,
-				Dependencies: pkg.Require,
-				Optional:     false,
-				Bundled:      false,
-				Dev:          true,
-				Prod:         false,
-				Direct:       isDirectDependency(pkg.Name, composerJSON, true),
-				Transitive:   !isDirectDependency(pkg.Name, composerJSON, true),
-				Licenses:     parser.NormalizeLicense(pkg.License),
-				// PHP-specific fields
-				PHPVersion:  "",
-				Type:        pkg.Type,
-				Authors:     convertAuthors(pkg.Authors),
-				Description: pkg.Description,
-			}
+			versions[versionKey] = buildVersions(pkg, lockCtx, true)
 			
 			dependencies[pkg.Name] = versions
 		}
@@ -157,21 +246,27 @@ func buildCompatibleWorkspace(composerJSON *parser.ComposerJSON, composerLock *p
 	// Build direct dependencies list from composer.json
 	if composerJSON != nil {
 		for name, version := range composerJSON.Require {
-			if name != "php" && !isExtension(name) {
+			if !parser.IsPlatformPackage(name) {
+				_, alias, _ := parser.ParseInlineAlias(version)
 				directDeps = append(directDeps, types.WorkSpaceDependency{
 					Name:       name,
 					Version:    getResolvedVersion(name, dependencies),
 					Constraint: version,
+					Alias:      alias,
 				})
 			}
 		}
 		
 		for name, version := range composerJSON.RequireDev {
-			directDevDeps = append(directDevDeps, types.WorkSpaceDependency{
-				Name:       name,
-				Version:    getResolvedVersion(name, dependencies),
-				Constraint: version,
-			})
+			if !parser.IsPlatformPackage(name) {
+				_, alias, _ := parser.ParseInlineAlias(version)
+				directDevDeps = append(directDevDeps, types.WorkSpaceDependency{
+					Name:       name,
+					Version:    getResolvedVersion(name, dependencies),
+					Constraint: version,
+					Alias:      alias,
+				})
+			}
 		}
 	}
 	
@@ -181,11 +276,66 @@ func buildCompatibleWorkspace(composerJSON *parser.ComposerJSON, composerLock *p
 			Dependencies:    directDeps,
 			DevDependencies: directDevDeps,
 		},
+		Platform: buildPlatformRequirements(composerJSON, composerLock, dependencies, dependencyGraph),
 	}
 }
 
+// lockContext holds what is derived once from a lock file and shared by the
+// version entries of all its packages
+type lockContext struct {
+	graph     *graph.Graph
+	aliases   aliasIndex
+	stability stabilityContext
+}
+
+// buildVersions builds the version entry of a locked package.
+// Scope and directness come from the dependency graph; the lock section the
+// package was found in is only used for packages unreachable from the root.
+func buildVersions(pkg parser.PackageInfo, lockCtx lockContext, lockedAsDev bool) types.Versions {
+	node := lockCtx.graph.Node(pkg.Name)
+	packageAliases := lockCtx.aliases.aliasesOf(pkg.Name, pkg.Version)
+	stability, stabilityRisks := lockCtx.stability.assess(pkg)
+	versions := types.Versions{
+		Key:          pkg.Name + VERSION_SEPARATOR + pkg.Version,
+		Requires:     pkg.Require,
+		Dependencies: packageDependencies(pkg.Require, lockCtx.graph), // Platform requirements are reported separately
+		Optional:     false,
+		Bundled:      false,
+		Dev:          lockedAsDev,
+		Prod:         !lockedAsDev,
+		Direct:       false,
+		Transitive:   true,
+		DevOnly:      lockedAsDev,
+		Licenses:     parser.NormalizeLicense(pkg.License),
+		// PHP-specific fields
+		PHPVersion:  pkg.Require["php"],
+		Type:        pkg.Type,
+		Authors:     convertAuthors(pkg.Authors),
+		Description: pkg.Description,
+		Replaces:    resolveSelfVersion(pkg.Replace, pkg.Version),
+		Provides:    resolveSelfVersion(pkg.Provide, pkg.Version),
+		// Aliased versions reported next to the real version
+		Aliases:           packageAliases,
+		ComparableVersion: comparableVersion(pkg.Version, packageAliases),
+		Reference:         pkg.Source.Reference,
+		Stability:         stability,
+		StabilityRisks:    stabilityRisks,
+	}
+	
+	if node != nil && node.Reachable() {
+		versions.Dev = node.DevReachable
+		versions.Prod = node.ProdReachable
+		versions.DevOnly = node.DevOnly()
+		versions.Direct = node.Direct || node.DirectDev
+		versions.Transitive = node.RequiredByPackage || !versions.Direct
+		versions.Depth = node.Depth()
+	}
+	
+	return versions
+}
+
 // generateCompatibleAnalysisInfo generates analysis info in js-sbom compatible format
-func generateCompatibleAnalysisInfo(projectInfo *project_finder.ProjectInfo, start time.Time) types.AnalysisInfo {
+func generateCompatibleAnalysisInfo(ctx context.Context, projectInfo *project_finder.ProjectInfo, workspaces map[string]types.WorkSpace, start time.Time) types.AnalysisInfo {
 	end := time.Now()
 	
 	// Build paths (composer.json/composer.lock instead of package.json/package-lock.json)
@@ -197,9 +347,27 @@ func generateCompatibleAnalysisInfo(projectInfo *project_finder.ProjectInfo, sta
 		RelativePackageFile:  projectInfo.RelativeComposerJSON,
 	}
 	
-	// Add workspace package files for monorepo
+	// Workspace files, keyed like the workspaces of the output
+	paths.WorkSpacePackageFile[types.DEFAULT_WORKSPACE_CHARACTER] = projectInfo.ComposerJSONPath
+	if projectInfo.ComposerLockPath != "" {
+		paths.WorkSpaceLockFile = map[string]string{types.DEFAULT_WORKSPACE_CHARACTER: projectInfo.ComposerLockPath}
+	}
 	for _, ws := range projectInfo.Workspaces {
-		paths.WorkSpacePackageFile[ws.Name] = ws.ComposerJSONPath
+		if _, ok := workspaces[ws.ID]; !ok {
+			// Not analyzed, the analysis was interrupted
+			continue
+		}
+		paths.WorkSpacePackageFile[ws.ID] = ws.ComposerJSONPath
+		lockPath := ws.ComposerLockPath
+		if workspaces[ws.ID].SharedLock {
+			lockPath = projectInfo.ComposerLockPath
+		}
+		if lockPath != "" {
+			if paths.WorkSpaceLockFile == nil {
+				paths.WorkSpaceLockFile = make(map[string]string)
+			}
+			paths.WorkSpaceLockFile[ws.ID] = lockPath
+		}
 	}
 	
 	// Build extra with PHP-specific information
@@ -216,12 +384,20 @@ func generateCompatibleAnalysisInfo(projectInfo *project_finder.ProjectInfo, sta
 		HasVendorDirectory: projectInfo.HasVendorDirectory,
 	}
 	
+	members := describeWorkspaces(projectInfo, workspaces)
+	extra.UnrelatedManifests = unrelatedManifests(projectInfo)
+	extra.Statistics, extra.WorkspaceStatistics = computeStatistics(workspaces)
+	extra.RequiredExtensions = requiredExtensions(workspaces)
+	extra.StabilitySummary = computeStabilitySummary(projectInfo.ComposerLock, workspaces)
+	extra.PHPCompatibility = computePHPCompatibility(ctx, projectInfo, workspaces[types.DEFAULT_WORKSPACE_CHARACTER])
+	
 	if projectInfo.ComposerLock != nil {
 		extra.MinimumStability = projectInfo.ComposerLock.MinimumStability
 		extra.PreferStable = projectInfo.ComposerLock.PreferStable
 		extra.PluginAPIVersion = projectInfo.ComposerLock.PluginAPIVersion
 		extra.ContentHash = projectInfo.ComposerLock.ContentHash
 		extra.Platform = projectInfo.ComposerLock.Platform
+		extra.PlatformOverrides = projectInfo.ComposerLock.PlatformOverrides
 	}
 	
 	return types.AnalysisInfo{
@@ -234,23 +410,26 @@ func generateCompatibleAnalysisInfo(projectInfo *project_finder.ProjectInfo, sta
 			AnalysisEndTime:   end.Format(time.RFC3339),
 			AnalysisDeltaTime: float64(end.Sub(start).Nanoseconds()) / 1e9,
 		},
-		Errors: exceptionManager.GetErrors(),
-		Paths:  paths,
+		Errors:   diagnostics.FromContext(ctx).Errors(),
+		Warnings: diagnostics.FromContext(ctx).Warnings(),
+		Paths:    paths,
 		Workspaces: types.Workspaces{
 			DefaultWorkspaceName:     types.DEFAULT_WORKSPACE_CHARACTER,
 			SelfManagedWorkspaceName: types.SELF_MANAGED_WORKSPACE_CHARACTER,
 			WorkSpacesUsed:           projectInfo.IsMonorepo,
+			Members:                  members,
 		},
 		Extra: extra,
 	}
 }
 
 // generateFailureOutput generates a failure output
-func generateFailureOutput(start time.Time, projectName string) types.Output {
+func generateFailureOutput(ctx context.Context, start time.Time, projectName string) types.Output {
 	end := time.Now()
 	
 	return types.Output{
 		WorkSpaces: make(map[string]types.WorkSpace),
+		Components: make(map[string]types.Component),
 		AnalysisInfo: types.AnalysisInfo{
 			Status:           codeclarity.FAILURE,
 			ProjectName:      projectName,
@@ -261,8 +440,9 @@ func generateFailureOutput(start time.Time, projectName string) types.Output {
 				AnalysisEndTime:   end.Format(time.RFC3339),
 				AnalysisDeltaTime: float64(end.Sub(start).Nanoseconds()) / 1e9,
 			},
-			Errors: exceptionManager.GetErrors(),
-			Paths:  types.Paths{},
+			Errors:   diagnostics.FromContext(ctx).Errors(),
+			Warnings: diagnostics.FromContext(ctx).Warnings(),
+			Paths:    types.Paths{},
 			Workspaces: types.Workspaces{
 				DefaultWorkspaceName:     types.DEFAULT_WORKSPACE_CHARACTER,
 				SelfManagedWorkspaceName: types.SELF_MANAGED_WORKSPACE_CHARACTER,
@@ -287,22 +467,80 @@ func getProjectName(composerJSON *parser.ComposerJSON) string {
 	return "unknown"
 }
 
-func isDirectDependency(packageName string, composerJSON *parser.ComposerJSON, isDev bool) bool {
-	if composerJSON == nil {
-		return false
+// packageDependencies returns the requirements of a package that target other
+// packages. Replaced and virtual names are resolved to the locked packages
+// satisfying them; names missing from the lock are kept as is. The distinct
+// constraints of names resolved to the same package are joined with ",", in
+// the order of the names.
+func packageDependencies(requires map[string]string, dependencyGraph *graph.Graph) map[string]string {
+	if requires == nil {
+		return nil
 	}
-	
-	if isDev {
-		_, exists := composerJSON.RequireDev[packageName]
-		return exists
+	names := make([]string, 0, len(requires))
+	for name := range requires {
+		names = append(names, name)
 	}
-	
-	_, exists := composerJSON.Require[packageName]
-	return exists
+	sort.Strings(names)
+
+	constraints := make(map[string][]string, len(requires))
+	add := func(name string, constraint string) {
+		if !slices.Contains(constraints[name], constraint) {
+			constraints[name] = append(constraints[name], constraint)
+		}
+	}
+	for _, name := range names {
+		if parser.IsPlatformPackage(name) {
+			continue
+		}
+		constraint := requires[name]
+		resolved := dependencyGraph.Resolve(name)
+		if len(resolved) == 0 {
+			add(name, constraint)
+			continue
+		}
+		for _, providerName := range resolved {
+			add(dependencyGraph.Nodes[providerName].Name, constraint)
+		}
+	}
+
+	dependencies := make(map[string]string, len(constraints))
+	for name, merged := range constraints {
+		dependencies[name] = joinConstraints(merged)
+	}
+	return dependencies
 }
 
-func isExtension(name string) bool {
-	return len(name) > 4 && name[:4] == "ext-"
+// joinConstraints requires all of the constraints at once. As , binds tighter
+// than || in Composer, the alternatives are parenthesized: "^5.0 || ^6.0" and
+// "^6.4" give "(^5.0 || ^6.0),^6.4".
+func joinConstraints(constraints []string) string {
+	if len(constraints) == 1 {
+		return constraints[0]
+	}
+	parts := make([]string, len(constraints))
+	for i, constraint := range constraints {
+		if strings.Contains(constraint, "|") {
+			constraint = "(" + constraint + ")"
+		}
+		parts[i] = constraint
+	}
+	return strings.Join(parts, ",")
+}
+
+// resolveSelfVersion replaces the "self.version" constraints of a replace or
+// provide map with the version of the package
+func resolveSelfVersion(links map[string]string, version string) map[string]string {
+	if len(links) == 0 {
+		return nil
+	}
+	resolved := make(map[string]string, len(links))
+	for name, constraint := range links {
+		if constraint == "self.version" {
+			constraint = version
+		}
+		resolved[name] = constraint
+	}
+	return resolved
 }
 
 func getResolvedVersion(packageName string, dependencies map[string]map[string]types.Versions) string {
@@ -0,0 +1,297 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/diagnostics"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/options"
+)
+
+// GITIGNORE is the ignore file read in each directory when the options respect it
+const GITIGNORE = ".gitignore"
+
+// IGNORE_FILE lists, with the syntax of .gitignore, the paths the analysis
+// skips. It is always read, after .gitignore so that it can override it.
+const IGNORE_FILE = ".codeclarityignore"
+
+// defaultIgnoredDirectories are never searched, with the reason why. The
+// files matchers expect inside them are still looked up.
+var defaultIgnoredDirectories = map[string]string{
+	".git":         "version control directory",
+	"vendor":       "installed dependencies",
+	"node_modules": "installed dependencies",
+}
+
+// ignorePattern is a line of an ignore file, with the gitignore semantics
+type ignorePattern struct {
+	// source is the ignore file and line the pattern comes from, like .gitignore:3
+	source string
+	text   string
+	// gitignore is set for the patterns of a .gitignore, which unignorable
+	// files override
+	gitignore bool
+	// base is the directory of the ignore file, relative to the scan root
+	base    string
+	negate  bool
+	dirOnly bool
+	// anchored patterns match the path relative to base, the others a name
+	anchored bool
+	regexp   *regexp.Regexp
+}
+
+// ignoreStack holds the patterns of the ignore files from the scan root down
+// to a directory. The last pattern matching a path decides for it.
+type ignoreStack []ignorePattern
+
+// load adds the patterns of the ignore files of a directory. The stack of
+// the parent directory is left unchanged, as its other subdirectories share it.
+func (stack ignoreStack) load(opts options.Options, logger *log.Logger, dir string, relative string, entries []fs.DirEntry) ignoreStack {
+	present := map[string]bool{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			present[entry.Name()] = true
+		}
+	}
+	return stack.loadWith(opts, logger, relative, func(name string) (io.ReadCloser, error) {
+		if !present[name] {
+			return nil, fs.ErrNotExist
+		}
+		return os.Open(filepath.Join(dir, name))
+	})
+}
+
+// loadWith adds the patterns of the ignore files of a directory opened with
+// open, which returns fs.ErrNotExist for the missing ones
+func (stack ignoreStack) loadWith(opts options.Options, logger *log.Logger, relative string, open func(name string) (io.ReadCloser, error)) ignoreStack {
+	for _, name := range ignoreFiles(opts) {
+		file, err := open(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		source := name
+		if relative != "" {
+			source = relative + "/" + name
+		}
+		var patterns []ignorePattern
+		if err == nil {
+			patterns, err = readIgnoreFile(file, source, relative)
+			file.Close()
+		}
+		if err != nil {
+			logger.Printf("PHP SBOM Debug - cannot read %s: %v", source, err)
+			continue
+		}
+		for i := range patterns {
+			patterns[i].gitignore = name == GITIGNORE
+		}
+		stack = append(stack[:len(stack):len(stack)], patterns...)
+	}
+	return stack
+}
+
+// ignoreFiles are the ignore files read in each directory, in order
+func ignoreFiles(opts options.Options) []string {
+	if opts.RespectGitignore {
+		return []string{GITIGNORE, IGNORE_FILE}
+	}
+	return []string{IGNORE_FILE}
+}
+
+// match reports whether a path relative to the scan root is ignored, and the
+// pattern ignoring it
+func (stack ignoreStack) match(relative string, isDir bool) (bool, string) {
+	if pattern := stack.matchPattern(relative, isDir); pattern != nil {
+		return true, pattern.source + ": " + pattern.text
+	}
+	return false, ""
+}
+
+// matchPattern returns the pattern ignoring a path relative to the scan
+// root, nil when it is not ignored
+func (stack ignoreStack) matchPattern(relative string, isDir bool) *ignorePattern {
+	for i := len(stack) - 1; i >= 0; i-- {
+		pattern := stack[i]
+		if pattern.dirOnly && !isDir {
+			continue
+		}
+		target := relative
+		if pattern.base != "" {
+			if !strings.HasPrefix(relative, pattern.base+"/") {
+				continue
+			}
+			target = relative[len(pattern.base)+1:]
+		}
+		if !pattern.anchored {
+			target = path.Base(target)
+		}
+		if !pattern.regexp.MatchString(target) {
+			continue
+		}
+		if pattern.negate {
+			return nil
+		}
+		return &stack[i]
+	}
+	return nil
+}
+
+// Ignores applies the ignore rules of a scan to a tree that is not on the
+// file system, such as a git revision. A directory is loaded before the
+// paths under it are matched, from the root down.
+type Ignores struct {
+	opts   options.Options
+	logger *log.Logger
+	stacks map[string]ignoreStack
+}
+
+// NewIgnores returns the ignore rules of the search options, before any
+// ignore file is loaded
+func NewIgnores(ctx context.Context, opts options.Options) *Ignores {
+	return &Ignores{opts: opts, logger: diagnostics.Logger(ctx), stacks: make(map[string]ignoreStack)}
+}
+
+// Load reads the ignore files of a directory relative to the root, "" for
+// the root itself. open returns fs.ErrNotExist for the missing ones.
+func (i *Ignores) Load(relative string, open func(name string) (io.ReadCloser, error)) {
+	i.stacks[relative] = i.stacks[parentDir(relative)].loadWith(i.opts, i.logger, relative, open)
+}
+
+// Ignored reports whether the scan skips a path relative to the root, and
+// why. The exemption of unignorable files is left to the matchers.
+func (i *Ignores) Ignored(relative string, isDir bool) (bool, string) {
+	if isDir {
+		if reason, ok := defaultIgnoredDirectories[path.Base(relative)]; ok {
+			return true, reason
+		}
+	}
+	if i.opts.IsExcluded(relative) {
+		return true, options.KEY_EXCLUDE_PATHS
+	}
+	return i.stacks[parentDir(relative)].match(relative, isDir)
+}
+
+// parentDir returns the directory of a path relative to the root, "" for the root
+func parentDir(relative string) string {
+	if slash := strings.LastIndexByte(relative, '/'); slash >= 0 {
+		return relative[:slash]
+	}
+	return ""
+}
+
+// ignored reports whether a path relative to the scan root is excluded by the
+// options or an ignore file, and why
+func (s *scan) ignored(relative string, isDir bool, ignores ignoreStack) (bool, string) {
+	if s.opts.IsExcluded(relative) {
+		return true, options.KEY_EXCLUDE_PATHS
+	}
+	return ignores.match(relative, isDir)
+}
+
+// readIgnoreFile parses the patterns of an ignore file located in the
+// directory base, relative to the scan root
+func readIgnoreFile(file io.Reader, source string, base string) ([]ignorePattern, error) {
+	var patterns []ignorePattern
+	lines := bufio.NewScanner(file)
+	for line := 1; lines.Scan(); line++ {
+		pattern, ok := parseIgnorePattern(lines.Text())
+		if !ok {
+			continue
+		}
+		pattern.source = source + ":" + strconv.Itoa(line)
+		pattern.base = base
+		patterns = append(patterns, pattern)
+	}
+	return patterns, lines.Err()
+}
+
+// parseIgnorePattern reads a line of an ignore file. Blank lines and comments
+// are not patterns.
+func parseIgnorePattern(line string) (ignorePattern, bool) {
+	// Trailing spaces are ignored unless escaped
+	line = strings.TrimSuffix(line, "\r")
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignorePattern{}, false
+	}
+
+	pattern := ignorePattern{text: line}
+	if strings.HasPrefix(line, "!") {
+		pattern.negate = true
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		pattern.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	// A separator at the beginning or in the middle anchors the pattern to
+	// the directory of the ignore file
+	if strings.Contains(line, "/") {
+		pattern.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return ignorePattern{}, false
+	}
+
+	expression, err := regexp.Compile("^" + globToRegexp(line) + "$")
+	if err != nil {
+		return ignorePattern{}, false
+	}
+	pattern.regexp = expression
+	return pattern, true
+}
+
+// globToRegexp translates a gitignore glob. * and ? do not match /, and **
+// matches any number of directories.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			b.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,334 @@
+// Package scanner finds the files of a project in a single walk of its
+// directory tree. Directories are read in parallel, and each file is offered
+// to a set of matchers, one per kind of file the analysis is interested in.
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/diagnostics"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/options"
+	"github.com/CodeClarityCE/utility-types/exceptions"
+)
+
+// WARNING_UNREADABLE_DIRECTORY is reported for a directory below the root
+// that cannot be read, the rest of the tree is still scanned
+const WARNING_UNREADABLE_DIRECTORY exceptions.ERROR_TYPE = "UnreadableDirectory"
+
+// Kind of file recognized by a matcher
+type Kind string
+
+const (
+	KIND_COMPOSER_JSON  Kind = "composer-json"
+	KIND_COMPOSER_LOCK  Kind = "composer-lock"
+	KIND_PHAR           Kind = "phar"
+	KIND_INSTALLED_JSON Kind = "installed-json"
+	KIND_PHIVE          Kind = "phive"
+	KIND_DOCKERFILE     Kind = "dockerfile"
+)
+
+// Matcher recognizes the files of one kind
+type Matcher struct {
+	Kind Kind
+	// Match is given the path of a file relative to the scan root, separated
+	// by /, and its name
+	Match func(relativePath string, name string) bool
+	// Within lists paths, relative to a skipped dependency directory such as
+	// vendor, that are still looked up
+	Within []string
+	// Unignorable files are reported even when a .gitignore lists them. The
+	// .codeclarityignore files still exclude them.
+	Unignorable bool
+}
+
+// ComposerJSON matches the manifests, composer.json unless renamed
+func ComposerJSON(filename string) Matcher {
+	return Matcher{Kind: KIND_COMPOSER_JSON, Match: func(_ string, name string) bool {
+		return name == filename
+	}}
+}
+
+// ComposerLock matches the lock files. Libraries usually list composer.lock
+// in their .gitignore while the checkout still has one, so it is unignorable
+// by .gitignore.
+func ComposerLock(filename string) Matcher {
+	return Matcher{Kind: KIND_COMPOSER_LOCK, Unignorable: true, Match: func(_ string, name string) bool {
+		return name == filename
+	}}
+}
+
+// PHAR matches PHP archives
+func PHAR() Matcher {
+	return Matcher{Kind: KIND_PHAR, Match: func(_ string, name string) bool {
+		return strings.HasSuffix(strings.ToLower(name), ".phar")
+	}}
+}
+
+// InstalledJSON matches the vendor/composer/installed.json written by Composer
+// when installing, found although vendor directories are skipped
+func InstalledJSON() Matcher {
+	return Matcher{Kind: KIND_INSTALLED_JSON, Within: []string{"composer/installed.json"}, Match: func(relativePath string, _ string) bool {
+		return relativePath == "vendor/composer/installed.json" || strings.HasSuffix(relativePath, "/vendor/composer/installed.json")
+	}}
+}
+
+// Phive matches the PHARs installed by Phive, .phive/phars.xml, and its phive.xml
+func Phive() Matcher {
+	return Matcher{Kind: KIND_PHIVE, Match: func(relativePath string, name string) bool {
+		return name == "phive.xml" || (name == "phars.xml" && path.Base(path.Dir(relativePath)) == ".phive")
+	}}
+}
+
+// Dockerfile matches Dockerfile, Dockerfile.prod and app.dockerfile
+func Dockerfile() Matcher {
+	return Matcher{Kind: KIND_DOCKERFILE, Match: func(_ string, name string) bool {
+		return name == "Dockerfile" || strings.HasPrefix(name, "Dockerfile.") || strings.HasSuffix(strings.ToLower(name), ".dockerfile")
+	}}
+}
+
+// Skip is a path left out of the scan, a directory when it ends with /
+type Skip struct {
+	Path   string
+	Reason string
+}
+
+// Result holds the paths of the files found, by kind and sorted
+type Result struct {
+	Files map[Kind][]string
+	// Skipped are the matching files and the directories left out by the
+	// options or an ignore file, relative to the scan root and sorted
+	Skipped []Skip
+}
+
+// Get returns the paths of the files of a kind
+func (r Result) Get(kind Kind) []string {
+	return r.Files[kind]
+}
+
+// Scanner walks a directory tree once for a set of matchers
+type Scanner struct {
+	opts     options.Options
+	matchers []Matcher
+	// Workers is the number of directories read at the same time
+	Workers int
+}
+
+// New returns a scanner honouring the search options: excluded paths,
+// maximum depth, symlinks and ignore files
+func New(opts options.Options, matchers ...Matcher) *Scanner {
+	return &Scanner{
+		opts:     opts,
+		matchers: matchers,
+		Workers:  max(4, 2*runtime.GOMAXPROCS(0)),
+	}
+}
+
+// scan is the state of a single Scan
+type scan struct {
+	*Scanner
+	ctx       context.Context
+	rootDir   string
+	semaphore chan struct{}
+	wg        sync.WaitGroup
+
+	mu      sync.Mutex
+	visited map[string]bool
+	files   map[Kind][]string
+	skipped []Skip
+	err     error
+}
+
+// Scan walks rootDir and returns the files recognized by the matchers. When
+// ctx is done, the files found so far are returned with its error. A
+// directory below the root that cannot be read is reported as a warning.
+func (s *Scanner) Scan(ctx context.Context, rootDir string) (Result, error) {
+	state := &scan{
+		Scanner:   s,
+		ctx:       ctx,
+		rootDir:   rootDir,
+		semaphore: make(chan struct{}, max(1, s.Workers)),
+		visited:   make(map[string]bool),
+		files:     make(map[Kind][]string),
+	}
+
+	root, err := state.readDir(rootDir)
+	if err != nil {
+		return Result{Files: state.files}, err
+	}
+	state.wg.Add(1)
+	state.walk(rootDir, "", 0, nil, root)
+	state.wg.Wait()
+
+	for _, files := range state.files {
+		sort.Strings(files)
+	}
+	sort.Slice(state.skipped, func(i, j int) bool {
+		return state.skipped[i].Path < state.skipped[j].Path
+	})
+	return Result{Files: state.files, Skipped: state.skipped}, state.err
+}
+
+// readDir reads a directory once, even when symlinks lead to it several times
+func (s *scan) readDir(dir string) ([]fs.DirEntry, error) {
+	if err := s.ctx.Err(); err != nil {
+		return nil, err
+	}
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	seen := s.visited[realDir]
+	s.visited[realDir] = true
+	s.mu.Unlock()
+	if seen {
+		return nil, nil
+	}
+
+	s.semaphore <- struct{}{}
+	defer func() { <-s.semaphore }()
+	return os.ReadDir(dir)
+}
+
+// walk matches the entries of a directory and scans its subdirectories in
+// their own goroutines
+func (s *scan) walk(dir string, relative string, depth int, ignores ignoreStack, entries []fs.DirEntry) {
+	defer s.wg.Done()
+
+	ignores = ignores.load(s.opts, diagnostics.Logger(s.ctx), dir, relative, entries)
+	for _, entry := range entries {
+		name := entry.Name()
+		entryPath := filepath.Join(dir, name)
+		entryRelative := name
+		if relative != "" {
+			entryRelative = relative + "/" + name
+		}
+
+		isDir := entry.IsDir()
+		if entry.Type()&fs.ModeSymlink != 0 {
+			info, err := os.Stat(entryPath)
+			if err != nil {
+				// Dangling symlink
+				continue
+			}
+			isDir = info.IsDir()
+			if isDir && !s.opts.FollowSymlinks {
+				continue
+			}
+		}
+
+		if !isDir {
+			s.matchFile(entryPath, entryRelative, name, ignores)
+			continue
+		}
+
+		if _, ok := defaultIgnoredDirectories[name]; ok {
+			s.lookWithin(entryPath, entryRelative)
+			continue
+		}
+		if skipped, reason := s.ignored(entryRelative, true, ignores); skipped {
+			s.skip(entryRelative+"/", reason)
+			continue
+		}
+		if s.opts.MaxDepth != options.UNLIMITED_DEPTH && depth+1 > s.opts.MaxDepth {
+			s.skip(entryRelative+"/", fmt.Sprintf("deeper than %s %d", options.KEY_MAX_DEPTH, s.opts.MaxDepth))
+			continue
+		}
+
+		s.wg.Add(1)
+		go func(entryPath string, entryRelative string) {
+			subEntries, err := s.readDir(entryPath)
+			if err != nil {
+				s.fail(entryRelative, err)
+				s.wg.Done()
+				return
+			}
+			s.walk(entryPath, entryRelative, depth+1, ignores, subEntries)
+		}(entryPath, entryRelative)
+	}
+}
+
+// matchFile records a file under the kinds whose matcher recognizes it
+func (s *scan) matchFile(filePath string, relative string, name string, ignores ignoreStack) {
+	for _, matcher := range s.matchers {
+		if !matcher.Match(relative, name) {
+			continue
+		}
+		if s.opts.IsExcluded(relative) {
+			s.skip(relative, options.KEY_EXCLUDE_PATHS)
+			continue
+		}
+		if pattern := ignores.matchPattern(relative, false); pattern != nil {
+			reason := pattern.source + ": " + pattern.text
+			if !matcher.Unignorable || !pattern.gitignore {
+				s.skip(relative, reason)
+				continue
+			}
+			diagnostics.Logger(s.ctx).Printf("PHP SBOM Debug - %s found although ignored by %s", relative, reason)
+		}
+		s.add(matcher.Kind, filePath)
+	}
+}
+
+// lookWithin checks the paths matchers expect inside a skipped dependency directory
+func (s *scan) lookWithin(dir string, relative string) {
+	for _, matcher := range s.matchers {
+		for _, within := range matcher.Within {
+			filePath := filepath.Join(dir, filepath.FromSlash(within))
+			if info, err := os.Stat(filePath); err != nil || info.IsDir() {
+				continue
+			}
+			fileRelative := relative + "/" + within
+			if matcher.Match(fileRelative, path.Base(within)) && !s.opts.IsExcluded(fileRelative) {
+				s.add(matcher.Kind, filePath)
+			}
+		}
+	}
+}
+
+func (s *scan) add(kind Kind, filePath string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[kind] = append(s.files[kind], filePath)
+}
+
+// skip records a path left out, with the reason in the debug output
+func (s *scan) skip(relative string, reason string) {
+	diagnostics.Logger(s.ctx).Printf("PHP SBOM Debug - skipped %s: %s", relative, reason)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.skipped = append(s.skipped, Skip{Path: relative, Reason: reason})
+}
+
+// fail records the interruption of the scan, other errors only skip the directory
+func (s *scan) fail(relative string, err error) {
+	if diagnostics.IsInterruption(err) {
+		s.mu.Lock()
+		if s.err == nil {
+			s.err = err
+		}
+		s.mu.Unlock()
+		return
+	}
+	diagnostics.Logger(s.ctx).Printf("PHP SBOM Debug - cannot read %s: %v", relative, err)
+	diagnostics.FromContext(s.ctx).Warn(WARNING_UNREADABLE_DIRECTORY, fmt.Sprintf("Directory skipped: %v", errorWithoutPath(err)), diagnostics.Location{File: relative})
+}
+
+// errorWithoutPath drops the absolute path from file system errors
+func errorWithoutPath(err error) error {
+	var pathError *fs.PathError
+	if errors.As(err, &pathError) {
+		return pathError.Err
+	}
+	return err
+}
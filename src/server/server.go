@@ -0,0 +1,258 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	codeclarity_src "github.com/CodeClarityCE/plugin-php-sbom/src"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/export"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/options"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+	codeclarity "github.com/CodeClarityCE/utility-types/codeclarity_db"
+	"github.com/google/uuid"
+)
+
+// Media types of the formats the server can answer with
+const (
+	MEDIA_TYPE_JSON      = "application/json"
+	MEDIA_TYPE_CYCLONEDX = "application/vnd.cyclonedx+json"
+	MEDIA_TYPE_SPDX      = "application/spdx+json"
+)
+
+// ARCHIVE_FIELD is the multipart field carrying a zip, tar or tar.gz of the project
+const ARCHIVE_FIELD = "archive"
+
+// DEFAULT_ANALYSIS_TIMEOUT bounds the analysis of a request when the handler
+// is created without a timeout
+const DEFAULT_ANALYSIS_TIMEOUT = 5 * time.Minute
+
+// multipartMemory is the part of a multipart upload kept in memory
+const multipartMemory = 8 << 20
+
+var formatMediaTypes = map[export.Format]string{
+	export.FORMAT_JSON:      MEDIA_TYPE_JSON,
+	export.FORMAT_CYCLONEDX: MEDIA_TYPE_CYCLONEDX,
+	export.FORMAT_SPDX:      MEDIA_TYPE_SPDX,
+}
+
+// Server analyzes uploaded projects over HTTP. Each analysis collects its
+// own diagnostics, so requests are analyzed concurrently.
+type Server struct {
+	// timeout bounds each analysis, the SBOM built when it expires is
+	// returned as truncated
+	timeout time.Duration
+}
+
+// NewHandler returns the HTTP handler of the server:
+//
+//	GET  /health   liveness probe
+//	POST /analyze  multipart upload of composer.json/composer.lock or of an
+//	               archive field, or a raw zip/tar/tar.gz body
+//
+// The SBOM format is chosen with the format query parameter or the Accept
+// header. Each analysis is bounded by DEFAULT_ANALYSIS_TIMEOUT.
+func NewHandler() http.Handler {
+	return NewHandlerWithTimeout(DEFAULT_ANALYSIS_TIMEOUT)
+}
+
+// NewHandlerWithTimeout returns the HTTP handler of the server, each analysis
+// being bounded by timeout
+func NewHandlerWithTimeout(timeout time.Duration) http.Handler {
+	server := &Server{timeout: timeout}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", server.health)
+	mux.HandleFunc("/analyze", server.analyze)
+	return mux
+}
+
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) analyze(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	format, err := negotiateFormat(r)
+	if err != nil {
+		writeError(w, http.StatusNotAcceptable, err.Error())
+		return
+	}
+
+	box, err := newSandbox()
+	if err != nil {
+		log.Printf("PHP SBOM Server - %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to prepare the analysis")
+		return
+	}
+	defer box.Close()
+
+	r.Body = http.MaxBytesReader(w, r.Body, MAX_UPLOAD_SIZE)
+	if err := readUpload(r, box); err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) || errors.Is(err, errTooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, errTooLarge.Error())
+			return
+		}
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// The analysis stops when the client goes away or the timeout expires
+	opts := options.Default()
+	opts.Timeout = s.timeout
+	output := codeclarity_src.StartContext(r.Context(), box.dir, uuid.New(), nil, opts)
+	hideSandboxPaths(&output, box.dir)
+
+	if output.AnalysisInfo.Status == codeclarity.FAILURE {
+		writeJSON(w, http.StatusUnprocessableEntity, output)
+		return
+	}
+
+	data, err := export.Generate(output, format)
+	if err != nil {
+		log.Printf("PHP SBOM Server - %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to export the SBOM")
+		return
+	}
+	w.Header().Set("Content-Type", formatMediaTypes[format])
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// readUpload writes the uploaded manifests or archive to the sandbox
+func readUpload(r *http.Request, box *sandbox) error {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return fmt.Errorf("missing or invalid Content-Type")
+	}
+
+	if mediaType != "multipart/form-data" {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		return box.extractArchive(data)
+	}
+
+	if err := r.ParseMultipartForm(multipartMemory); err != nil {
+		return fmt.Errorf("invalid multipart upload: %w", err)
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	if file, _, err := r.FormFile(ARCHIVE_FIELD); err == nil {
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return err
+		}
+		return box.extractArchive(data)
+	}
+
+	found := false
+	for _, name := range manifestFileNames {
+		file, _, err := r.FormFile(name)
+		if err != nil {
+			continue
+		}
+		err = box.writeFile(name, file)
+		file.Close()
+		if err != nil {
+			return err
+		}
+		found = true
+	}
+	if !found {
+		return fmt.Errorf("expected a composer.json, composer.lock or %s field", ARCHIVE_FIELD)
+	}
+	return nil
+}
+
+// negotiateFormat picks the SBOM format from the format query parameter, or
+// from the Accept header by decreasing quality
+func negotiateFormat(r *http.Request) (export.Format, error) {
+	if name := r.URL.Query().Get("format"); name != "" {
+		return export.ParseFormat(name)
+	}
+
+	accept := r.Header.Get("Accept")
+	if strings.TrimSpace(accept) == "" {
+		return export.FORMAT_JSON, nil
+	}
+
+	type candidate struct {
+		mediaType string
+		quality   float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsed
+			}
+		}
+		if quality > 0 {
+			candidates = append(candidates, candidate{mediaType, quality})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].quality > candidates[j].quality
+	})
+
+	for _, c := range candidates {
+		switch c.mediaType {
+		case MEDIA_TYPE_CYCLONEDX:
+			return export.FORMAT_CYCLONEDX, nil
+		case MEDIA_TYPE_SPDX:
+			return export.FORMAT_SPDX, nil
+		case MEDIA_TYPE_JSON, "application/*", "*/*":
+			return export.FORMAT_JSON, nil
+		}
+	}
+	return "", fmt.Errorf("none of the accepted media types is supported, use %s, %s or %s", MEDIA_TYPE_JSON, MEDIA_TYPE_CYCLONEDX, MEDIA_TYPE_SPDX)
+}
+
+// hideSandboxPaths makes the paths of the output relative to the upload,
+// including the ones quoted in the errors and warnings
+func hideSandboxPaths(output *types.Output, dir string) {
+	output.RelocatePaths(dir, func(path string) string {
+		relative, err := filepath.Rel(dir, path)
+		if err != nil {
+			return "."
+		}
+		return filepath.ToSlash(relative)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", MEDIA_TYPE_JSON)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Printf("PHP SBOM Server - failed to write the response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Limits applied to uploaded projects
+const (
+	MAX_UPLOAD_SIZE    = 64 << 20
+	MAX_EXTRACTED_SIZE = 256 << 20
+	MAX_ARCHIVE_FILES  = 20000
+)
+
+var errTooLarge = errors.New("upload exceeds the size limit")
+
+// manifestFileNames are the files accepted as individual multipart fields
+var manifestFileNames = []string{"composer.json", "composer.lock"}
+
+// sandbox is a temporary directory the uploaded project is written to
+type sandbox struct {
+	dir       string
+	files     int
+	extracted int64
+}
+
+func newSandbox() (*sandbox, error) {
+	dir, err := os.MkdirTemp("", "php-sbom-upload-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the upload directory: %w", err)
+	}
+	return &sandbox{dir: dir}, nil
+}
+
+// Close removes the sandbox and everything written to it
+func (s *sandbox) Close() error {
+	return os.RemoveAll(s.dir)
+}
+
+// safePath returns the path of an archive entry inside the sandbox, rejecting
+// absolute paths and paths escaping it
+func (s *sandbox) safePath(name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(name, "/") || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("invalid path in archive: %s", name)
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == ".." {
+			return "", fmt.Errorf("invalid path in archive: %s", name)
+		}
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(path.Clean("/"+name))), nil
+}
+
+// writeFile writes an entry to the sandbox, enforcing the size limits
+func (s *sandbox) writeFile(name string, content io.Reader) error {
+	target, err := s.safePath(name)
+	if err != nil {
+		return err
+	}
+	s.files++
+	if s.files > MAX_ARCHIVE_FILES {
+		return fmt.Errorf("archive contains more than %d files", MAX_ARCHIVE_FILES)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	remaining := MAX_EXTRACTED_SIZE - s.extracted
+	written, err := io.Copy(file, io.LimitReader(content, remaining+1))
+	s.extracted += written
+	if err != nil {
+		return err
+	}
+	if written > remaining {
+		return errTooLarge
+	}
+	return nil
+}
+
+// extractArchive detects the archive type from its content and extracts it.
+// Symbolic links and special files are skipped.
+func (s *sandbox) extractArchive(data []byte) error {
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")), bytes.HasPrefix(data, []byte("PK\x05\x06")):
+		return s.extractZip(data)
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("invalid gzip archive: %w", err)
+		}
+		defer reader.Close()
+		return s.extractTar(reader)
+	case len(data) > 262 && string(data[257:262]) == "ustar":
+		return s.extractTar(bytes.NewReader(data))
+	default:
+		return fmt.Errorf("unsupported archive, expected zip, tar or tar.gz")
+	}
+}
+
+func (s *sandbox) extractZip(data []byte) error {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("invalid zip archive: %w", err)
+	}
+	for _, entry := range reader.File {
+		if !entry.Mode().IsRegular() {
+			continue
+		}
+		content, err := entry.Open()
+		if err != nil {
+			return fmt.Errorf("invalid zip entry %s: %w", entry.Name, err)
+		}
+		err = s.writeFile(entry.Name, content)
+		content.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sandbox) extractTar(r io.Reader) error {
+	reader := tar.NewReader(r)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid tar archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := s.writeFile(header.Name, reader); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package src
+
+import (
+	"github.com/CodeClarityCE/plugin-php-sbom/src/graph"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/parser"
+)
+
+// sharedLock returns the part of the root lock file a workspace without its
+// own lock installs: the packages reachable from its require and require-dev.
+// Built from it, the workspace gets its own subgraph, direct and transitive
+// dependencies and scopes.
+func sharedLock(composerJSON *parser.ComposerJSON, rootLock *parser.ComposerLock) *parser.ComposerLock {
+	dependencyGraph := graph.Build(composerJSON, rootLock)
+	reachable := func(packages []parser.PackageInfo) []parser.PackageInfo {
+		kept := []parser.PackageInfo{}
+		for _, pkg := range packages {
+			if node := dependencyGraph.Node(pkg.Name); node != nil && node.Reachable() {
+				kept = append(kept, pkg)
+			}
+		}
+		return kept
+	}
+
+	lock := *rootLock
+	lock.Packages = reachable(rootLock.Packages)
+	lock.PackagesDev = reachable(rootLock.PackagesDev)
+	return &lock
+}
@@ -0,0 +1,206 @@
+package src
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/constraint"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/graph"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/parser"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+)
+
+// Stability risks reported on packages
+const (
+	RISK_DEV_BRANCH    = "dev-branch"
+	RISK_PRE_RELEASE   = "pre-release"
+	RISK_COMMIT_PINNED = "commit-pinned"
+	RISK_FORK          = "fork"
+)
+
+var commitReferencePattern = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+// stabilityContext holds the root information needed to assess the stability
+// of the locked packages
+type stabilityContext struct {
+	// pinned maps the normalized names of packages the root pins to a commit
+	pinned map[string]bool
+	// customRepositories contains the normalized URLs of the VCS repositories
+	// declared in the root composer.json
+	customRepositories map[string]bool
+}
+
+func newStabilityContext(composerJSON *parser.ComposerJSON) stabilityContext {
+	ctx := stabilityContext{
+		pinned:             make(map[string]bool),
+		customRepositories: make(map[string]bool),
+	}
+	if composerJSON == nil {
+		return ctx
+	}
+
+	for _, requires := range []map[string]string{composerJSON.Require, composerJSON.RequireDev} {
+		for name, requirement := range requires {
+			if i := strings.Index(requirement, "#"); i >= 0 && commitReferencePattern.MatchString(strings.TrimSpace(requirement[i+1:])) {
+				ctx.pinned[graph.NormalizeName(name)] = true
+			}
+		}
+	}
+	for _, repository := range composerJSON.Repositories {
+		switch repository.Type {
+		case "vcs", "git", "github", "gitlab", "bitbucket":
+			ctx.customRepositories[normalizeRepositoryURL(repository.URL)] = true
+		}
+	}
+	return ctx
+}
+
+// assess returns the stability of a locked package and the risks of its resolution
+func (ctx stabilityContext) assess(pkg parser.PackageInfo) (string, []string) {
+	var risks []string
+
+	version, err := constraint.ParseVersion(pkg.Version)
+	stability := constraint.STABILITY_DEV
+	if err == nil {
+		stability = version.Stability
+	}
+	switch stability {
+	case constraint.STABILITY_DEV:
+		risks = append(risks, RISK_DEV_BRANCH)
+	case constraint.STABILITY_ALPHA, constraint.STABILITY_BETA, constraint.STABILITY_RC:
+		risks = append(risks, RISK_PRE_RELEASE)
+	}
+
+	if ctx.pinned[graph.NormalizeName(pkg.Name)] || (err == nil && version.IsBranch() && commitReferencePattern.MatchString(version.Branch)) {
+		risks = append(risks, RISK_COMMIT_PINNED)
+	}
+
+	if ctx.isFork(pkg) {
+		risks = append(risks, RISK_FORK)
+	}
+
+	return stability.String(), risks
+}
+
+// isFork reports whether a package is installed from a repository declared in
+// the root composer.json whose owner differs from the package vendor
+func (ctx stabilityContext) isFork(pkg parser.PackageInfo) bool {
+	url := normalizeRepositoryURL(pkg.Source.URL)
+	if url == "" || !ctx.customRepositories[url] {
+		return false
+	}
+	owner := repositoryOwner(url)
+	vendor, _ := parser.GetPackageName(pkg.Name)
+	return owner != "" && vendor != "" && !strings.EqualFold(owner, vendor)
+}
+
+// normalizeRepositoryURL reduces the different forms of a repository URL to
+// host/owner/name, e.g. git@github.com:acme/lib.git and
+// https://github.com:443/acme/lib give github.com/acme/lib
+func normalizeRepositoryURL(repository string) string {
+	repository = strings.ToLower(strings.TrimSpace(repository))
+	if strings.Contains(repository, "://") {
+		parsed, err := url.Parse(repository)
+		if err != nil {
+			return ""
+		}
+		// The user and the port are not part of the repository
+		repository = parsed.Hostname() + "/" + strings.TrimPrefix(parsed.Path, "/")
+	} else {
+		// scp-like syntax: [user@]host:owner/name
+		if i := strings.Index(repository, "@"); i >= 0 && i < strings.Index(repository+"/", "/") {
+			repository = repository[i+1:]
+		}
+		repository = strings.Replace(repository, ":", "/", 1)
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(repository, "/"), ".git")
+}
+
+// repositoryOwner returns the owner part of a normalized repository URL
+func repositoryOwner(url string) string {
+	parts := strings.Split(url, "/")
+	if len(parts) < 3 {
+		return ""
+	}
+	return parts[1]
+}
+
+// computeStabilitySummary summarizes the stability of the packages of every
+// workspace and lists the production packages resolved to unreleased code
+func computeStabilitySummary(composerLock *parser.ComposerLock, workspaces map[string]types.WorkSpace) *types.StabilitySummary {
+	summary := &types.StabilitySummary{
+		Counts:          make(map[string]int),
+		RiskyProduction: []types.StabilityRisk{},
+	}
+	if composerLock != nil {
+		summary.MinimumStability = composerLock.MinimumStability
+		summary.PreferStable = composerLock.PreferStable
+	}
+	if summary.MinimumStability == "" {
+		summary.MinimumStability = "stable"
+	}
+
+	seen := make(map[string]bool)
+	for _, workspaceName := range sortedKeys(workspaces) {
+		for name, versions := range workspaces[workspaceName].Dependencies {
+			for version, info := range versions {
+				key := name + VERSION_SEPARATOR + version
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+
+				summary.Counts[info.Stability]++
+				for _, risk := range info.StabilityRisks {
+					switch risk {
+					case RISK_DEV_BRANCH:
+						summary.DevBranches++
+					case RISK_PRE_RELEASE:
+						summary.PreReleases++
+					case RISK_COMMIT_PINNED:
+						summary.CommitPinned++
+					case RISK_FORK:
+						summary.Forks++
+					}
+				}
+
+				if info.Prod && len(info.StabilityRisks) > 0 {
+					risk := types.StabilityRisk{
+						Package:   name,
+						Version:   version,
+						Stability: info.Stability,
+						Risks:     info.StabilityRisks,
+						Workspace: workspaceName,
+					}
+					if composerLock != nil {
+						if flag, ok := composerLock.StabilityFlags[name]; ok {
+							risk.StabilityFlag = parser.StabilityFlagName(flag)
+						}
+					}
+					summary.RiskyProduction = append(summary.RiskyProduction, risk)
+				}
+			}
+		}
+	}
+
+	sort.Slice(summary.RiskyProduction, func(i, j int) bool {
+		a, b := summary.RiskyProduction[i], summary.RiskyProduction[j]
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		// A package locked at several versions across the workspaces
+		return a.Version < b.Version
+	})
+	return summary
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
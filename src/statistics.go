@@ -0,0 +1,123 @@
+package src
+
+import (
+	"strings"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+)
+
+// DEFAULT_PACKAGE_TYPE is the type Composer assigns to packages that do not declare one
+const DEFAULT_PACKAGE_TYPE = "library"
+
+// statisticsAccumulator collects the statistics of one or more workspaces
+// while deduplicating packages shared between them
+type statisticsAccumulator struct {
+	stats    types.Statistics
+	packages map[string]*packageUsage
+	authors  map[string]bool
+	licenses map[string]bool
+}
+
+// packageUsage merges how the workspaces locking a package use it: it is
+// direct when one of them requires it, dev-only when all of them only need
+// it for development
+type packageUsage struct {
+	direct  bool
+	devOnly bool
+}
+
+func newStatisticsAccumulator() *statisticsAccumulator {
+	return &statisticsAccumulator{
+		stats: types.Statistics{
+			LicenseBreakdown: make(map[string]int),
+			TypeBreakdown:    make(map[string]int),
+		},
+		packages: make(map[string]*packageUsage),
+		authors:  make(map[string]bool),
+		licenses: make(map[string]bool),
+	}
+}
+
+// add accounts for every package of the workspace not seen before
+func (acc *statisticsAccumulator) add(workspace types.WorkSpace) {
+	for name, versions := range workspace.Dependencies {
+		for version, info := range versions {
+			key := name + types.VERSION_SEPARATOR + version
+			devOnly := info.Dev && !info.Prod
+			if usage, ok := acc.packages[key]; ok {
+				usage.direct = usage.direct || info.Direct
+				usage.devOnly = usage.devOnly && devOnly
+				continue
+			}
+			acc.packages[key] = &packageUsage{direct: info.Direct, devOnly: devOnly}
+
+			packageType := info.Type
+			if packageType == "" {
+				packageType = DEFAULT_PACKAGE_TYPE
+			}
+			acc.stats.TypeBreakdown[packageType]++
+
+			if len(info.Licenses) == 0 {
+				acc.stats.LicenseBreakdown["unknown"]++
+			}
+			for _, license := range info.Licenses {
+				acc.stats.LicenseBreakdown[license]++
+				acc.licenses[license] = true
+			}
+
+			for _, author := range info.Authors {
+				if id := authorIdentity(author); id != "" {
+					acc.authors[id] = true
+				}
+			}
+		}
+	}
+}
+
+// result returns the accumulated statistics
+func (acc *statisticsAccumulator) result() types.Statistics {
+	stats := acc.stats
+	stats.TotalPackages = len(acc.packages)
+	for _, usage := range acc.packages {
+		if usage.direct {
+			stats.DirectPackages++
+		} else {
+			stats.TransitivePackages++
+		}
+		if usage.devOnly {
+			stats.DevPackages++
+		}
+	}
+	stats.UniqueAuthors = len(acc.authors)
+	stats.UniqueLicenses = len(acc.licenses)
+	return stats
+}
+
+// authorIdentity identifies an author by email, falling back to the name
+func authorIdentity(author types.Author) string {
+	if author.Email != "" {
+		return strings.ToLower(strings.TrimSpace(author.Email))
+	}
+	return strings.ToLower(strings.TrimSpace(author.Name))
+}
+
+// computeWorkspaceStatistics computes the statistics of a single workspace
+func computeWorkspaceStatistics(workspace types.WorkSpace) types.Statistics {
+	acc := newStatisticsAccumulator()
+	acc.add(workspace)
+	return acc.result()
+}
+
+// computeStatistics computes the statistics of every workspace and their aggregate.
+// Packages locked in several workspaces are only counted once in the aggregate.
+func computeStatistics(workspaces map[string]types.WorkSpace) (types.Statistics, map[string]types.Statistics) {
+	aggregate := newStatisticsAccumulator()
+	perWorkspace := make(map[string]types.Statistics, len(workspaces))
+
+	for _, id := range sortedKeys(workspaces) {
+		perWorkspace[id] = computeWorkspaceStatistics(workspaces[id])
+		aggregate.add(workspaces[id])
+	}
+
+	return aggregate.result(), perWorkspace
+}
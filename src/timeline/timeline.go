@@ -0,0 +1,310 @@
+// Package timeline reconstructs the history of the dependencies of a project
+// from the commits of its git repository that touch composer.lock.
+package timeline
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/diff"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/gitobject"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/parser"
+)
+
+// Kinds of timeline events
+const (
+	EVENT_INTRODUCED = "introduced"
+	EVENT_UPGRADED   = "upgraded"
+	EVENT_DOWNGRADED = "downgraded"
+	EVENT_CHANGED    = "changed"
+	EVENT_REMOVED    = "removed"
+)
+
+// DEFAULT_LOCK_PATH is the lock file followed when none is given
+const DEFAULT_LOCK_PATH = "composer.lock"
+
+// Options selects the history to walk
+type Options struct {
+	// Revision is the commit, branch or tag the walk starts from, HEAD by default
+	Revision string
+	// LockPath is the slash separated path of the lock file in the repository
+	LockPath string
+	// MaxCommits stops the walk after this many commits, 0 walks the whole history
+	MaxCommits int
+}
+
+// Timeline is the history of the dependencies of a lock file
+type Timeline struct {
+	Revision string `json:"revision"`
+	Commit   string `json:"commit"`
+	LockPath string `json:"lock_path"`
+	// Commits lists the commits that changed the lock file, oldest first
+	Commits  []CommitInfo     `json:"commits"`
+	Packages []PackageHistory `json:"packages"`
+	// Truncated is set when the walk stopped at MaxCommits, or at the
+	// boundary of a shallow clone
+	Truncated bool `json:"truncated"`
+}
+
+// CommitInfo identifies a commit and its author
+type CommitInfo struct {
+	Hash    string    `json:"hash"`
+	Author  string    `json:"author"`
+	Email   string    `json:"email"`
+	Date    time.Time `json:"date"`
+	Summary string    `json:"summary"`
+}
+
+// Event is a change of a package in a commit
+type Event struct {
+	Type string `json:"type"`
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+	// Change is the kind of version change: major, minor, patch, pre-release, reference or branch
+	Change string     `json:"change,omitempty"`
+	Commit CommitInfo `json:"commit"`
+}
+
+// VersionSpan is a period during which a version was locked
+type VersionSpan struct {
+	Version string     `json:"version"`
+	Since   CommitInfo `json:"since"`
+	// Until is the commit that replaced or removed the version, nil while it is still locked
+	Until *CommitInfo `json:"until,omitempty"`
+	// Duration is the time between the two commits, or until the walked revision
+	Duration time.Duration `json:"duration"`
+}
+
+// PackageHistory is the history of a package
+type PackageHistory struct {
+	Name     string        `json:"name"`
+	Current  string        `json:"current,omitempty"`
+	Events   []Event       `json:"events"`
+	Versions []VersionSpan `json:"versions"`
+}
+
+// Build walks the first-parent history of a revision and records the package
+// changes of every commit modifying the lock file. Merge commits are compared
+// with their first parent, so changes made on a merged branch are attributed
+// to the merge.
+func Build(repository *gitobject.Repository, options Options) (*Timeline, error) {
+	if options.Revision == "" {
+		options.Revision = "HEAD"
+	}
+	if options.LockPath == "" {
+		options.LockPath = DEFAULT_LOCK_PATH
+	}
+
+	head, err := repository.ResolveRevision(options.Revision)
+	if err != nil {
+		return nil, err
+	}
+	timeline := &Timeline{
+		Revision: options.Revision,
+		Commit:   head.String(),
+		LockPath: options.LockPath,
+		Commits:  []CommitInfo{},
+		Packages: []PackageHistory{},
+	}
+
+	touching, baseline, err := commitsTouching(repository, head, options)
+	if err != nil {
+		return nil, err
+	}
+	timeline.Truncated = baseline != nil
+
+	histories := make(map[string]*PackageHistory)
+	previous := diff.Snapshot{Packages: map[string]diff.Package{}, Platform: map[string][]string{}}
+	if baseline != nil && baseline.lock != nil {
+		// Packages locked before the walked commits are not reported as introduced
+		previous = diff.FromLock(baseline.lock)
+	}
+	for _, change := range touching {
+		current := diff.Snapshot{Packages: map[string]diff.Package{}, Platform: map[string][]string{}}
+		if change.lock != nil {
+			current = diff.FromLock(change.lock)
+		}
+		info := commitInfo(change.commit)
+		timeline.Commits = append(timeline.Commits, info)
+		record(histories, diff.CompareSnapshots(previous, current), info)
+		previous = current
+	}
+
+	end := time.Now()
+	if commit, err := repository.Commit(head); err == nil {
+		end = commit.Committer.When
+	}
+	names := make([]string, 0, len(histories))
+	for name := range histories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		history := histories[name]
+		for i := range history.Versions {
+			span := &history.Versions[i]
+			if span.Until != nil {
+				span.Duration = span.Until.Date.Sub(span.Since.Date)
+			} else {
+				span.Duration = end.Sub(span.Since.Date)
+				history.Current = span.Version
+			}
+		}
+		timeline.Packages = append(timeline.Packages, *history)
+	}
+	return timeline, nil
+}
+
+// lockChange is a commit that changed the lock file, with the parsed lock
+// file after the commit, nil when the commit deleted it
+type lockChange struct {
+	commit *gitobject.Commit
+	lock   *parser.ComposerLock
+}
+
+// commitsTouching lists the commits changing the lock file, oldest first.
+// When the walk stops at MaxCommits, it also returns the lock file of the
+// first commit left out. A shallow clone has no history past its boundary
+// commits, the walk stops at them and returns their lock file the same way.
+func commitsTouching(repository *gitobject.Repository, head gitobject.Hash, options Options) ([]lockChange, *lockChange, error) {
+	var changes []lockChange
+	var baseline *lockChange
+
+	current := head
+	for walked := 0; ; walked++ {
+		commit, err := repository.Commit(current)
+		if err != nil {
+			return nil, nil, err
+		}
+		shallow := repository.IsShallow(commit.Hash)
+		if shallow {
+			log.Printf("PHP SBOM Debug - %s is the boundary of a shallow clone, the history stops there", commit.Hash)
+		}
+		if shallow || options.MaxCommits > 0 && walked >= options.MaxCommits {
+			baseline = &lockChange{commit: commit}
+			if blob := lockBlob(repository, commit.Hash, options.LockPath); !blob.IsZero() {
+				baseline.lock, _ = readLock(repository, blob)
+			}
+			break
+		}
+
+		blob := lockBlob(repository, commit.Hash, options.LockPath)
+		var parentBlob gitobject.Hash
+		if len(commit.Parents) > 0 {
+			parentBlob = lockBlob(repository, commit.Parents[0], options.LockPath)
+		}
+		if blob != parentBlob {
+			change := lockChange{commit: commit}
+			if !blob.IsZero() {
+				change.lock, err = readLock(repository, blob)
+			}
+			if err != nil {
+				// Keep the previous state, e.g. for a lock committed with conflict markers
+				log.Printf("PHP SBOM Debug - skipping %s at %s: %v", options.LockPath, commit.Hash, err)
+			} else {
+				changes = append(changes, change)
+			}
+		}
+
+		if len(commit.Parents) == 0 {
+			break
+		}
+		current = commit.Parents[0]
+	}
+
+	// Oldest first
+	for i, j := 0, len(changes)-1; i < j; i, j = i+1, j-1 {
+		changes[i], changes[j] = changes[j], changes[i]
+	}
+	return changes, baseline, nil
+}
+
+func lockBlob(repository *gitobject.Repository, commit gitobject.Hash, lockPath string) gitobject.Hash {
+	entry, err := repository.Entry(commit, lockPath)
+	if err != nil || !entry.IsFile() {
+		return gitobject.Hash{}
+	}
+	return entry.Hash
+}
+
+func readLock(repository *gitobject.Repository, blob gitobject.Hash) (*parser.ComposerLock, error) {
+	_, data, err := repository.Object(blob)
+	if err != nil {
+		return nil, err
+	}
+	return parser.ParseComposerLockData(data)
+}
+
+func commitInfo(commit *gitobject.Commit) CommitInfo {
+	return CommitInfo{
+		Hash:    commit.Hash.String(),
+		Author:  commit.Author.Name,
+		Email:   commit.Author.Email,
+		Date:    commit.Author.When,
+		Summary: commit.Summary(),
+	}
+}
+
+// record adds the changes of a commit to the package histories
+func record(histories map[string]*PackageHistory, report diff.Report, info CommitInfo) {
+	history := func(name string) *PackageHistory {
+		if existing, ok := histories[name]; ok {
+			return existing
+		}
+		created := &PackageHistory{Name: name, Events: []Event{}, Versions: []VersionSpan{}}
+		histories[name] = created
+		return created
+	}
+	closeSpan := func(h *PackageHistory) {
+		if n := len(h.Versions); n > 0 && h.Versions[n-1].Until == nil {
+			until := info
+			h.Versions[n-1].Until = &until
+		}
+	}
+
+	for _, change := range report.Added {
+		h := history(change.Name)
+		h.Events = append(h.Events, Event{Type: EVENT_INTRODUCED, To: change.After, Commit: info})
+		h.Versions = append(h.Versions, VersionSpan{Version: change.After, Since: info})
+	}
+	for _, change := range report.Removed {
+		h := history(change.Name)
+		h.Events = append(h.Events, Event{Type: EVENT_REMOVED, From: change.Before, Commit: info})
+		closeSpan(h)
+	}
+	updates := []struct {
+		eventType string
+		changes   []diff.PackageChange
+	}{
+		{EVENT_UPGRADED, report.Upgraded},
+		{EVENT_DOWNGRADED, report.Downgraded},
+		{EVENT_CHANGED, report.Changed},
+	}
+	for _, update := range updates {
+		for _, change := range update.changes {
+			h := history(change.Name)
+			h.Events = append(h.Events, Event{Type: update.eventType, From: change.Before, To: change.After, Change: change.Change, Commit: info})
+			if change.Before != change.After {
+				closeSpan(h)
+				h.Versions = append(h.Versions, VersionSpan{Version: change.After, Since: info})
+			}
+		}
+	}
+}
+
+// Span returns the periods during which a version of a package was locked
+func (t *Timeline) Span(name string, version string) []VersionSpan {
+	var spans []VersionSpan
+	for _, history := range t.Packages {
+		if history.Name != name {
+			continue
+		}
+		for _, span := range history.Versions {
+			if span.Version == version {
+				spans = append(spans, span)
+			}
+		}
+	}
+	return spans
+}
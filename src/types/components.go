@@ -0,0 +1,34 @@
+package types
+
+// Scopes of a package in a workspace
+const (
+	SCOPE_PROD = "prod"
+	SCOPE_DEV  = "dev"
+)
+
+// Component is a package locked by at least one workspace, with the versions
+// each workspace uses. Output.Components is keyed by its package URL without
+// version, such as pkg:composer/symfony/console.
+type Component struct {
+	Name string `json:"name"`
+	// Versions are keyed by version
+	Versions map[string]ComponentVersion `json:"versions"`
+	// VersionSkew is set when the workspaces do not all use the same version
+	VersionSkew bool `json:"version_skew,omitempty"`
+}
+
+// ComponentVersion is a version of a component and the workspaces using it
+type ComponentVersion struct {
+	PURL       string           `json:"purl"`
+	Workspaces []ComponentUsage `json:"workspaces"`
+}
+
+// ComponentUsage is the use of a component version by a workspace, identified
+// like in Output.WorkSpaces
+type ComponentUsage struct {
+	Workspace string `json:"workspace"`
+	// Scope is prod when the workspace needs the package in production, dev
+	// when only for development
+	Scope  string `json:"scope"`
+	Direct bool   `json:"direct,omitempty"`
+}
@@ -0,0 +1,83 @@
+package types
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// pathDelimiters end a path quoted in a message
+const pathDelimiters = " \t\n\"':,;)]"
+
+// RelocatePaths rewrites the paths under root in the analysis info, when the
+// analyzed directory is not the one to report: the path-valued fields and the
+// paths quoted in the errors and warnings. relocate is only given paths
+// under root, root included; siblings such as root2/ are left unchanged.
+func (output *Output) RelocatePaths(root string, relocate func(string) string) {
+	rewrite := func(p string) string {
+		if p == root || strings.HasPrefix(p, root+string(filepath.Separator)) {
+			return relocate(p)
+		}
+		return p
+	}
+
+	info := &output.AnalysisInfo
+	info.WorkingDirectory = rewrite(info.WorkingDirectory)
+	info.Paths.Lockfile = rewrite(info.Paths.Lockfile)
+	info.Paths.PackageFile = rewrite(info.Paths.PackageFile)
+	for id, p := range info.Paths.WorkSpacePackageFile {
+		info.Paths.WorkSpacePackageFile[id] = rewrite(p)
+	}
+	for id, p := range info.Paths.WorkSpaceLockFile {
+		info.Paths.WorkSpaceLockFile[id] = rewrite(p)
+	}
+	for i := range info.Workspaces.Members {
+		member := &info.Workspaces.Members[i]
+		member.Directory = rewrite(member.Directory)
+		member.PackageFile = rewrite(member.PackageFile)
+		member.LockFile = rewrite(member.LockFile)
+	}
+	for i := range info.Extra.PHARFiles {
+		info.Extra.PHARFiles[i].Path = rewrite(info.Extra.PHARFiles[i].Path)
+	}
+	for i, p := range info.Extra.UnrelatedManifests {
+		info.Extra.UnrelatedManifests[i] = rewrite(p)
+	}
+
+	for i := range info.Errors {
+		info.Errors[i].Public.Description = relocateQuotedPaths(info.Errors[i].Public.Description, root, relocate)
+		info.Errors[i].Private.Description = relocateQuotedPaths(info.Errors[i].Private.Description, root, relocate)
+	}
+	for i := range info.Warnings {
+		warning := &info.Warnings[i]
+		warning.File = rewrite(warning.File)
+		warning.Description = relocateQuotedPaths(warning.Description, root, relocate)
+	}
+}
+
+// relocateQuotedPaths applies relocate to the paths under root in a message
+func relocateQuotedPaths(message string, root string, relocate func(string) string) string {
+	if root == "" {
+		return message
+	}
+	var b strings.Builder
+	for {
+		start := strings.Index(message, root)
+		if start < 0 {
+			b.WriteString(message)
+			return b.String()
+		}
+		end := start + len(root)
+		if end < len(message) && message[end] != filepath.Separator && !strings.ContainsRune(pathDelimiters, rune(message[end])) {
+			// A sibling of root
+			b.WriteString(message[:end])
+			message = message[end:]
+			continue
+		}
+		for end < len(message) && !strings.ContainsRune(pathDelimiters, rune(message[end])) {
+			end++
+		}
+		b.WriteString(message[:start])
+		b.WriteString(relocate(message[start:end]))
+		message = message[end:]
+	}
+}
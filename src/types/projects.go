@@ -0,0 +1,41 @@
+package types
+
+import (
+	codeclarity "github.com/CodeClarityCE/utility-types/codeclarity_db"
+	"github.com/CodeClarityCE/utility-types/exceptions"
+)
+
+// ProjectsOutput is the result of the multi-project mode: the output of each
+// independent project of a repository, keyed by project id, and their index
+type ProjectsOutput struct {
+	Index    ProjectsIndex     `json:"index"`
+	Projects map[string]Output `json:"projects"`
+}
+
+// ProjectsIndex summarizes the projects found. Its errors and warnings
+// concern the search for projects, those of each project are in its output.
+type ProjectsIndex struct {
+	Status   codeclarity.AnalysisStatus `json:"status"`
+	Time     Time                       `json:"time"`
+	Errors   []exceptions.Error         `json:"errors"`
+	Warnings []Diagnostic               `json:"warnings"`
+	// Truncated is set when projects were not analyzed before the deadline
+	Truncated bool             `json:"truncated,omitempty"`
+	Projects  []ProjectSummary `json:"projects"`
+	// UnrelatedManifests are the manifests found outside of every project
+	UnrelatedManifests []string `json:"unrelated_manifests,omitempty"`
+}
+
+// ProjectSummary is the entry of a project in the index
+type ProjectSummary struct {
+	// ID is the directory of the root manifest relative to the analyzed
+	// directory, separated by /, and "." for the analyzed directory itself
+	ID                  string                     `json:"id"`
+	Name                string                     `json:"name"`
+	Framework           string                     `json:"framework,omitempty"`
+	RelativePackageFile string                     `json:"relative_package_file_path"`
+	RelativeLockFile    string                     `json:"relative_lock_file_path,omitempty"`
+	Status              codeclarity.AnalysisStatus `json:"status"`
+	Workspaces          int                        `json:"workspaces"`
+	Dependencies        int                        `json:"dependencies"`
+}
@@ -10,6 +10,8 @@ import (
 type Output struct {
 	WorkSpaces   map[string]WorkSpace `json:"workspaces"`
 	AnalysisInfo AnalysisInfo         `json:"analysis_info"`
+	// PHP-specific: the packages of all workspaces by package URL
+	Components map[string]Component `json:"components"`
 }
 
 // WorkSpace represents a single workspace/project in the SBOM
@@ -17,6 +19,13 @@ type Output struct {
 type WorkSpace struct {
 	Dependencies map[string]map[string]Versions `json:"dependencies"`
 	Start        Start                          `json:"start"`
+	// PHP-specific: platform requirements (php, ext-*, lib-*, composer-plugin-api)
+	Platform map[string]PlatformRequirement `json:"platform,omitempty"`
+	// PHP-specific: risky version constraints found in the manifests
+	ConstraintFindings []ConstraintFinding `json:"constraint_findings,omitempty"`
+	// SharedLock is set when the workspace has no lock file of its own and
+	// its dependencies are resolved against the lock file of the root
+	SharedLock bool `json:"shared_lock,omitempty"`
 }
 
 // Versions represents dependency version information
@@ -31,12 +40,33 @@ type Versions struct {
 	Prod         bool              `json:"prod"`
 	Direct       bool              `json:"direct"`
 	Transitive   bool              `json:"transitive"`
+	DevOnly      bool              `json:"dev_only"`
+	Depth        int               `json:"depth"`
 	Licenses     []string          `json:"licenses"`
 	// PHP-specific fields (will be filtered in extra processing)
 	PHPVersion  string   `json:"php_version,omitempty"`
 	Type        string   `json:"type,omitempty"`
 	Authors     []Author `json:"authors,omitempty"`
 	Description string   `json:"description,omitempty"`
+	// Replaces and Provides list the names this package stands in for, reported
+	// as sub-components of the package
+	Replaces map[string]string `json:"replaces,omitempty"`
+	Provides map[string]string `json:"provides,omitempty"`
+	// Aliases lists the versions this version is aliased as (inline aliases,
+	// lock aliases and branch-alias), e.g. dev-main as 2.1.x-dev
+	Aliases []string `json:"aliases,omitempty"`
+	// ComparableVersion is the version in Composer's normalized form, usable
+	// for range comparisons rather than a semantic version. Dev branches take
+	// the form of their first numeric alias, e.g. 2.1.9999999.9999999-dev for
+	// dev-main as 2.1.x-dev.
+	ComparableVersion string `json:"comparable_version,omitempty"`
+	// Reference is the commit of the locked source, which changes without the
+	// version for dev branches
+	Reference string `json:"reference,omitempty"`
+	// Stability is the stability of the locked version (stable, RC, beta, alpha or dev)
+	Stability string `json:"stability,omitempty"`
+	// StabilityRisks flags risky resolutions: dev-branch, pre-release, commit-pinned, fork
+	StabilityRisks []string `json:"stability_risks,omitempty"`
 }
 
 // Start represents direct dependencies
@@ -50,6 +80,94 @@ type WorkSpaceDependency struct {
 	Name       string `json:"name"`
 	Version    string `json:"version"`
 	Constraint string `json:"constraint"`
+	Alias      string `json:"alias,omitempty"`
+}
+
+// PlatformRequirement represents a platform component needed by the project (PHP-specific)
+// such as the PHP runtime, an extension, a system library or the Composer API
+type PlatformRequirement struct {
+	Name        string               `json:"name"`
+	Type        string               `json:"type"`
+	Constraints []PlatformConstraint `json:"constraints"`
+	Dev         bool                 `json:"dev"`
+	Prod        bool                 `json:"prod"`
+	// LockConstraint is the root constraint recorded in the lock platform/platform-dev sections
+	LockConstraint string `json:"lock_constraint,omitempty"`
+	// Override is the faked version from config.platform / platform-overrides
+	Override string `json:"override,omitempty"`
+	// ProvidedBy lists the packages providing the requirement, such as polyfills
+	ProvidedBy []string `json:"provided_by,omitempty"`
+}
+
+// PlatformConstraint is a constraint put on a platform requirement by a package
+type PlatformConstraint struct {
+	RequiredBy string `json:"required_by"`
+	Version    string `json:"version,omitempty"`
+	Constraint string `json:"constraint"`
+	Dev        bool   `json:"dev"`
+}
+
+// PHPCompatibility is the PHP version range supported by the whole application,
+// computed for the root workspace (PHP-specific)
+type PHPCompatibility struct {
+	// RootConstraint is the require.php constraint of the root composer.json
+	RootConstraint string `json:"root_constraint,omitempty"`
+	// PlatformVersion is the PHP version faked with config.platform.php
+	PlatformVersion string `json:"platform_version,omitempty"`
+	// EffectiveRange is the intersection of the root constraint and of every
+	// production package constraint
+	EffectiveRange string        `json:"effective_range"`
+	Satisfiable    bool          `json:"satisfiable"`
+	Conflicts      []PHPConflict `json:"conflicts,omitempty"`
+}
+
+// PHPConflict is a package whose PHP constraint cannot be satisfied together
+// with the root constraint or the configured platform version
+type PHPConflict struct {
+	Package       string `json:"package"`
+	Version       string `json:"version"`
+	Constraint    string `json:"constraint"`
+	ConflictsWith string `json:"conflicts_with"`
+	Dev           bool   `json:"dev"`
+}
+
+// ConstraintFinding is a risky version constraint, such as *, an unbounded >=
+// or a dev branch, along with where it is written (PHP-specific)
+type ConstraintFinding struct {
+	Rule       string `json:"rule"`
+	Severity   string `json:"severity"`
+	Message    string `json:"message"`
+	Dependency string `json:"dependency"`
+	Constraint string `json:"constraint"`
+	// RequiredBy is the root package or the locked package declaring the constraint
+	RequiredBy string `json:"required_by"`
+	Dev        bool   `json:"dev"`
+	File       string `json:"file"`
+	Line       int    `json:"line,omitempty"`
+	Column     int    `json:"column,omitempty"`
+}
+
+// StabilitySummary summarizes the stability of the resolved packages (PHP-specific)
+type StabilitySummary struct {
+	MinimumStability string         `json:"minimum_stability"`
+	PreferStable     bool           `json:"prefer_stable"`
+	Counts           map[string]int `json:"counts"`
+	DevBranches      int            `json:"dev_branches"`
+	PreReleases      int            `json:"pre_releases"`
+	CommitPinned     int            `json:"commit_pinned"`
+	Forks            int            `json:"forks"`
+	// RiskyProduction lists the production packages depending on unreleased code
+	RiskyProduction []StabilityRisk `json:"risky_production"`
+}
+
+// StabilityRisk is a production package with a risky resolution
+type StabilityRisk struct {
+	Package       string   `json:"package"`
+	Version       string   `json:"version"`
+	Stability     string   `json:"stability"`
+	Risks         []string `json:"risks"`
+	StabilityFlag string   `json:"stability_flag,omitempty"`
+	Workspace     string   `json:"workspace"`
 }
 
 // Author represents package author information (PHP-specific)
@@ -68,17 +186,32 @@ type AnalysisInfo struct {
 	PackageManager   string                     `json:"package_manager"`
 	Time             Time                       `json:"time"`
 	Errors           []exceptions.Error         `json:"errors"`
+	Warnings         []Diagnostic               `json:"warnings"`
+	// Truncated is set when the analysis stopped at its deadline, the SBOM is partial
+	Truncated        bool                       `json:"truncated,omitempty"`
 	Paths            Paths                      `json:"paths"`
 	Workspaces       Workspaces                 `json:"workspaces"`
 	Extra            Extra                      `json:"extra"`
 }
 
+// Diagnostic is a warning or note reported by the analysis, with the
+// manifest it concerns when there is one
+type Diagnostic struct {
+	Severity    string `json:"severity"`
+	Type        string `json:"key"`
+	Description string `json:"description"`
+	File        string `json:"file,omitempty"`
+	Line        int    `json:"line,omitempty"`
+	Column      int    `json:"column,omitempty"`
+}
+
 // Paths contains file path information
 // Adapted for PHP (composer.json/composer.lock instead of package.json/package-lock.json)
 type Paths struct {
 	Lockfile             string            `json:"lock_file_path"`
 	PackageFile          string            `json:"package_file_path"`
 	WorkSpacePackageFile map[string]string `json:"work_space_package_file_paths"`
+	WorkSpaceLockFile    map[string]string `json:"work_space_lock_file_paths,omitempty"`
 	RelativeLockFile     string            `json:"relative_lock_file_path"`
 	RelativePackageFile  string            `json:"relative_package_file_path"`
 }
@@ -98,12 +231,59 @@ type Extra struct {
 	PluginAPIVersion     string            `json:"plugin_api_version,omitempty"`
 	ContentHash          string            `json:"content_hash,omitempty"`
 	Platform             map[string]string `json:"platform,omitempty"`
+	PlatformOverrides    map[string]string `json:"platform_overrides,omitempty"`
+	RequiredExtensions   []string          `json:"required_extensions,omitempty"`
+	PHPCompatibility     *PHPCompatibility `json:"php_compatibility,omitempty"`
+	StabilitySummary     *StabilitySummary `json:"stability_summary,omitempty"`
 	Statistics           Statistics        `json:"statistics,omitempty"`
+	WorkspaceStatistics  map[string]Statistics `json:"workspace_statistics,omitempty"`
+	// Revision and Commit are set when the manifests are read from git
+	Revision             string            `json:"revision,omitempty"`
+	Commit               string            `json:"commit,omitempty"`
+	// PolicyViolations are the conditions of the configured policy met by the SBOM
+	PolicyViolations     []PolicyViolation `json:"policy_violations,omitempty"`
+	// UnrelatedManifests are the manifests found that are not workspaces of the root
+	UnrelatedManifests   []string          `json:"unrelated_manifests,omitempty"`
 	// PHAR and vendor support
 	PHARFiles            []PHARInfo        `json:"phar_files,omitempty"`
 	HasVendorDirectory   bool              `json:"has_vendor_directory,omitempty"`
 }
 
+// PolicyViolation is a condition of the analysis policy met by the SBOM
+type PolicyViolation struct {
+	Rule    string `json:"rule"`
+	Package string `json:"package,omitempty"`
+	Message string `json:"message"`
+}
+
+// Workspace describes a workspace of the output. Its ID is its key in
+// Output.WorkSpaces, Paths and Extra.WorkspaceStatistics: "." for the root,
+// else the path of its manifest relative to the working directory. The
+// other paths are relative to the working directory too, separated by /.
+type Workspace struct {
+	ID string `json:"id"`
+	// Name is the package name, or the directory when the manifest has no
+	// name, made unique among the workspaces
+	Name        string `json:"name"`
+	PackageName string `json:"package_name,omitempty"`
+	Directory   string `json:"directory"`
+	PackageFile string `json:"package_file"`
+	LockFile    string `json:"lock_file,omitempty"`
+	// Parent is the closest workspace whose directory contains this one
+	Parent   string   `json:"parent,omitempty"`
+	Children []string `json:"children,omitempty"`
+	// Source is how the root manifest declares the workspace: a path
+	// repository, a merge-plugin include or a monorepo-builder package
+	Source string `json:"source,omitempty"`
+	// LockedVersion is the version of the root lock entry installing the
+	// workspace from its path, when there is one
+	LockedVersion string `json:"locked_version,omitempty"`
+	LockedAsDev   bool   `json:"locked_as_dev,omitempty"`
+	// SharedLock is set when LockFile is the lock file of the root, the
+	// workspace having none of its own
+	SharedLock bool `json:"shared_lock,omitempty"`
+}
+
 // PHARInfo represents information about a PHAR archive
 type PHARInfo struct {
 	Path         string                 `json:"path"`
@@ -122,6 +302,8 @@ type Workspaces struct {
 	DefaultWorkspaceName     string `json:"default_workspace_name"`
 	SelfManagedWorkspaceName string `json:"self_managed_workspace_name"`
 	WorkSpacesUsed           bool   `json:"work_spaces_used"`
+	// Members describe the workspaces of the output, the root first
+	Members []Workspace `json:"members,omitempty"`
 }
 
 // Time contains timing information
@@ -132,7 +314,10 @@ type Time struct {
 	AnalysisDeltaTime float64 `json:"analysis_delta_time"`
 }
 
-// Statistics contains analysis statistics (PHP-specific, goes in Extra)
+// Statistics contains analysis statistics (PHP-specific, goes in Extra).
+// A package is counted once, as direct when a workspace requires it and as
+// transitive otherwise, so that direct and transitive packages add up to the
+// total. Dev packages are only needed for development by every workspace.
 type Statistics struct {
 	TotalPackages          int            `json:"total_packages"`
 	DirectPackages         int            `json:"direct_packages"`
@@ -155,10 +340,15 @@ const (
 	PACKAGE_MANAGER                  = "composer"
 )
 
+// SUCCESS_WITH_WARNINGS is the status of an analysis that produced a
+// partial SBOM, some manifests or archives could not be read
+const SUCCESS_WITH_WARNINGS codeclarity.AnalysisStatus = "success_with_warnings"
+
 // ConvertOutputToMap converts the PHP SBOM output to map (compatible with js-sbom)
 func ConvertOutputToMap(output Output) map[string]interface{} {
 	outputMap := make(map[string]interface{})
 	outputMap["workspaces"] = output.WorkSpaces
 	outputMap["analysis_info"] = output.AnalysisInfo
+	outputMap["components"] = output.Components
 	return outputMap
 }
\ No newline at end of file
@@ -0,0 +1,110 @@
+package src
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/project_finder"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+)
+
+// describeWorkspaces returns the model of the root and of each analyzed
+// workspace, under the IDs of the output, the root first
+func describeWorkspaces(projectInfo *project_finder.ProjectInfo, workspaces map[string]types.WorkSpace) []types.Workspace {
+	rootDir := filepath.Dir(projectInfo.ComposerJSONPath)
+	root := types.Workspace{
+		ID:          types.DEFAULT_WORKSPACE_CHARACTER,
+		PackageName: projectInfo.Name,
+		Directory:   ".",
+		PackageFile: filepath.ToSlash(filepath.Base(projectInfo.ComposerJSONPath)),
+	}
+	if projectInfo.ComposerLockPath != "" {
+		root.LockFile = filepath.ToSlash(filepath.Base(projectInfo.ComposerLockPath))
+	}
+	members := []types.Workspace{root}
+
+	for _, ws := range projectInfo.Workspaces {
+		if _, ok := workspaces[ws.ID]; !ok {
+			// Not analyzed, the analysis was interrupted
+			continue
+		}
+		member := types.Workspace{
+			ID:          ws.ID,
+			PackageName: ws.Name,
+			Directory:   path.Dir(ws.ID),
+			PackageFile: ws.ID,
+			Source:      string(ws.Source),
+		}
+		lockPath := ws.ComposerLockPath
+		if workspaces[ws.ID].SharedLock {
+			lockPath = projectInfo.ComposerLockPath
+			member.SharedLock = true
+		}
+		if lockPath != "" {
+			if relative, err := filepath.Rel(rootDir, lockPath); err == nil {
+				member.LockFile = filepath.ToSlash(relative)
+			}
+		}
+		if ws.LockEntry != nil {
+			member.LockedVersion = ws.LockEntry.Version
+			member.LockedAsDev = ws.LockedAsDev
+		}
+		members = append(members, member)
+	}
+	sort.SliceStable(members[1:], func(i, j int) bool {
+		return members[1+i].ID < members[1+j].ID
+	})
+
+	nameWorkspaces(members)
+	relateWorkspaces(members)
+	return members
+}
+
+// nameWorkspaces sets the display names: the package name, or the directory
+// when there is none, followed by the directory when several workspaces share it
+func nameWorkspaces(members []types.Workspace) {
+	counts := map[string]int{}
+	for i := range members {
+		members[i].Name = members[i].PackageName
+		if members[i].Name == "" {
+			members[i].Name = members[i].Directory
+		}
+		counts[members[i].Name]++
+	}
+	for i := range members {
+		if counts[members[i].Name] > 1 {
+			members[i].Name = fmt.Sprintf("%s (%s)", members[i].Name, members[i].Directory)
+		}
+	}
+}
+
+// relateWorkspaces sets the parent of each workspace, the closest one whose
+// directory contains it, and the children of each parent
+func relateWorkspaces(members []types.Workspace) {
+	byDirectory := map[string]int{}
+	for i, member := range members {
+		byDirectory[member.Directory] = i
+	}
+	for i := 1; i < len(members); i++ {
+		parent := 0
+		for dir := path.Dir(members[i].Directory); dir != "."; dir = path.Dir(dir) {
+			if index, ok := byDirectory[dir]; ok {
+				parent = index
+				break
+			}
+		}
+		members[i].Parent = members[parent].ID
+		members[parent].Children = append(members[parent].Children, members[i].ID)
+	}
+}
+
+// unrelatedManifests returns the manifests found that are not workspaces
+func unrelatedManifests(projectInfo *project_finder.ProjectInfo) []string {
+	var unrelated []string
+	for _, manifest := range projectInfo.UnrelatedManifests {
+		unrelated = append(unrelated, manifest.RelativeComposerJSON)
+	}
+	return unrelated
+}
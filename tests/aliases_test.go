@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+
+	plugin "github.com/CodeClarityCE/plugin-php-sbom/src"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/parser"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAliases(t *testing.T) {
+	out := plugin.Start("./aliases", uuid.UUID{}, nil)
+	deps := out.WorkSpaces["."].Dependencies
+
+	// Lock alias from the root inline alias, then the branch-alias
+	framework := deps["acme/framework"]["dev-main"]
+	assert.Equal(t, []string{"2.1.x-dev", "2.2.x-dev"}, framework.Aliases)
+	assert.Equal(t, "2.1.9999999.9999999-dev", framework.ComparableVersion)
+
+	tooling := deps["acme/tooling"]["dev-master"]
+	assert.Equal(t, []string{"3.1.x-dev"}, tooling.Aliases)
+	assert.Equal(t, "3.1.9999999.9999999-dev", tooling.ComparableVersion)
+
+	for _, dep := range out.WorkSpaces["."].Start.Dependencies {
+		if dep.Name == "acme/framework" {
+			assert.Equal(t, "2.1.x-dev", dep.Alias)
+			assert.Equal(t, "dev-main", dep.Version)
+		}
+	}
+
+	// Tagged releases are comparable as is
+	for _, version := range plugin.Start("./test1", uuid.UUID{}, nil).WorkSpaces["."].Dependencies["bacon/bacon-qr-code"] {
+		assert.Equal(t, "3.0.1", version.ComparableVersion)
+		assert.Empty(t, version.Aliases)
+	}
+}
+
+func TestParseInlineAlias(t *testing.T) {
+	cases := map[string][3]string{
+		"dev-main as 2.1.x-dev":         {"dev-main", "2.1.x-dev", "true"},
+		"dev-main#abc123 as 1.0.x-dev":  {"dev-main", "1.0.x-dev", "true"},
+		"  dev-main   as   1.0.x-dev  ": {"dev-main", "1.0.x-dev", "true"},
+		"^1.0":                          {"^1.0", "", "false"},
+		"dev-main as":                   {"dev-main as", "", "false"},
+		"1.0 as 2.0 as 3.0":             {"1.0 as 2.0 as 3.0", "", "false"},
+	}
+	for constraint, expected := range cases {
+		version, alias, ok := parser.ParseInlineAlias(constraint)
+		assert.Equal(t, expected, [3]string{version, alias, strconv.FormatBool(ok)}, constraint)
+	}
+}
+
+func TestInlineAliasPinnedCommit(t *testing.T) {
+	dir := t.TempDir()
+	writeManifest(t, dir, "composer.json", `{"name": "acme/app", "require": {"acme/framework": "dev-main#abc123 as 2.1.x-dev"}}`)
+	writeManifest(t, dir, "composer.lock", `{"packages": [
+		{"name": "acme/framework", "version": "dev-main", "source": {"type": "git", "url": "https://github.com/acme/framework.git", "reference": "abc123"}}
+	]}`)
+
+	framework := plugin.Start(dir, uuid.UUID{}, nil).WorkSpaces["."].Dependencies["acme/framework"]["dev-main"]
+	assert.Equal(t, []string{"2.1.x-dev"}, framework.Aliases)
+	assert.Equal(t, "2.1.9999999.9999999-dev", framework.ComparableVersion)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	plugin "github.com/CodeClarityCE/plugin-php-sbom/src"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/diagnostics"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/options"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+	codeclarity "github.com/CodeClarityCE/utility-types/codeclarity_db"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// countdownContext expires after its Err method was called a number of
+// times, so that an analysis can be interrupted at each of its checks
+type countdownContext struct {
+	context.Context
+	mu        sync.Mutex
+	remaining int
+}
+
+func (c *countdownContext) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.remaining <= 0 {
+		return context.DeadlineExceeded
+	}
+	c.remaining--
+	return nil
+}
+
+func TestStartContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	out := plugin.StartContext(ctx, "./test1", uuid.UUID{}, nil, options.Default())
+	assert.Equal(t, codeclarity.FAILURE, out.AnalysisInfo.Status)
+	assert.Len(t, out.AnalysisInfo.Errors, 1)
+	assert.Equal(t, diagnostics.ERROR_ANALYSIS_INTERRUPTED, out.AnalysisInfo.Errors[0].Private.Type)
+}
+
+func TestStartContextTruncated(t *testing.T) {
+	root := t.TempDir()
+	writeManifest(t, root, "composer.json", `{"name": "acme/root", "repositories": [{"type": "path", "url": "packages/*"}]}`)
+	for i := 0; i < 5; i++ {
+		writeManifest(t, filepath.Join(root, "packages", fmt.Sprintf("lib%d", i)), "composer.json", fmt.Sprintf(`{"name": "acme/lib%d"}`, i))
+	}
+
+	truncated := 0
+	for checks := 0; ; checks++ {
+		ctx := &countdownContext{Context: context.Background(), remaining: checks}
+		out := plugin.StartContext(ctx, root, uuid.UUID{}, nil, options.Default())
+		if out.AnalysisInfo.Status == codeclarity.FAILURE {
+			assert.Equal(t, diagnostics.ERROR_ANALYSIS_INTERRUPTED, out.AnalysisInfo.Errors[0].Private.Type)
+			continue
+		}
+		assert.Contains(t, out.WorkSpaces, ".")
+		if !out.AnalysisInfo.Truncated {
+			// Enough checks to complete the analysis
+			assert.Len(t, out.WorkSpaces, 6)
+			break
+		}
+
+		truncated++
+		assert.Equal(t, types.SUCCESS_WITH_WARNINGS, out.AnalysisInfo.Status)
+		kinds := []string{}
+		for _, warning := range out.AnalysisInfo.Warnings {
+			kinds = append(kinds, warning.Type)
+		}
+		assert.Contains(t, kinds, string(diagnostics.WARNING_ANALYSIS_TRUNCATED))
+		assert.Less(t, checks, 1000)
+	}
+	assert.Greater(t, truncated, 0)
+}
+
+func TestTimeoutOption(t *testing.T) {
+	opts, err := options.Parse(map[string]any{"timeout": "90s"})
+	assert.Nil(t, err)
+	assert.Equal(t, 90*time.Second, opts.Timeout)
+	opts, err = options.Parse(map[string]any{"timeout": float64(5)})
+	assert.Nil(t, err)
+	assert.Equal(t, 5*time.Second, opts.Timeout)
+	_, err = options.Parse(map[string]any{"timeout": "soon"})
+	assert.NotNil(t, err)
+
+	opts.Timeout = time.Nanosecond
+	out := plugin.StartWithOptions("./test1", uuid.UUID{}, nil, opts)
+	assert.Equal(t, codeclarity.FAILURE, out.AnalysisInfo.Status)
+}
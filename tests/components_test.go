@@ -0,0 +1,62 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	plugin "github.com/CodeClarityCE/plugin-php-sbom/src"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregatedComponents(t *testing.T) {
+	root := t.TempDir()
+	writeManifest(t, root, "composer.json", `{"name": "acme/app", "require": {"psr/log": "^3.0", "acme/http": "^1.0"}, "repositories": [{"type": "path", "url": "packages/*"}]}`)
+	writeManifest(t, root, "composer.lock", `{"packages": [
+		{"name": "acme/http", "version": "1.2.0", "require": {"psr/log": "^3.0"}},
+		{"name": "psr/log", "version": "3.0.0"}
+	]}`)
+	writeManifest(t, filepath.Join(root, "packages", "legacy"), "composer.json", `{"name": "acme/legacy", "require": {"acme/http": "^1.0"}, "require-dev": {"psr/log": "^1.1"}}`)
+	writeManifest(t, filepath.Join(root, "packages", "legacy"), "composer.lock", `{
+		"packages": [{"name": "acme/http", "version": "1.2.0"}],
+		"packages-dev": [{"name": "psr/log", "version": "1.1.4"}]
+	}`)
+
+	out := plugin.Start(root, uuid.UUID{}, nil)
+	assert.Len(t, out.Components, 2)
+
+	psrLog := out.Components["pkg:composer/psr/log"]
+	assert.Equal(t, "psr/log", psrLog.Name)
+	assert.True(t, psrLog.VersionSkew)
+	assert.Equal(t, types.ComponentVersion{
+		PURL:       "pkg:composer/psr/log@3.0.0",
+		Workspaces: []types.ComponentUsage{{Workspace: ".", Scope: types.SCOPE_PROD, Direct: true}},
+	}, psrLog.Versions["3.0.0"])
+	assert.Equal(t, types.ComponentVersion{
+		PURL:       "pkg:composer/psr/log@1.1.4",
+		Workspaces: []types.ComponentUsage{{Workspace: "packages/legacy/composer.json", Scope: types.SCOPE_DEV, Direct: true}},
+	}, psrLog.Versions["1.1.4"])
+
+	http := out.Components["pkg:composer/acme/http"]
+	assert.False(t, http.VersionSkew)
+	assert.Len(t, http.Versions, 1)
+	assert.Len(t, http.Versions["1.2.0"].Workspaces, 2)
+
+	// Excluded packages leave the table
+	filtered := plugin.ExcludePackages(out, []string{"psr/*"})
+	assert.NotContains(t, filtered.Components, "pkg:composer/psr/log")
+	assert.Contains(t, filtered.Components, "pkg:composer/acme/http")
+}
+
+func TestAggregatedComponentsSingleWorkspace(t *testing.T) {
+	root := t.TempDir()
+	writeManifest(t, root, "composer.json", `{"name": "acme/app", "require": {"psr/log": "^3.0"}}`)
+	writeManifest(t, root, "composer.lock", `{"packages": [{"name": "psr/log", "version": "3.0.0"}]}`)
+
+	out := plugin.Start(root, uuid.UUID{}, nil)
+	psrLog := out.Components["pkg:composer/psr/log"]
+	assert.False(t, psrLog.VersionSkew)
+	assert.Equal(t, []types.ComponentUsage{{Workspace: ".", Scope: types.SCOPE_PROD, Direct: true}}, psrLog.Versions["3.0.0"].Workspaces)
+	assert.Contains(t, types.ConvertOutputToMap(out), "components")
+}
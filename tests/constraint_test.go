@@ -0,0 +1,99 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/constraint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConstraintMatching(t *testing.T) {
+	cases := []struct {
+		constraint string
+		version    string
+		expected   bool
+	}{
+		{"^8.1", "8.1.0", true},
+		{"^8.1", "8.3.12", true},
+		{"^8.1", "9.0.0", false},
+		{"^0.3", "0.3.5", true},
+		{"^0.3", "0.4.0", false},
+		{"^0.0.3", "0.0.4", false},
+		{"~1.2", "1.9.0", true},
+		{"~1.2", "2.0.0", false},
+		{"~1.2.3", "1.2.9", true},
+		{"~1.2.3", "1.3.0", false},
+		{">=7.4 <8.1", "8.0.30", true},
+		{">=7.4 <8.1", "8.1.0", false},
+		{">=7.4, <8.1", "7.3.0", false},
+		{"7.4.*", "7.4.33", true},
+		{"7.4.*", "7.5.0", false},
+		{"^7.4 || ^8.0", "8.2.0", true},
+		{"^7.4|^8.0", "7.3.0", false},
+		{"1.0 - 2.0", "2.0.5", true},
+		{"1.0 - 2.0", "2.1.0", false},
+		{"!=1.5.0", "1.5.0", false},
+		{">= 8.2", "8.2.0", true},
+		{"*", "1.0.0", true},
+		{"^1.0@dev", "1.2.0", true},
+		{"dev-master", "dev-master", true},
+		{"dev-master#30c24a0dbebd5a91ce7db9ce3a67db1a0d4711e3", "dev-master", true},
+		{"dev-main as 2.1.x-dev", "dev-main", true},
+		{"^2.0", "v2.3.1", true},
+		{"^1.0", "1.1.0-beta2", true},
+		{">8.0", "8.0.0", false},
+	}
+
+	for _, c := range cases {
+		set, err := constraint.Parse(c.constraint)
+		assert.NoError(t, err, c.constraint)
+		version, err := constraint.ParseVersion(c.version)
+		assert.NoError(t, err, c.version)
+		assert.Equal(t, c.expected, set.Contains(version), "%s contains %s", c.constraint, c.version)
+	}
+}
+
+func TestConstraintIntersection(t *testing.T) {
+	effective := constraint.MustParse(">=8.2").Intersect(constraint.MustParse("^8.1")).Intersect(constraint.MustParse("^7.4 || ^8.0"))
+	assert.Equal(t, ">=8.2.0 <9.0.0", effective.String())
+
+	empty := constraint.MustParse("^7.4").Intersect(constraint.MustParse(">=8.0"))
+	assert.True(t, empty.IsEmpty())
+
+	majors, bounded := constraint.MustParse("^1.0 || ^2.0").Majors()
+	assert.True(t, bounded)
+	assert.Equal(t, []int{1, 2}, majors)
+
+	_, bounded = constraint.MustParse(">=1.0").Majors()
+	assert.False(t, bounded)
+	assert.False(t, constraint.MustParse(">=1.0").HasUpperBound())
+
+	_, err := constraint.Parse("self.version")
+	assert.Error(t, err)
+
+	// An empty alternative does not allow any version
+	for _, invalid := range []string{"^1.0 ||", "|| ^1.0", "^1.0 || || ^2.0"} {
+		_, err = constraint.Parse(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestVersionParsing(t *testing.T) {
+	v, err := constraint.ParseVersion("2.1.x-dev")
+	assert.NoError(t, err)
+	assert.Equal(t, constraint.STABILITY_DEV, v.Stability)
+	assert.Equal(t, 2, v.Major())
+
+	v, err = constraint.ParseVersion("v1.0.0-RC2")
+	assert.NoError(t, err)
+	assert.Equal(t, constraint.STABILITY_RC, v.Stability)
+	assert.Equal(t, 2, v.StabilityNumber)
+
+	v, err = constraint.ParseVersion("dev-cakephp5")
+	assert.NoError(t, err)
+	assert.True(t, v.IsBranch())
+
+	a, _ := constraint.ParseVersion("1.10.0")
+	b, _ := constraint.ParseVersion("1.9.9")
+	assert.Equal(t, 1, a.Compare(b))
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	plugin "github.com/CodeClarityCE/plugin-php-sbom/src"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/diagnostics"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/parser"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+	codeclarity "github.com/CodeClarityCE/utility-types/codeclarity_db"
+	"github.com/CodeClarityCE/utility-types/exceptions"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollector(t *testing.T) {
+	ctx, collector := diagnostics.NewContext(context.Background())
+	assert.Same(t, collector, diagnostics.FromContext(ctx))
+	assert.Nil(t, diagnostics.FromContext(context.Background()))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			collector.Warn(diagnostics.WARNING_INVALID_MANIFEST, "invalid", diagnostics.Location{File: "composer.json", Line: 3, Column: 5})
+		}()
+	}
+	wg.Wait()
+	collector.Info(diagnostics.INFO_CONSTRAINTS_UNLOCATED, "note", diagnostics.Location{})
+	collector.Add(diagnostics.Diagnostic{
+		Severity: diagnostics.SEVERITY_ERROR,
+		Public:   exceptions.ErrorContent{Description: "Invalid manifest", Type: exceptions.GENERIC_ERROR},
+		Private:  exceptions.ErrorContent{Description: "unexpected token", Type: "ManifestSyntaxError"},
+		Location: diagnostics.Location{File: "composer.json", Line: 3},
+	})
+
+	assert.True(t, collector.HasErrors())
+	errors := collector.Errors()
+	assert.Len(t, errors, 1)
+	assert.Equal(t, "unexpected token (composer.json:3)", errors[0].Private.Description)
+	warnings := collector.Warnings()
+	assert.Len(t, warnings, 11)
+	assert.Equal(t, "warning", warnings[0].Severity)
+	assert.Equal(t, 3, warnings[0].Line)
+	assert.Equal(t, "info", warnings[10].Severity)
+
+	// A nil collector discards everything
+	var discard *diagnostics.Collector
+	discard.AddError("a", exceptions.GENERIC_ERROR, "b", exceptions.GENERIC_ERROR)
+	assert.False(t, discard.HasErrors())
+	assert.Empty(t, discard.Errors())
+}
+
+func TestErrorsDoNotLeakBetweenAnalyses(t *testing.T) {
+	failed := plugin.Start("./nonexistent", uuid.UUID{}, nil)
+	assert.Equal(t, codeclarity.FAILURE, failed.AnalysisInfo.Status)
+	assert.Len(t, failed.AnalysisInfo.Errors, 1)
+
+	out := plugin.Start("./test1", uuid.UUID{}, nil)
+	assert.Equal(t, codeclarity.SUCCESS, out.AnalysisInfo.Status)
+	assert.Empty(t, out.AnalysisInfo.Errors)
+	assert.Empty(t, out.AnalysisInfo.Warnings)
+
+	failed = plugin.Start("./nonexistent", uuid.UUID{}, nil)
+	assert.Len(t, failed.AnalysisInfo.Errors, 1)
+}
+
+func TestAnalysisWarnings(t *testing.T) {
+	root := t.TempDir()
+	writeManifest(t, root, "composer.json", `{"name": "acme/root", "require": {"acme/lib": "^1.0"}, "repositories": [{"type": "path", "url": "*"}]}`)
+	writeManifest(t, filepath.Join(root, "broken"), "composer.json", "{\n  \"name\": \"acme/broken\",\n  \"require\": {,}\n}")
+	writeManifest(t, filepath.Join(root, "app"), "composer.json", `{"name": "acme/app"}`)
+	writeManifest(t, filepath.Join(root, "app"), "composer.lock", "{\n  \"packages\": {}\n}")
+
+	out := plugin.Start(root, uuid.UUID{}, nil)
+	assert.Equal(t, types.SUCCESS_WITH_WARNINGS, out.AnalysisInfo.Status)
+	assert.Empty(t, out.AnalysisInfo.Errors)
+	// The results of the readable manifests are still there
+	assert.Contains(t, out.WorkSpaces, filepath.Join("app", "composer.json"))
+	assert.NotContains(t, out.WorkSpaces, filepath.Join("broken", "composer.json"))
+
+	warnings := map[string]types.Diagnostic{}
+	for _, warning := range out.AnalysisInfo.Warnings {
+		assert.Equal(t, "warning", warning.Severity)
+		warnings[warning.Type] = warning
+	}
+	assert.Len(t, warnings, 3)
+	assert.Equal(t, "composer.json", warnings[string(diagnostics.WARNING_MISSING_LOCK_FILE)].File)
+	assert.Equal(t, types.Diagnostic{
+		Severity:    "warning",
+		Type:        string(diagnostics.WARNING_INVALID_MANIFEST),
+		Description: "Workspace skipped: failed to parse composer.json at line 3, column 15: invalid character ',' looking for beginning of object key string",
+		File:        filepath.Join("broken", "composer.json"),
+		Line:        3,
+		Column:      15,
+	}, warnings[string(diagnostics.WARNING_INVALID_MANIFEST)])
+	lockWarning := warnings[string(diagnostics.WARNING_INVALID_LOCK_FILE)]
+	assert.Equal(t, filepath.Join("app", "composer.lock"), lockWarning.File)
+	assert.Equal(t, 2, lockWarning.Line)
+	for _, warning := range out.AnalysisInfo.Warnings {
+		assert.False(t, strings.Contains(warning.Description, root), warning.Description)
+	}
+}
+
+func TestInvalidRootManifest(t *testing.T) {
+	root := t.TempDir()
+	writeManifest(t, root, "composer.json", "{\n  \"name\": \"acme/root\"\n  \"require\": {}\n}")
+
+	out := plugin.Start(root, uuid.UUID{}, nil)
+	assert.Equal(t, codeclarity.FAILURE, out.AnalysisInfo.Status)
+	assert.Len(t, out.AnalysisInfo.Errors, 1)
+	assert.Equal(t, diagnostics.ERROR_INVALID_ROOT_MANIFEST, out.AnalysisInfo.Errors[0].Private.Type)
+	assert.True(t, strings.HasSuffix(out.AnalysisInfo.Errors[0].Private.Description, "(composer.json:3:3)"), out.AnalysisInfo.Errors[0].Private.Description)
+
+	_, err := parser.ParseComposerJSON(filepath.Join(root, "composer.json"))
+	var syntaxError *parser.SyntaxError
+	assert.True(t, errors.As(err, &syntaxError))
+	assert.Equal(t, parser.Position{Line: 3, Column: 3}, syntaxError.Position)
+	assert.Equal(t, filepath.Join(root, "composer.json"), syntaxError.File)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"testing"
+
+	plugin "github.com/CodeClarityCE/plugin-php-sbom/src"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/diff"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/parser"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareLockFiles(t *testing.T) {
+	report, err := diff.CompareLockFiles("./diff/before.lock", "./diff/after.lock")
+	assert.Nil(t, err)
+
+	assert.Equal(t, []diff.PackageChange{{Name: "acme/added", After: "0.3.0", Scope: "prod"}}, report.Added)
+	assert.Equal(t, []diff.PackageChange{{Name: "acme/removed", Before: "1.0.0", Scope: "prod"}}, report.Removed)
+
+	changes := make(map[string]string)
+	for _, change := range report.Upgraded {
+		changes[change.Name] = change.Change
+	}
+	assert.Equal(t, map[string]string{"acme/major": "major", "acme/minor": "minor", "acme/patch": "patch"}, changes)
+	assert.Equal(t, []diff.PackageChange{{Name: "acme/down", Before: "5.2.0", After: "5.1.9", Change: "minor", Scope: "prod"}}, report.Downgraded)
+	assert.Equal(t, []diff.PackageChange{{Name: "acme/branch", Before: "dev-main", After: "dev-main", Change: "reference", Scope: "prod"}}, report.Changed)
+
+	assert.Equal(t, []diff.LicenseChange{{Name: "acme/relicensed", Before: []string{"MIT"}, After: []string{"GPL-3.0-only"}}}, report.LicenseChanges)
+	assert.Equal(t, []diff.ScopeChange{{Name: "acme/promoted", Before: "dev", After: "prod"}}, report.ScopeChanges)
+
+	platform := make(map[string]diff.PlatformChange)
+	for _, change := range report.PlatformChanges {
+		platform[change.Name] = change
+	}
+	assert.Equal(t, []string{}, platform["ext-intl"].Before)
+	assert.Equal(t, []string{"*"}, platform["ext-intl"].After)
+	assert.Equal(t, []string{">=8.1", "^8.1"}, platform["php"].After)
+
+	markdown := report.Markdown()
+	assert.Contains(t, markdown, "| acme/major | 1.4.2 | 2.0.0 | major | prod |")
+	assert.Contains(t, markdown, "- acme/relicensed: MIT → GPL-3.0-only")
+}
+
+func TestCompareOutputs(t *testing.T) {
+	out := plugin.Start("./test1", uuid.UUID{}, nil)
+	assert.True(t, diff.Compare(out, out).IsEmpty())
+
+	aliases := plugin.Start("./aliases", uuid.UUID{}, nil)
+	report := diff.Compare(aliases, out)
+	assert.NotEmpty(t, report.Added)
+	assert.NotEmpty(t, report.Removed)
+}
+
+func TestCompareWorkspaces(t *testing.T) {
+	workspace := func(versions map[string]string, dev bool) types.WorkSpace {
+		dependencies := make(map[string]map[string]types.Versions)
+		for name, version := range versions {
+			dependencies[name] = map[string]types.Versions{version: {Prod: !dev, Dev: dev}}
+		}
+		return types.WorkSpace{Dependencies: dependencies}
+	}
+	before := types.Output{WorkSpaces: map[string]types.WorkSpace{
+		".":                             workspace(map[string]string{"psr/log": "3.0.0"}, false),
+		"packages/legacy/composer.json": workspace(map[string]string{"psr/log": "1.1.0", "acme/old": "1.0.0"}, false),
+	}}
+	after := types.Output{WorkSpaces: map[string]types.WorkSpace{
+		".":                             workspace(map[string]string{"psr/log": "3.0.0"}, false),
+		"packages/legacy/composer.json": workspace(map[string]string{"psr/log": "1.1.4"}, true),
+	}}
+
+	// The changes of a workspace are reported although the root is unchanged
+	report := diff.Compare(before, after)
+	assert.Equal(t, []diff.PackageChange{{Workspace: "packages/legacy/composer.json", Name: "psr/log", Before: "1.1.0", After: "1.1.4", Change: diff.CHANGE_PATCH, Scope: diff.SCOPE_DEV}}, report.Upgraded)
+	assert.Equal(t, []diff.PackageChange{{Workspace: "packages/legacy/composer.json", Name: "acme/old", Before: "1.0.0", Scope: diff.SCOPE_PROD}}, report.Removed)
+	assert.Equal(t, []diff.ScopeChange{{Workspace: "packages/legacy/composer.json", Name: "psr/log", Before: diff.SCOPE_PROD, After: diff.SCOPE_DEV}}, report.ScopeChanges)
+	assert.Empty(t, report.Added)
+	assert.Contains(t, report.Markdown(), "| psr/log (packages/legacy/composer.json) | 1.1.0 | 1.1.4 | patch | dev |")
+
+	// The root of an SBOM compares with a lock file
+	lock := diff.FromLock(&parser.ComposerLock{Packages: []parser.PackageInfo{{Name: "psr/log", Version: "3.0.0"}}})
+	assert.Empty(t, diff.CompareSnapshots(lock, diff.FromOutput(before)).Upgraded)
+	assert.Len(t, diff.CompareSnapshots(lock, diff.FromOutput(before)).Added, 2)
+}
+
+func TestCompareOutputsReference(t *testing.T) {
+	analyze := func(reference string) types.Output {
+		dir := t.TempDir()
+		writeManifest(t, dir, "composer.json", `{"name": "acme/app", "require": {"acme/branch": "dev-main"}}`)
+		writeManifest(t, dir, "composer.lock", `{"packages": [
+			{"name": "acme/branch", "version": "dev-main", "source": {"type": "git", "url": "https://github.com/acme/branch.git", "reference": "`+reference+`"}}
+		]}`)
+		return plugin.Start(dir, uuid.UUID{}, nil)
+	}
+	before := analyze("1111111111111111111111111111111111111111")
+	after := analyze("2222222222222222222222222222222222222222")
+
+	// A new commit of the same dev branch is reported between two SBOMs
+	report := diff.Compare(before, after)
+	assert.Equal(t, []diff.PackageChange{{Name: "acme/branch", Before: "dev-main", After: "dev-main", Change: diff.CHANGE_REFERENCE, Scope: diff.SCOPE_PROD}}, report.Changed)
+	assert.True(t, diff.Compare(after, after).IsEmpty())
+}
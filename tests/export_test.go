@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	plugin "github.com/CodeClarityCE/plugin-php-sbom/src"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/export"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/policy"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPackageURL(t *testing.T) {
+	assert.Equal(t, "pkg:composer/symfony/console@v6.4.1", export.PackageURL("Symfony/Console", "v6.4.1"))
+	assert.Equal(t, "pkg:composer/acme/app", export.PackageURL("acme/app", ""))
+}
+
+func TestCycloneDX(t *testing.T) {
+	out := plugin.Start("./test1", uuid.UUID{}, nil)
+	bom := export.CycloneDX(out)
+
+	assert.Equal(t, "CycloneDX", bom.BOMFormat)
+	assert.Equal(t, "passbolt", bom.Metadata.Component.Group)
+
+	var qrCode *export.CycloneDXComponent
+	for i, component := range bom.Components {
+		if component.PURL == "pkg:composer/bacon/bacon-qr-code@v3.0.1" {
+			qrCode = &bom.Components[i]
+		}
+	}
+	if assert.NotNil(t, qrCode) {
+		assert.Equal(t, "bacon", qrCode.Group)
+		assert.Equal(t, "required", qrCode.Scope)
+		assert.Equal(t, "BSD-2-Clause", qrCode.Licenses[0].License.ID)
+	}
+
+	// Every dependency reference points to a component
+	refs := map[string]bool{bom.Metadata.Component.BOMRef: true}
+	for _, component := range bom.Components {
+		refs[component.BOMRef] = true
+	}
+	assert.Len(t, bom.Dependencies, len(bom.Components)+1)
+	for _, dependency := range bom.Dependencies {
+		for _, ref := range dependency.DependsOn {
+			assert.True(t, refs[ref], ref)
+		}
+	}
+}
+
+// licensedOutput returns an output whose root requires one package per
+// license list
+func licensedOutput(licenses map[string][]string) types.Output {
+	dependencies := make(map[string]map[string]types.Versions)
+	start := []types.WorkSpaceDependency{}
+	for name, packageLicenses := range licenses {
+		dependencies[name] = map[string]types.Versions{"1.0.0": {Key: name + "@1.0.0", Prod: true, Direct: true, Licenses: packageLicenses}}
+		start = append(start, types.WorkSpaceDependency{Name: name, Version: "1.0.0"})
+	}
+	return types.Output{
+		WorkSpaces:   map[string]types.WorkSpace{".": {Dependencies: dependencies, Start: types.Start{Dependencies: start}}},
+		AnalysisInfo: types.AnalysisInfo{ProjectName: "acme/app"},
+	}
+}
+
+func TestCycloneDXLicenses(t *testing.T) {
+	bom := export.CycloneDX(licensedOutput(map[string][]string{
+		"acme/a": {"mit"},
+		"acme/b": {"proprietary"},
+		"acme/c": {"GPL-2.0+"},
+		"acme/d": {"MIT", "Apache-2.0"},
+		"acme/e": {"MIT", "proprietary"},
+		"acme/f": {},
+	}))
+
+	licenses := map[string][]export.CycloneDXLicense{}
+	for _, component := range bom.Components {
+		licenses[component.Group+"/"+component.Name] = component.Licenses
+	}
+	assert.Equal(t, []export.CycloneDXLicense{{License: &export.CycloneDXLicenseID{ID: "MIT"}}}, licenses["acme/a"])
+	assert.Equal(t, []export.CycloneDXLicense{{License: &export.CycloneDXLicenseID{Name: "proprietary"}}}, licenses["acme/b"])
+	assert.Equal(t, []export.CycloneDXLicense{{License: &export.CycloneDXLicenseID{ID: "GPL-2.0-or-later"}}}, licenses["acme/c"])
+	assert.Equal(t, []export.CycloneDXLicense{{Expression: "MIT OR Apache-2.0"}}, licenses["acme/d"])
+	assert.Equal(t, []export.CycloneDXLicense{
+		{License: &export.CycloneDXLicenseID{ID: "MIT"}},
+		{License: &export.CycloneDXLicenseID{Name: "proprietary"}},
+	}, licenses["acme/e"])
+	assert.Empty(t, licenses["acme/f"])
+}
+
+func TestSPDX(t *testing.T) {
+	out := plugin.Start("./test1", uuid.UUID{}, nil)
+	data, err := export.Generate(out, export.FORMAT_SPDX)
+	assert.Nil(t, err)
+
+	var document export.SPDXDocument
+	assert.Nil(t, json.Unmarshal(data, &document))
+	assert.Equal(t, "SPDX-2.3", document.SPDXVersion)
+
+	ids := make(map[string]bool)
+	for _, pkg := range document.Packages {
+		assert.Regexp(t, `^SPDXRef-[A-Za-z0-9.-]+$`, pkg.SPDXID)
+		assert.False(t, ids[pkg.SPDXID], pkg.SPDXID)
+		ids[pkg.SPDXID] = true
+	}
+	for _, relationship := range document.Relationships {
+		assert.True(t, relationship.SPDXElementID == "SPDXRef-DOCUMENT" || ids[relationship.SPDXElementID])
+		assert.True(t, ids[relationship.RelatedSPDXElement])
+	}
+}
+
+func TestSPDXLicenses(t *testing.T) {
+	document := export.SPDX(licensedOutput(map[string][]string{
+		"acme/a": {"mit"},
+		"acme/b": {"proprietary"},
+		"acme/c": {"MIT", "GPL-2.0+"},
+		"acme/d": {"lgpl-2.1", "GPL-3.0+"},
+		"acme/e": {"GPL-2.0-", "GPL 2.0"},
+		"acme/f": {},
+		"acme/g": {"MIT+"},
+	}))
+
+	declared := map[string]string{}
+	for _, pkg := range document.Packages {
+		declared[pkg.Name] = pkg.LicenseDeclared
+	}
+	assert.Equal(t, "MIT", declared["acme/a"])
+	assert.Equal(t, "LicenseRef-proprietary", declared["acme/b"])
+	// The deprecated GNU identifiers take their current form
+	assert.Equal(t, "MIT OR GPL-2.0-or-later", declared["acme/c"])
+	assert.Equal(t, "LGPL-2.1-only OR GPL-3.0-or-later", declared["acme/d"])
+	// Distinct licenses never share a reference
+	assert.Equal(t, "LicenseRef-GPL-2.0 OR LicenseRef-GPL-2.0-2", declared["acme/e"])
+	assert.Equal(t, export.SPDX_NOASSERTION, declared["acme/f"])
+	assert.Equal(t, "LicenseRef-MIT", declared["acme/g"])
+
+	refs := map[string]string{}
+	for _, extracted := range document.HasExtractedLicensingInfos {
+		refs[extracted.LicenseID] = extracted.Name
+		assert.NotEmpty(t, extracted.ExtractedText)
+	}
+	assert.Equal(t, map[string]string{
+		"LicenseRef-proprietary": "proprietary",
+		"LicenseRef-GPL-2.0":     "GPL-2.0-",
+		"LicenseRef-GPL-2.0-2":   "GPL 2.0",
+		"LicenseRef-MIT":         "MIT+",
+	}, refs)
+}
+
+func TestSPDXIDCollisions(t *testing.T) {
+	document := export.SPDX(licensedOutput(map[string][]string{
+		"a/b_c": {"MIT"},
+		"a/b-c": {"MIT"},
+		"a-b/c": {"MIT"},
+	}))
+
+	ids := map[string]string{}
+	for _, pkg := range document.Packages {
+		assert.NotContains(t, ids, pkg.SPDXID)
+		ids[pkg.SPDXID] = pkg.Name
+	}
+	assert.Len(t, ids, 4)
+	// Each package keeps its own relationship with the root
+	related := map[string]bool{}
+	for _, relationship := range document.Relationships {
+		related[relationship.RelatedSPDXElement] = true
+	}
+	for id, name := range ids {
+		if name != "acme/app" {
+			assert.True(t, related[id], name)
+		}
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	_, err := policy.Parse("severity:critical")
+	assert.NotNil(t, err)
+	_, err = policy.Parse("unknown")
+	assert.NotNil(t, err)
+
+	out := plugin.Start("./test1", uuid.UUID{}, nil)
+	failOn, err := policy.Parse("stability:fork, license:BSD-2-Clause")
+	assert.Nil(t, err)
+	violations := failOn.Evaluate(out)
+
+	packages := make(map[string]bool)
+	for _, violation := range violations {
+		packages[violation.Package] = true
+	}
+	assert.True(t, packages["lorenzo/cakephp-email-queue"])
+	assert.True(t, packages["bacon/bacon-qr-code"])
+
+	empty, err := policy.Parse("")
+	assert.Nil(t, err)
+	assert.Empty(t, empty.Evaluate(out))
+}
+
+func TestPolicyConstraintLocations(t *testing.T) {
+	out := types.Output{WorkSpaces: map[string]types.WorkSpace{".": {ConstraintFindings: []types.ConstraintFinding{
+		{Rule: "wildcard", Dependency: "acme/any", Constraint: "*", RequiredBy: "acme/app", File: "composer.json", Line: 6},
+		{Rule: "wildcard", Dependency: "acme/safe", Constraint: "*", RequiredBy: "acme/any", File: "composer.lock"},
+	}}}}
+	failOn, err := policy.Parse("constraint:wildcard")
+	assert.Nil(t, err)
+
+	// Unknown lines are left out of the location
+	messages := []string{}
+	for _, violation := range failOn.Evaluate(out) {
+		messages = append(messages, violation.Message)
+	}
+	assert.ElementsMatch(t, []string{
+		`wildcard: acme/app requires "*" (composer.json:6)`,
+		`wildcard: acme/any requires "*" (composer.lock)`,
+	}, messages)
+}
+
+func TestPolicyOrder(t *testing.T) {
+	root := t.TempDir()
+	writeManifest(t, root, "composer.json", `{"name": "acme/app", "require": {"acme/a": "*", "acme/b": "*", "acme/c": "*"}}`)
+	writeManifest(t, root, "composer.lock", `{"packages": [
+		{"name": "acme/a", "version": "1.0.0", "require": {"psr/log": "*", "psr/cache": "*", "psr/clock": "*"}},
+		{"name": "acme/b", "version": "1.0.0", "require": {"psr/log": "*"}},
+		{"name": "acme/c", "version": "1.0.0", "require": {"psr/log": "*"}}
+	]}`)
+	failOn, err := policy.Parse("constraint:wildcard")
+	assert.Nil(t, err)
+
+	// Violations of the same package come out in the same order on every run
+	expected := failOn.Evaluate(plugin.Start(root, uuid.UUID{}, nil))
+	assert.NotEmpty(t, expected)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, expected, failOn.Evaluate(plugin.Start(root, uuid.UUID{}, nil)))
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	plugin "github.com/CodeClarityCE/plugin-php-sbom/src"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/gitobject"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/options"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// testRepository is a git repository created for a test with the git command
+type testRepository struct {
+	t   *testing.T
+	dir string
+}
+
+func newTestRepository(t *testing.T) *testRepository {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repository := &testRepository{t: t, dir: t.TempDir()}
+	repository.git("init", "-q", "-b", "main")
+	return repository
+}
+
+func (r *testRepository) git(args ...string) string {
+	return r.gitAs("test", args...)
+}
+
+func (r *testRepository) gitAs(author string, args ...string) string {
+	command := exec.Command("git", args...)
+	command.Dir = r.dir
+	command.Env = append(os.Environ(), "GIT_AUTHOR_NAME="+author, "GIT_AUTHOR_EMAIL="+author+"@example.com", "GIT_COMMITTER_NAME="+author, "GIT_COMMITTER_EMAIL="+author+"@example.com")
+	out, err := command.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func (r *testRepository) write(path string, content string) {
+	target := filepath.Join(r.dir, path)
+	assert.Nil(r.t, os.MkdirAll(filepath.Dir(target), 0o755))
+	assert.Nil(r.t, os.WriteFile(target, []byte(content), 0o644))
+}
+
+func readFixture(t *testing.T, path string) string {
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	return string(data)
+}
+
+// gitRepository creates a repository with two commits of the hygiene fixture
+// in app/, the second one adding a package, and returns its directory
+func gitRepository(t *testing.T, pack bool) string {
+	repository := newTestRepository(t)
+	lock := readFixture(t, "./hygiene/composer.lock")
+	repository.write("app/composer.json", readFixture(t, "./hygiene/composer.json"))
+	repository.write("app/composer.lock", lock)
+	repository.git("add", "-A")
+	repository.git("commit", "-q", "-m", "Initial commit")
+	repository.git("tag", "-a", "v1.0", "-m", "First release")
+
+	repository.write("app/composer.lock", strings.Replace(lock, `"packages-dev": [`, `"packages-dev": [
+        {"name": "acme/extra", "version": "1.0.0"},`, 1))
+	repository.git("commit", "-q", "-am", "Add acme/extra")
+
+	if pack {
+		repository.git("gc", "-q", "--aggressive")
+		repository.git("pack-refs", "--all")
+	}
+	// A dirty checkout must not change the analysis of a revision
+	repository.write("app/composer.lock", "{}")
+	return repository.dir
+}
+
+func TestGitObjects(t *testing.T) {
+	for _, pack := range []bool{false, true} {
+		dir := gitRepository(t, pack)
+		repository, err := gitobject.Open(filepath.Join(dir, "app"))
+		assert.Nil(t, err)
+
+		head, err := repository.ResolveRevision("HEAD")
+		assert.Nil(t, err)
+		main, err := repository.ResolveRevision("main")
+		assert.Nil(t, err)
+		assert.Equal(t, head, main)
+
+		commit, err := repository.Commit(head)
+		assert.Nil(t, err)
+		assert.Equal(t, "Add acme/extra", commit.Summary())
+		assert.Len(t, commit.Parents, 1)
+
+		// Annotated tags, ancestry and abbreviated names
+		tagged, err := repository.ResolveRevision("v1.0")
+		assert.Nil(t, err)
+		parent, err := repository.ResolveRevision("main~1")
+		assert.Nil(t, err)
+		assert.Equal(t, tagged, parent)
+		abbreviated, err := repository.ResolveRevision(tagged.String()[:8])
+		assert.Nil(t, err)
+		assert.Equal(t, tagged, abbreviated)
+
+		data, err := repository.File(head, "app/composer.lock")
+		assert.Nil(t, err)
+		assert.Contains(t, string(data), "acme/extra")
+
+		_, err = repository.File(head, "app/missing.json")
+		assert.ErrorIs(t, err, gitobject.ErrNotFound)
+		_, err = repository.ResolveRevision("unknown-branch")
+		assert.ErrorIs(t, err, gitobject.ErrNotFound)
+	}
+}
+
+// writePack writes a pack and its index holding raw packed objects, sorted
+// by hash, into a new repository and returns its directory
+func writePack(t *testing.T, hashes []gitobject.Hash, objects [][]byte) string {
+	repository := newTestRepository(t)
+	pack := []byte("PACK\x00\x00\x00\x02")
+	pack = binary.BigEndian.AppendUint32(pack, uint32(len(objects)))
+	offsets := []uint32{}
+	for _, raw := range objects {
+		offsets = append(offsets, uint32(len(pack)))
+		pack = append(pack, raw...)
+	}
+	pack = append(pack, make([]byte, gitobject.HASH_SIZE)...)
+
+	index := []byte{0xff, 't', 'O', 'c', 0, 0, 0, 2}
+	for i := 0; i < 256; i++ {
+		count := 0
+		for _, h := range hashes {
+			if int(h[0]) <= i {
+				count++
+			}
+		}
+		index = binary.BigEndian.AppendUint32(index, uint32(count))
+	}
+	for _, h := range hashes {
+		index = append(index, h[:]...)
+	}
+	index = append(index, make([]byte, 4*len(hashes))...)
+	for _, offset := range offsets {
+		index = binary.BigEndian.AppendUint32(index, offset)
+	}
+	index = append(index, make([]byte, 2*gitobject.HASH_SIZE)...)
+
+	packDir := filepath.Join(repository.dir, ".git", "objects", "pack")
+	assert.Nil(t, os.WriteFile(filepath.Join(packDir, "pack-test.pack"), pack, 0o644))
+	assert.Nil(t, os.WriteFile(filepath.Join(packDir, "pack-test.idx"), index, 0o644))
+	return repository.dir
+}
+
+// packedObject encodes the header of a packed object followed by extra bytes
+// and the compressed content
+func packedObject(typ byte, size uint64, extra []byte, content []byte) []byte {
+	b := typ<<4 | byte(size&0x0f)
+	size >>= 4
+	raw := []byte{}
+	for size > 0 {
+		raw = append(raw, b|0x80)
+		b = byte(size & 0x7f)
+		size >>= 7
+	}
+	raw = append(raw, b)
+	raw = append(raw, extra...)
+	var compressed bytes.Buffer
+	writer := zlib.NewWriter(&compressed)
+	writer.Write(content)
+	writer.Close()
+	return append(raw, compressed.Bytes()...)
+}
+
+func TestGitObjectsOversizedPack(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	base := gitobject.Hash{0x01}
+	oversized := gitobject.Hash{0x02}
+	delta := gitobject.Hash{0x03}
+	instructions := binary.AppendUvarint(binary.AppendUvarint(nil, 3), 1<<40)
+	instructions = append(instructions, 1, 'x')
+	dir := writePack(t, []gitobject.Hash{base, oversized, delta}, [][]byte{
+		packedObject(3, 3, nil, []byte("abc")),
+		// Sizes are read from the pack, a corrupt one must not be allocated
+		packedObject(3, 1<<40, nil, []byte("abc")),
+		packedObject(7, uint64(len(instructions)), base[:], instructions),
+	})
+
+	repository, err := gitobject.Open(dir)
+	assert.Nil(t, err)
+	_, data, err := repository.Object(base)
+	assert.Nil(t, err)
+	assert.Equal(t, "abc", string(data))
+	_, _, err = repository.Object(oversized)
+	assert.ErrorContains(t, err, "out of range")
+	_, _, err = repository.Object(delta)
+	assert.ErrorContains(t, err, "out of range")
+}
+
+func TestStartAtRevision(t *testing.T) {
+	dir := gitRepository(t, true)
+	project := filepath.Join(dir, "app")
+
+	out := plugin.StartAtRevision(context.Background(), project, "main", uuid.UUID{}, nil, options.Default())
+	assert.Contains(t, out.WorkSpaces["."].Dependencies, "acme/extra")
+	assert.Equal(t, "main", out.AnalysisInfo.Extra.Revision)
+	assert.Len(t, out.AnalysisInfo.Extra.Commit, 40)
+	assert.Equal(t, project, out.AnalysisInfo.WorkingDirectory)
+
+	out = plugin.StartAtRevision(context.Background(), project, "v1.0", uuid.UUID{}, nil, options.Default())
+	assert.NotContains(t, out.WorkSpaces["."].Dependencies, "acme/extra")
+	assert.Contains(t, out.WorkSpaces["."].Dependencies, "acme/any")
+
+	out = plugin.StartAtRevision(context.Background(), project, "0000000000000000000000000000000000000000", uuid.UUID{}, nil, options.Default())
+	assert.Equal(t, "failure", string(out.AnalysisInfo.Status))
+}
+
+func TestStartAtRevisionRejectsParentEntries(t *testing.T) {
+	repository := newTestRepository(t)
+	repository.write("composer.json", `{"name": "acme/app"}`)
+	repository.git("add", "-A")
+	repository.git("commit", "-q", "-m", "Initial commit")
+
+	// A tree holding a .. directory, which git itself refuses to create
+	inner := repository.git("rev-parse", "HEAD^{tree}")
+	hash, err := gitobject.ParseHash(inner)
+	assert.Nil(t, err)
+	command := exec.Command("git", "hash-object", "-w", "-t", "tree", "--literally", "--stdin")
+	command.Dir = repository.dir
+	command.Stdin = strings.NewReader("40000 ..\x00" + string(hash[:]))
+	out, err := command.Output()
+	assert.Nil(t, err)
+	commit := repository.git("commit-tree", strings.TrimSpace(string(out)), "-m", "Escape")
+	repository.git("update-ref", "refs/heads/escape", commit)
+
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	result := plugin.StartAtRevision(context.Background(), repository.dir, "escape", uuid.UUID{}, nil, options.Default())
+	assert.Equal(t, "failure", string(result.AnalysisInfo.Status))
+	assert.NoFileExists(t, filepath.Join(tmp, "composer.json"))
+}
+
+func TestStartAtRevisionIgnoreFilesAndMonorepoBuilder(t *testing.T) {
+	repository := newTestRepository(t)
+	repository.write("composer.json", `{"name": "acme/framework"}`)
+	repository.write("monorepo-builder.yml", "parameters:\n    package_directories:\n        - packages\n")
+	repository.write(".codeclarityignore", "tests/fixtures/\n")
+	repository.write("packages/cache/composer.json", `{"name": "acme/cache", "require": {"psr/log": "^3.0"}}`)
+	repository.write("packages/cache/composer.lock", `{"packages": [{"name": "psr/log", "version": "3.0.0"}]}`)
+	repository.write("tests/fixtures/project/composer.json", `{"name": "acme/fixture"}`)
+	repository.git("add", "-A")
+	repository.git("commit", "-q", "-m", "Initial commit")
+
+	out := plugin.StartAtRevision(context.Background(), repository.dir, "main", uuid.UUID{}, nil, options.Default())
+	assert.Contains(t, out.WorkSpaces, "packages/cache/composer.json")
+	assert.Contains(t, out.WorkSpaces["packages/cache/composer.json"].Dependencies, "psr/log")
+	// The ignored fixture is not even an unrelated manifest
+	assert.Empty(t, out.AnalysisInfo.Extra.UnrelatedManifests)
+
+	// Every path points to the repository, not to the removed snapshot
+	assert.Equal(t, filepath.Join(repository.dir, "packages", "cache", "composer.lock"), out.AnalysisInfo.Paths.WorkSpaceLockFile["packages/cache/composer.json"])
+	data, err := json.Marshal(out)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(data), "php-sbom-revision-")
+}
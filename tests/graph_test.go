@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+
+	plugin "github.com/CodeClarityCE/plugin-php-sbom/src"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDependencyGraphReachability(t *testing.T) {
+	out := plugin.Start("./graph", uuid.UUID{}, nil)
+	deps := out.WorkSpaces["."].Dependencies
+
+	// Required by the root in require
+	appLib := deps["acme/app-lib"]["1.2.0"]
+	assert.True(t, appLib.Direct)
+	assert.False(t, appLib.Transitive)
+	assert.True(t, appLib.Prod)
+	assert.False(t, appLib.DevOnly)
+	assert.Equal(t, 1, appLib.Depth)
+
+	// Locked in packages, required directly in require-dev and transitively in prod
+	shared := deps["acme/shared"]["1.4.1"]
+	assert.True(t, shared.Direct)
+	assert.True(t, shared.Transitive)
+	assert.True(t, shared.Prod)
+	assert.True(t, shared.Dev)
+	assert.Equal(t, 1, shared.Depth)
+
+	leaf := deps["acme/leaf"]["3.0.0"]
+	assert.False(t, leaf.Direct)
+	assert.True(t, leaf.Transitive)
+	assert.True(t, leaf.Prod)
+	assert.Equal(t, 2, leaf.Depth)
+
+	// Locked in packages but only reachable through require-dev
+	devOnly := deps["acme/dev-only-in-prod-section"]["0.9.0"]
+	assert.False(t, devOnly.Direct)
+	assert.True(t, devOnly.Transitive)
+	assert.False(t, devOnly.Prod)
+	assert.True(t, devOnly.DevOnly)
+	assert.Equal(t, 2, devOnly.Depth)
+
+	tool := deps["acme/test-tool"]["2.1.0"]
+	assert.True(t, tool.Direct)
+	assert.True(t, tool.DevOnly)
+}
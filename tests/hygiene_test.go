@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+
+	plugin "github.com/CodeClarityCE/plugin-php-sbom/src"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/options"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConstraintHygiene(t *testing.T) {
+	out := plugin.Start("./hygiene", uuid.UUID{}, nil)
+	findings := out.WorkSpaces["."].ConstraintFindings
+
+	find := func(requiredBy, dependency, rule string) *types.ConstraintFinding {
+		for i, finding := range findings {
+			if finding.RequiredBy == requiredBy && finding.Dependency == dependency && finding.Rule == rule {
+				return &findings[i]
+			}
+		}
+		return nil
+	}
+
+	wildcard := find("acme/hygiene", "acme/any", "wildcard")
+	if assert.NotNil(t, wildcard) {
+		assert.Equal(t, "high", wildcard.Severity)
+		assert.Equal(t, "composer.json", wildcard.File)
+		assert.Equal(t, 6, wildcard.Line)
+		assert.Equal(t, 9, wildcard.Column)
+	}
+	assert.NotNil(t, find("acme/hygiene", "acme/open", "unbounded"))
+	assert.NotNil(t, find("acme/hygiene", "acme/branch", "dev-branch"))
+	assert.NotNil(t, find("acme/hygiene", "acme/overlap", "overlapping-ranges"))
+
+	// Dev requirements are reported one level lower
+	tool := find("acme/hygiene", "acme/tool", "unbounded")
+	if assert.NotNil(t, tool) {
+		assert.True(t, tool.Dev)
+		assert.Equal(t, "medium", tool.Severity)
+	}
+
+	// Lock package requirements are located in composer.lock
+	locked := find("acme/any", "acme/safe", "wildcard")
+	if assert.NotNil(t, locked) {
+		assert.Equal(t, "composer.lock", locked.File)
+		assert.Equal(t, 9, locked.Line)
+	}
+
+	// Platform requirements and disjoint alternatives are fine
+	for _, finding := range findings {
+		assert.NotEqual(t, "php", finding.Dependency)
+		assert.NotEqual(t, "ext-json", finding.Dependency)
+	}
+	assert.Nil(t, find("acme/hygiene", "acme/safe", "overlapping-ranges"))
+	assert.Nil(t, find("acme/safe", "acme/open", "unbounded"))
+}
+
+func TestConstraintHygieneComposerFilename(t *testing.T) {
+	root := t.TempDir()
+	writeManifest(t, root, "app.json", readFixture(t, "./hygiene/composer.json"))
+	writeManifest(t, root, "app.lock", readFixture(t, "./hygiene/composer.lock"))
+	opts := options.Default()
+	opts.ComposerFilename = "app.json"
+
+	// The findings of the manifest come first, then the ones of the lock file
+	findings := plugin.StartWithOptions(root, uuid.UUID{}, nil, opts).WorkSpaces["."].ConstraintFindings
+	files := []string{}
+	for _, finding := range findings {
+		if len(files) == 0 || files[len(files)-1] != finding.File {
+			files = append(files, finding.File)
+		}
+	}
+	assert.Equal(t, []string{"app.json", "app.lock"}, files)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	plugin "github.com/CodeClarityCE/plugin-php-sbom/src"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/options"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/project_finder"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// workspaceSources returns the source of each workspace by name
+func workspaceSources(projectInfo *project_finder.ProjectInfo) map[string]project_finder.WorkspaceSource {
+	sources := map[string]project_finder.WorkspaceSource{}
+	for _, ws := range projectInfo.Workspaces {
+		sources[ws.Name] = ws.Source
+	}
+	return sources
+}
+
+func TestMonorepoPathRepositories(t *testing.T) {
+	root := t.TempDir()
+	writeManifest(t, root, "composer.json", `{
+		"name": "acme/monorepo",
+		"require": {"acme/api": "*", "acme/web": "*"},
+		"require-dev": {"acme/testing": "*"},
+		"repositories": [
+			{"type": "path", "url": "packages/*"},
+			{"type": "path", "url": "{apps,tools}/testing"},
+			{"type": "composer", "url": "https://packagist.example.com"}
+		],
+		"extra": {"merge-plugin": {"include": ["modules/*/composer.json"]}}
+	}`)
+	writeManifest(t, root, "composer.lock", `{
+		"packages": [
+			{"name": "acme/api", "version": "dev-main", "dist": {"type": "path", "url": "packages/api"}},
+			{"name": "acme/web", "version": "1.2.0", "dist": {"type": "path", "url": "/ci/build/packages/web"}},
+			{"name": "acme/lib", "version": "1.0.0", "dist": {"type": "zip", "url": "https://example.com/lib.zip"}}
+		],
+		"packages-dev": [
+			{"name": "acme/testing", "version": "dev-main", "dist": {"type": "path", "url": "./tools/testing"}}
+		]
+	}`)
+	writeManifest(t, filepath.Join(root, "packages", "api"), "composer.json", `{"name": "acme/api"}`)
+	writeManifest(t, filepath.Join(root, "packages", "web"), "composer.json", `{"name": "acme/web"}`)
+	writeManifest(t, filepath.Join(root, "tools", "testing"), "composer.json", `{"name": "acme/testing"}`)
+	writeManifest(t, filepath.Join(root, "modules", "billing"), "composer.json", `{"name": "acme/billing"}`)
+	writeManifest(t, filepath.Join(root, "docs", "example"), "composer.json", `{"name": "acme/example"}`)
+
+	projectInfo, err := project_finder.FindPHPProjects(context.Background(), root, options.Default())
+	assert.Nil(t, err)
+	assert.True(t, projectInfo.IsMonorepo)
+	assert.Equal(t, map[string]project_finder.WorkspaceSource{
+		"acme/api":     project_finder.SOURCE_PATH_REPOSITORY,
+		"acme/web":     project_finder.SOURCE_PATH_REPOSITORY,
+		"acme/testing": project_finder.SOURCE_PATH_REPOSITORY,
+		"acme/billing": project_finder.SOURCE_MERGE_PLUGIN,
+	}, workspaceSources(projectInfo))
+	assert.Len(t, projectInfo.UnrelatedManifests, 1)
+	assert.Equal(t, "acme/example", projectInfo.UnrelatedManifests[0].Name)
+
+	for _, ws := range projectInfo.Workspaces {
+		switch ws.Name {
+		case "acme/api", "acme/web":
+			// The web entry is matched by name, its path is from another machine
+			assert.NotNil(t, ws.LockEntry, ws.Name)
+			assert.Equal(t, ws.Name, ws.LockEntry.Name)
+			assert.False(t, ws.LockedAsDev)
+		case "acme/testing":
+			assert.NotNil(t, ws.LockEntry)
+			assert.True(t, ws.LockedAsDev)
+		default:
+			assert.Nil(t, ws.LockEntry, ws.Name)
+		}
+	}
+
+	out := plugin.Start(root, uuid.UUID{}, nil)
+	assert.True(t, out.AnalysisInfo.Workspaces.WorkSpacesUsed)
+	assert.Len(t, out.WorkSpaces, 5)
+	assert.NotContains(t, out.WorkSpaces, filepath.Join("docs", "example", "composer.json"))
+	assert.Equal(t, []string{filepath.Join("docs", "example", "composer.json")}, out.AnalysisInfo.Extra.UnrelatedManifests)
+	members := map[string]types.Workspace{}
+	for _, member := range out.AnalysisInfo.Workspaces.Members {
+		members[member.ID] = member
+	}
+	tools := members["tools/testing/composer.json"]
+	assert.Equal(t, "path-repository", tools.Source)
+	assert.Equal(t, "dev-main", tools.LockedVersion)
+	assert.True(t, tools.LockedAsDev)
+	assert.Equal(t, "merge-plugin", members["modules/billing/composer.json"].Source)
+	assert.Empty(t, members["modules/billing/composer.json"].LockedVersion)
+}
+
+func TestMonorepoBuilder(t *testing.T) {
+	for name, config := range map[string]string{
+		"monorepo-builder.php": `<?php
+use Symplify\MonorepoBuilder\Config\MBConfig;
+
+return static function (MBConfig $mbConfig): void {
+    $mbConfig->packageDirectories([
+        __DIR__ . '/src/Components',
+        __DIR__ . "/bundles",
+    ]);
+};`,
+		"monorepo-builder.yml": `parameters:
+    package_directories:
+        - 'src/Components'
+        - bundles
+    data_to_append: []`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			root := t.TempDir()
+			writeManifest(t, root, "composer.json", `{"name": "acme/framework"}`)
+			writeManifest(t, root, name, config)
+			writeManifest(t, filepath.Join(root, "src", "Components", "Cache"), "composer.json", `{"name": "acme/cache"}`)
+			writeManifest(t, filepath.Join(root, "bundles", "admin", "v2"), "composer.json", `{"name": "acme/admin"}`)
+			writeManifest(t, filepath.Join(root, "packages", "stray"), "composer.json", `{"name": "acme/stray"}`)
+
+			projectInfo, err := project_finder.FindPHPProjects(context.Background(), root, options.Default())
+			assert.Nil(t, err)
+			assert.Equal(t, map[string]project_finder.WorkspaceSource{
+				"acme/cache": project_finder.SOURCE_MONOREPO_BUILDER,
+				"acme/admin": project_finder.SOURCE_MONOREPO_BUILDER,
+			}, workspaceSources(projectInfo))
+			assert.Len(t, projectInfo.UnrelatedManifests, 1)
+		})
+	}
+}
+
+func TestStrayManifestsAreNotWorkspaces(t *testing.T) {
+	root := t.TempDir()
+	writeManifest(t, root, "composer.json", `{"name": "acme/app"}`)
+	writeManifest(t, filepath.Join(root, "tests", "fixtures", "project"), "composer.json", `{"name": "acme/fixture"}`)
+
+	projectInfo, err := project_finder.FindPHPProjects(context.Background(), root, options.Default())
+	assert.Nil(t, err)
+	assert.False(t, projectInfo.IsMonorepo)
+	assert.Empty(t, projectInfo.Workspaces)
+	assert.Len(t, projectInfo.UnrelatedManifests, 1)
+
+	out := plugin.Start(root, uuid.UUID{}, nil)
+	assert.False(t, out.AnalysisInfo.Workspaces.WorkSpacesUsed)
+	assert.Len(t, out.WorkSpaces, 1)
+	assert.Len(t, out.AnalysisInfo.Workspaces.Members, 1)
+}
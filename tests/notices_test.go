@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	plugin "github.com/CodeClarityCE/plugin-php-sbom/src"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/notices"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThirdPartyNotices(t *testing.T) {
+	out := plugin.Start("./test1", uuid.UUID{}, nil)
+
+	// No vendor directory in the fixture: the SPDX text is used
+	collected := notices.Collect(out, "")
+	assert.NotEmpty(t, collected.Groups)
+	for _, group := range collected.Groups {
+		for _, pkg := range group.Packages {
+			assert.NotEqual(t, "phpunit/phpunit", pkg.Name, "Dev packages should not be listed")
+		}
+	}
+
+	markdown, err := notices.Generate(out, "", notices.FORMAT_MARKDOWN)
+	assert.NoError(t, err)
+	assert.Contains(t, markdown, "## MIT")
+	assert.Contains(t, markdown, "### cakephp/cakephp")
+	assert.Contains(t, markdown, "Permission is hereby granted")
+
+	// A vendored package ships its own license text and copyright
+	vendorDir := t.TempDir()
+	packageDir := filepath.Join(vendorDir, "ramsey", "uuid")
+	assert.NoError(t, os.MkdirAll(packageDir, 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(packageDir, "LICENSE"), []byte("Copyright (c) 2012-2023 Ben Ramsey <ben@benramsey.com>\n\nPermission is hereby granted..."), 0644))
+
+	text, err := notices.Generate(out, vendorDir, notices.FORMAT_TEXT)
+	assert.NoError(t, err)
+	assert.Contains(t, text, "Copyright (c) 2012-2023 Ben Ramsey <ben@benramsey.com>")
+
+	html, err := notices.Generate(out, vendorDir, notices.FORMAT_HTML)
+	assert.NoError(t, err)
+	assert.Contains(t, html, "Copyright (c) 2012-2023 Ben Ramsey &lt;ben@benramsey.com&gt;")
+
+	_, err = notices.Generate(out, "", notices.Format("pdf"))
+	assert.Error(t, err)
+}
+
+func TestStandardLicenseText(t *testing.T) {
+	for license, title := range map[string]string{
+		"MIT":               "MIT License",
+		"Apache-2.0":        "Apache License",
+		"GPL-2.0-only":      "GNU GENERAL PUBLIC LICENSE",
+		"GPL-2.0-or-later":  "Version 2, June 1991",
+		"GPL-2.0+":          "Version 2, June 1991",
+		"gpl-3.0-only":      "Version 3, 29 June 2007",
+		"GPL-3.0":           "GNU GENERAL PUBLIC LICENSE",
+		"LGPL-2.1-or-later": "GNU LESSER GENERAL PUBLIC LICENSE",
+		"LGPL-3.0-only":     "GNU LESSER GENERAL PUBLIC LICENSE",
+		"MPL-2.0":           "Mozilla Public License Version 2.0",
+	} {
+		assert.Contains(t, notices.StandardLicenseText(license), title, license)
+	}
+	assert.Empty(t, notices.StandardLicenseText("proprietary"))
+	assert.Empty(t, notices.StandardLicenseText("(MIT OR GPL-3.0-only)"))
+}
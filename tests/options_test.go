@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	plugin "github.com/CodeClarityCE/plugin-php-sbom/src"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/export"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/options"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/project_finder"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOptions(t *testing.T) {
+	opts, err := options.Parse(map[string]any{"project": "acme", "branch": "main"})
+	assert.Nil(t, err)
+	assert.Equal(t, options.Default(), opts)
+
+	opts, err = options.Parse(map[string]any{
+		"include_dev":       false,
+		"exclude_paths":     []any{"tests/fixtures", "legacy"},
+		"scan_phars":        "false",
+		"output_formats":    []any{"json", "cdx", "spdx", "cyclonedx"},
+		"policy":            "severity:high,php-conflict",
+		"max_depth":         float64(2),
+		"follow_symlinks":   true,
+		"root_manifest":     "./app/composer.json",
+		"composer_filename": "composer-ci.json",
+	})
+	assert.Nil(t, err)
+	assert.False(t, opts.IncludeDev)
+	assert.Equal(t, []string{"tests/fixtures", "legacy"}, opts.ExcludePaths)
+	assert.False(t, opts.ScanPHARs)
+	assert.Equal(t, []export.Format{export.FORMAT_JSON, export.FORMAT_CYCLONEDX, export.FORMAT_SPDX}, opts.OutputFormats)
+	assert.Len(t, opts.Policy.Rules, 2)
+	assert.Equal(t, 2, opts.MaxDepth)
+	assert.True(t, opts.FollowSymlinks)
+	assert.Equal(t, "app/composer.json", opts.RootManifest)
+	assert.Equal(t, "composer-ci.lock", opts.LockFilename())
+
+	assert.True(t, opts.IsExcluded("tests/fixtures"))
+	assert.True(t, opts.IsExcluded("modules/legacy"))
+	assert.False(t, opts.IsExcluded("tests/unit"))
+
+	for _, config := range []map[string]any{
+		{"include_dev": "yes"},
+		{"exclude_paths": "["},
+		{"output_formats": []any{"xml"}},
+		{"policy": "severity:critical"},
+		{"max_depth": 1.5},
+		{"max_depth": float64(-2)},
+		{"root_manifest": "../composer.json"},
+		{"composer_filename": "composer.yaml"},
+	} {
+		_, err := options.Parse(config)
+		assert.NotNil(t, err, "%v", config)
+	}
+}
+
+func writeManifest(t *testing.T, dir string, name string, content string) {
+	assert.Nil(t, os.MkdirAll(dir, 0o755))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+}
+
+func TestFindPHPProjectsWithOptions(t *testing.T) {
+	root := t.TempDir()
+	writeManifest(t, root, "composer.json", `{"name": "acme/root"}`)
+	writeManifest(t, filepath.Join(root, "app"), "composer.json", `{"name": "acme/app"}`)
+	writeManifest(t, filepath.Join(root, "app"), "composer.lock", `{"packages": [{"name": "acme/lib", "version": "1.0.0"}]}`)
+	writeManifest(t, filepath.Join(root, "packages", "deep", "lib"), "composer.json", `{"name": "acme/deep"}`)
+	writeManifest(t, filepath.Join(root, "tests", "fixtures"), "composer.json", `{"name": "acme/fixture"}`)
+	writeManifest(t, filepath.Join(root, "ci"), "composer-ci.json", `{"name": "acme/ci"}`)
+	writeManifest(t, filepath.Join(root, "ci"), "tool.phar", "<?php __HALT_COMPILER();")
+	outside := t.TempDir()
+	writeManifest(t, outside, "composer.json", `{"name": "acme/linked"}`)
+	assert.Nil(t, os.Symlink(outside, filepath.Join(root, "linked")))
+	// A symlink cycle must not hang the search
+	assert.Nil(t, os.Symlink(root, filepath.Join(root, "app", "loop")))
+
+	// The manifests found other than the root one, workspaces or not
+	manifestNames := func(projectInfo *project_finder.ProjectInfo) []string {
+		names := []string{}
+		for _, ws := range append(projectInfo.Workspaces, projectInfo.UnrelatedManifests...) {
+			names = append(names, ws.Name)
+		}
+		return names
+	}
+
+	projectInfo, err := project_finder.FindPHPProjects(context.Background(), root, options.Default())
+	assert.Nil(t, err)
+	assert.Equal(t, "acme/root", projectInfo.Name)
+	assert.ElementsMatch(t, []string{"acme/app", "acme/deep", "acme/fixture"}, manifestNames(projectInfo))
+	assert.Len(t, projectInfo.PHARFiles, 1)
+
+	opts := options.Default()
+	opts.ExcludePaths = []string{"tests/*"}
+	opts.MaxDepth = 1
+	opts.ScanPHARs = false
+	opts.FollowSymlinks = true
+	opts.RootManifest = "app/composer.json"
+	projectInfo, err = project_finder.FindPHPProjects(context.Background(), root, opts)
+	assert.Nil(t, err)
+	assert.Equal(t, "acme/app", projectInfo.Name)
+	assert.NotNil(t, projectInfo.ComposerLock)
+	assert.ElementsMatch(t, []string{"acme/root", "acme/linked"}, manifestNames(projectInfo))
+	assert.Empty(t, projectInfo.PHARFiles)
+
+	opts = options.Default()
+	opts.ComposerFilename = "composer-ci.json"
+	projectInfo, err = project_finder.FindPHPProjects(context.Background(), root, opts)
+	assert.Nil(t, err)
+	assert.Equal(t, "acme/ci", projectInfo.Name)
+
+	opts = options.Default()
+	opts.RootManifest = "missing/composer.json"
+	_, err = project_finder.FindPHPProjects(context.Background(), root, opts)
+	assert.NotNil(t, err)
+}
+
+func TestStartWithOptions(t *testing.T) {
+	opts := options.Default()
+	opts.IncludeDev = false
+	policy, err := options.Parse(map[string]any{"policy": "php-conflict"})
+	assert.Nil(t, err)
+	opts.Policy = policy.Policy
+
+	out := plugin.StartWithOptions("./test1", uuid.UUID{}, nil, opts)
+	assert.Equal(t, "success", string(out.AnalysisInfo.Status))
+	ws := out.WorkSpaces["."]
+	assert.Empty(t, ws.Start.DevDependencies)
+	for name, versions := range ws.Dependencies {
+		for _, info := range versions {
+			assert.True(t, info.Prod, name)
+		}
+	}
+	for _, finding := range ws.ConstraintFindings {
+		assert.False(t, finding.Dev, finding.Dependency)
+	}
+	assert.Zero(t, out.AnalysisInfo.Extra.Statistics.DevPackages)
+	assert.Less(t, out.AnalysisInfo.Extra.Statistics.TotalPackages, plugin.Start("./test1", uuid.UUID{}, nil).AnalysisInfo.Extra.Statistics.TotalPackages)
+
+	conflict := plugin.StartWithOptions("./php_conflict", uuid.UUID{}, nil, opts)
+	assert.NotEmpty(t, conflict.AnalysisInfo.Extra.PolicyViolations)
+	assert.Equal(t, "php-conflict", conflict.AnalysisInfo.Extra.PolicyViolations[0].Rule)
+}
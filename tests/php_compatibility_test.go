@@ -0,0 +1,78 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	plugin "github.com/CodeClarityCE/plugin-php-sbom/src"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/diagnostics"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPHPCompatibility(t *testing.T) {
+	out := plugin.Start("./test1", uuid.UUID{}, nil)
+
+	for _, version := range out.WorkSpaces["."].Dependencies["bacon/bacon-qr-code"] {
+		assert.Equal(t, "^8.1", version.PHPVersion)
+	}
+
+	compatibility := out.AnalysisInfo.Extra.PHPCompatibility
+	assert.NotNil(t, compatibility)
+	assert.Equal(t, ">=8.2", compatibility.RootConstraint)
+	assert.Equal(t, "8.2", compatibility.PlatformVersion)
+	assert.True(t, compatibility.Satisfiable)
+	assert.Equal(t, ">=8.2.0 <8.5.0", compatibility.EffectiveRange)
+}
+
+func TestPHPCompatibilityConflicts(t *testing.T) {
+	out := plugin.Start("./php_conflict", uuid.UUID{}, nil)
+
+	compatibility := out.AnalysisInfo.Extra.PHPCompatibility
+	assert.NotNil(t, compatibility)
+	assert.False(t, compatibility.Satisfiable)
+
+	conflicts := make(map[string][]string)
+	for _, conflict := range compatibility.Conflicts {
+		conflicts[conflict.Package] = append(conflicts[conflict.Package], conflict.ConflictsWith)
+	}
+	assert.Equal(t, []string{"platform", "root"}, conflicts["acme/legacy"])
+	assert.Equal(t, []string{"platform"}, conflicts["acme/modern"])
+}
+
+func TestPHPCompatibilityInvalidConstraints(t *testing.T) {
+	root := t.TempDir()
+	writeManifest(t, root, "composer.json", `{"name": "acme/app", "require": {"php": ">=8.1", "acme/odd": "^1.0"}}`)
+	writeManifest(t, root, "composer.lock", `{"packages": [{"name": "acme/odd", "version": "1.0.0", "require": {"php": ">=eight"}}]}`)
+
+	out := plugin.Start(root, uuid.UUID{}, nil)
+	assert.Equal(t, types.SUCCESS_WITH_WARNINGS, out.AnalysisInfo.Status)
+	assert.True(t, out.AnalysisInfo.Extra.PHPCompatibility.Satisfiable)
+	if assert.Len(t, out.AnalysisInfo.Warnings, 1) {
+		warning := out.AnalysisInfo.Warnings[0]
+		assert.Equal(t, string(diagnostics.WARNING_INVALID_PHP_CONSTRAINT), warning.Type)
+		assert.Equal(t, "composer.lock", warning.File)
+		assert.Contains(t, warning.Description, `Invalid PHP constraint ">=eight" of acme/odd@1.0.0 ignored`)
+	}
+}
+
+func TestPHPCompatibilityWarningsOrder(t *testing.T) {
+	root := t.TempDir()
+	writeManifest(t, root, "composer.json", `{"name": "acme/app", "require": {"acme/a": "^1.0", "acme/b": "^1.0", "acme/c": "^1.0"}}`)
+	writeManifest(t, root, "composer.lock", `{"packages": [
+		{"name": "acme/c", "version": "1.0.0", "require": {"php": "^8.1 ||"}},
+		{"name": "acme/a", "version": "1.0.0", "require": {"php": ">=eight"}},
+		{"name": "acme/b", "version": "1.0.0", "require": {"php": "~>8"}}
+	]}`)
+
+	for i := 0; i < 10; i++ {
+		packages := []string{}
+		for _, warning := range plugin.Start(root, uuid.UUID{}, nil).AnalysisInfo.Warnings {
+			_, rest, _ := strings.Cut(warning.Description, " of ")
+			pkg, _, _ := strings.Cut(rest, " ")
+			packages = append(packages, pkg)
+		}
+		assert.Equal(t, []string{"acme/a@1.0.0", "acme/b@1.0.0", "acme/c@1.0.0"}, packages)
+	}
+}
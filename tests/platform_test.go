@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+
+	plugin "github.com/CodeClarityCE/plugin-php-sbom/src"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlatformRequirements(t *testing.T) {
+	out := plugin.Start("./test1", uuid.UUID{}, nil)
+	defaultWs := out.WorkSpaces["."]
+
+	// Platform requirements are not listed as package dependencies
+	for _, dep := range defaultWs.Start.Dependencies {
+		assert.NotEqual(t, "php", dep.Name)
+		assert.NotContains(t, dep.Name, "ext-")
+	}
+	for _, version := range defaultWs.Dependencies["bacon/bacon-qr-code"] {
+		assert.NotContains(t, version.Dependencies, "php")
+		assert.NotContains(t, version.Dependencies, "ext-iconv")
+		assert.Contains(t, version.Requires, "ext-iconv")
+	}
+
+	php, exists := defaultWs.Platform["php"]
+	assert.True(t, exists)
+	assert.Equal(t, "php", php.Type)
+	assert.Equal(t, ">=8.2", php.LockConstraint)
+	assert.Equal(t, "8.2", php.Override)
+	assert.Greater(t, len(php.Constraints), 1, "php should aggregate the constraints of every package")
+
+	iconv, exists := defaultWs.Platform["ext-iconv"]
+	assert.True(t, exists)
+	assert.Equal(t, "extension", iconv.Type)
+	assert.True(t, iconv.Prod)
+	found := false
+	for _, constraint := range iconv.Constraints {
+		if constraint.RequiredBy == "bacon/bacon-qr-code" {
+			found = true
+		}
+	}
+	assert.True(t, found)
+
+	assert.Contains(t, out.AnalysisInfo.Extra.RequiredExtensions, "ext-gnupg")
+	assert.Contains(t, out.AnalysisInfo.Extra.RequiredExtensions, "ext-iconv")
+	assert.Equal(t, "8.2", out.AnalysisInfo.Extra.PlatformOverrides["php"])
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	plugin "github.com/CodeClarityCE/plugin-php-sbom/src"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/export"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/options"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+	codeclarity "github.com/CodeClarityCE/utility-types/codeclarity_db"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeProjectsRepository writes a repository holding an API, an admin
+// monorepo with a package, a legacy application and a stray example
+func writeProjectsRepository(t *testing.T) string {
+	root := t.TempDir()
+	writeManifest(t, filepath.Join(root, "apps", "api"), "composer.json", `{"name": "acme/api", "require": {"acme/http": "^1.0"}}`)
+	writeManifest(t, filepath.Join(root, "apps", "api"), "composer.lock", `{"packages": [{"name": "acme/http", "version": "1.2.0"}]}`)
+	writeManifest(t, filepath.Join(root, "apps", "admin"), "composer.json", `{"name": "acme/admin", "repositories": [{"type": "path", "url": "packages/*"}]}`)
+	writeManifest(t, filepath.Join(root, "apps", "admin"), "composer.lock", `{"packages": []}`)
+	// A workspace of the admin, although it has its own lock
+	writeManifest(t, filepath.Join(root, "apps", "admin", "packages", "ui"), "composer.json", `{"name": "acme/ui"}`)
+	writeManifest(t, filepath.Join(root, "apps", "admin", "packages", "ui"), "composer.lock", `{"packages": []}`)
+	writeManifest(t, filepath.Join(root, "legacy"), "composer.json", `{"name": "acme/legacy", "require": {"acme/old": "^0.1"}}`)
+	writeManifest(t, filepath.Join(root, "legacy"), "composer.lock", "{\n  \"packages\": [,]\n}")
+	writeManifest(t, filepath.Join(root, "docs", "example"), "composer.json", `{"name": "acme/example"}`)
+	return root
+}
+
+func TestStartProjects(t *testing.T) {
+	root := writeProjectsRepository(t)
+	opts := options.Default()
+	opts.MultiProject = true
+
+	out := plugin.StartProjects(context.Background(), root, uuid.UUID{}, nil, opts)
+	assert.Equal(t, types.SUCCESS_WITH_WARNINGS, out.Index.Status)
+	assert.Empty(t, out.Index.Errors)
+	assert.Equal(t, []string{filepath.Join("docs", "example", "composer.json")}, out.Index.UnrelatedManifests)
+
+	ids := []string{}
+	for _, summary := range out.Index.Projects {
+		ids = append(ids, summary.ID)
+		assert.Contains(t, out.Projects, summary.ID)
+	}
+	assert.Equal(t, []string{"apps/admin", "apps/api", "legacy"}, ids)
+	assert.Len(t, out.Projects, 3)
+
+	api := out.Index.Projects[1]
+	assert.Equal(t, types.ProjectSummary{
+		ID:                  "apps/api",
+		Name:                "acme/api",
+		Framework:           "Generic PHP",
+		RelativePackageFile: filepath.Join("apps", "api", "composer.json"),
+		RelativeLockFile:    filepath.Join("apps", "api", "composer.lock"),
+		Status:              codeclarity.SUCCESS,
+		Workspaces:          1,
+		Dependencies:        1,
+	}, api)
+	assert.Empty(t, out.Projects["apps/api"].AnalysisInfo.Warnings)
+
+	// The workspace of the admin is analyzed with it
+	admin := out.Projects["apps/admin"]
+	assert.Equal(t, "acme/admin", admin.AnalysisInfo.ProjectName)
+	assert.Contains(t, admin.WorkSpaces, filepath.Join("packages", "ui", "composer.json"))
+	assert.Empty(t, admin.AnalysisInfo.Extra.UnrelatedManifests)
+
+	// The invalid lock of the legacy application is only reported for it
+	legacy := out.Projects["legacy"]
+	assert.Equal(t, types.SUCCESS_WITH_WARNINGS, legacy.AnalysisInfo.Status)
+	assert.Len(t, legacy.AnalysisInfo.Warnings, 1)
+	assert.Equal(t, filepath.Join("legacy", "composer.lock"), legacy.AnalysisInfo.Warnings[0].File)
+
+	data, err := export.GenerateProjects(out, export.FORMAT_CYCLONEDX)
+	assert.Nil(t, err)
+	var document map[string]map[string]any
+	assert.Nil(t, json.Unmarshal(data, &document))
+	assert.Len(t, document["projects"], 3)
+	assert.Equal(t, "CycloneDX", document["projects"]["apps/api"].(map[string]any)["bomFormat"])
+}
+
+func TestStartProjectsWithoutLockFiles(t *testing.T) {
+	root := t.TempDir()
+	writeManifest(t, root, "composer.json", `{"name": "acme/app"}`)
+	writeManifest(t, filepath.Join(root, "tools"), "composer.json", `{"name": "acme/tools"}`)
+
+	out := plugin.StartProjects(context.Background(), root, uuid.UUID{}, nil, options.Default())
+	// The project has no lock file
+	assert.Equal(t, types.SUCCESS_WITH_WARNINGS, out.Index.Status)
+	assert.Len(t, out.Index.Projects, 1)
+	assert.Equal(t, ".", out.Index.Projects[0].ID)
+	assert.Equal(t, []string{filepath.Join("tools", "composer.json")}, out.Projects["."].AnalysisInfo.Extra.UnrelatedManifests)
+
+	failed := plugin.StartProjects(context.Background(), t.TempDir(), uuid.UUID{}, nil, options.Default())
+	assert.Equal(t, codeclarity.FAILURE, failed.Index.Status)
+	assert.Len(t, failed.Index.Errors, 1)
+	assert.Empty(t, failed.Projects)
+}
+
+func TestMultiProjectOption(t *testing.T) {
+	opts, err := options.Parse(map[string]any{"multi_project": true})
+	assert.Nil(t, err)
+	assert.True(t, opts.MultiProject)
+	_, err = options.Parse(map[string]any{"multi_project": true, "root_manifest": "apps/api/composer.json"})
+	assert.NotNil(t, err)
+}
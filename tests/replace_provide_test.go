@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+
+	plugin "github.com/CodeClarityCE/plugin-php-sbom/src"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplaceAndProvideResolution(t *testing.T) {
+	out := plugin.Start("./test1", uuid.UUID{}, nil)
+	deps := out.WorkSpaces["."].Dependencies
+
+	// cakephp/orm is replaced by cakephp/cakephp
+	for _, migrations := range deps["cakephp/migrations"] {
+		assert.Contains(t, migrations.Requires, "cakephp/orm")
+		assert.Contains(t, migrations.Dependencies, "cakephp/cakephp")
+		assert.NotContains(t, migrations.Dependencies, "cakephp/orm")
+	}
+
+	// psr/http-message-implementation is a virtual package provided by laminas/laminas-diactoros
+	for _, runner := range deps["laminas/laminas-httphandlerrunner"] {
+		assert.Contains(t, runner.Dependencies, "laminas/laminas-diactoros")
+		assert.NotContains(t, runner.Dependencies, "psr/http-message-implementation")
+	}
+
+	// Replaced names are reported as sub-components of their provider
+	for version, cakephp := range deps["cakephp/cakephp"] {
+		assert.Equal(t, version, cakephp.Replaces["cakephp/orm"])
+		assert.Equal(t, "^3.0", cakephp.Provides["psr/log-implementation"])
+	}
+
+	// Polyfills providing extensions are linked to the platform requirement
+	mbstring, exists := out.WorkSpaces["."].Platform["ext-mbstring"]
+	assert.True(t, exists)
+	assert.Contains(t, mbstring.ProvidedBy, "symfony/polyfill-mbstring")
+
+	// No package is left unreachable once replaced names are resolved
+	for name, versions := range deps {
+		for _, version := range versions {
+			assert.NotZero(t, version.Depth, "%s should be reachable from the root", name)
+		}
+	}
+}
+
+func TestReplacedRequirementsAreMerged(t *testing.T) {
+	root := t.TempDir()
+	writeManifest(t, root, "composer.json", `{"name": "acme/app", "require": {"acme/bundle": "^1.0"}}`)
+	writeManifest(t, root, "composer.lock", `{"packages": [
+		{"name": "acme/bundle", "version": "1.0.0", "require": {
+			"symfony/console": "^6.4",
+			"symfony/http-kernel": "^6.4",
+			"symfony/yaml": "^6.0",
+			"symfony/symfony": "^6.4"
+		}},
+		{"name": "acme/legacy", "version": "1.0.0", "require": {
+			"symfony/console": "^5.4 || ^6.0",
+			"symfony/yaml": "^6.4"
+		}},
+		{"name": "symfony/symfony", "version": "v6.4.1", "replace": {
+			"symfony/console": "self.version",
+			"symfony/http-kernel": "self.version",
+			"symfony/yaml": "self.version"
+		}}
+	]}`)
+
+	// The same constraints whatever the order of the requirements
+	for i := 0; i < 10; i++ {
+		out := plugin.Start(root, uuid.UUID{}, nil)
+		bundle := out.WorkSpaces["."].Dependencies["acme/bundle"]["1.0.0"]
+		assert.Equal(t, map[string]string{"symfony/symfony": "^6.4,^6.0"}, bundle.Dependencies)
+		// Alternatives keep their meaning once merged
+		legacy := out.WorkSpaces["."].Dependencies["acme/legacy"]["1.0.0"]
+		assert.Equal(t, map[string]string{"symfony/symfony": "(^5.4 || ^6.0),^6.4"}, legacy.Dependencies)
+	}
+}
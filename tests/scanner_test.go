@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/diagnostics"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/options"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/parser"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/project_finder"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/scanner"
+	"github.com/stretchr/testify/assert"
+)
+
+// relativeFiles returns the files of a kind relative to root
+func relativeFiles(t *testing.T, root string, result scanner.Result, kind scanner.Kind) []string {
+	files := []string{}
+	for _, file := range result.Get(kind) {
+		relative, err := filepath.Rel(root, file)
+		assert.Nil(t, err)
+		files = append(files, filepath.ToSlash(relative))
+	}
+	return files
+}
+
+func TestScanProjectMatchers(t *testing.T) {
+	root := t.TempDir()
+	writeManifest(t, root, "composer.json", `{}`)
+	writeManifest(t, root, "composer.lock", `{}`)
+	writeManifest(t, root, "Dockerfile", "FROM php:8.3")
+	writeManifest(t, root, "phive.xml", "<phive/>")
+	writeManifest(t, filepath.Join(root, ".phive"), "phars.xml", "<phive/>")
+	writeManifest(t, filepath.Join(root, "docker"), "app.dockerfile", "FROM php:8.3")
+	writeManifest(t, filepath.Join(root, "tools"), "phpunit.phar", "")
+	writeManifest(t, filepath.Join(root, "vendor", "composer"), "installed.json", `{}`)
+	writeManifest(t, filepath.Join(root, "vendor", "acme", "lib"), "composer.json", `{}`)
+	writeManifest(t, filepath.Join(root, "node_modules", "lib"), "composer.json", `{}`)
+	writeManifest(t, filepath.Join(root, ".git"), "composer.json", `{}`)
+	writeManifest(t, filepath.Join(root, "packages", "lib"), "composer.json", `{}`)
+
+	result, err := parser.ScanProject(context.Background(), root, options.Default())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"composer.json", "packages/lib/composer.json"}, relativeFiles(t, root, result, scanner.KIND_COMPOSER_JSON))
+	assert.Equal(t, []string{"composer.lock"}, relativeFiles(t, root, result, scanner.KIND_COMPOSER_LOCK))
+	assert.Equal(t, []string{"tools/phpunit.phar"}, relativeFiles(t, root, result, scanner.KIND_PHAR))
+	assert.Equal(t, []string{"vendor/composer/installed.json"}, relativeFiles(t, root, result, scanner.KIND_INSTALLED_JSON))
+	assert.Equal(t, []string{".phive/phars.xml", "phive.xml"}, relativeFiles(t, root, result, scanner.KIND_PHIVE))
+	assert.Equal(t, []string{"Dockerfile", "docker/app.dockerfile"}, relativeFiles(t, root, result, scanner.KIND_DOCKERFILE))
+
+	opts := options.Default()
+	opts.ScanPHARs = false
+	opts.MaxDepth = 0
+	result, err = parser.ScanProject(context.Background(), root, opts)
+	assert.Nil(t, err)
+	assert.Empty(t, result.Get(scanner.KIND_PHAR))
+	assert.Equal(t, []string{"composer.json"}, relativeFiles(t, root, result, scanner.KIND_COMPOSER_JSON))
+}
+
+func TestScanGitignore(t *testing.T) {
+	root := t.TempDir()
+	writeManifest(t, root, ".gitignore", "# generated\nbuild/\n/tmp\n*.local.json\n**/fixtures/*\n!fixtures/kept/\ncomposer.lock\n")
+	writeManifest(t, root, "composer.json", `{}`)
+	writeManifest(t, root, "composer.lock", `{}`)
+	writeManifest(t, filepath.Join(root, "build"), "composer.json", `{}`)
+	writeManifest(t, filepath.Join(root, "tmp"), "composer.json", `{}`)
+	writeManifest(t, filepath.Join(root, "app", "tmp"), "composer.json", `{}`)
+	writeManifest(t, filepath.Join(root, "app", "fixtures", "broken"), "composer.json", `{}`)
+	writeManifest(t, filepath.Join(root, "fixtures", "kept"), "composer.json", `{}`)
+	writeManifest(t, filepath.Join(root, "lib"), ".gitignore", "composer.json\n")
+	writeManifest(t, filepath.Join(root, "lib"), "composer.json", `{}`)
+	writeManifest(t, filepath.Join(root, "lib"), "composer.lock", `{}`)
+
+	scan := scanner.New(options.Default(), scanner.ComposerJSON("composer.json"), scanner.ComposerLock("composer.lock"))
+	result, err := scan.Scan(context.Background(), root)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"app/tmp/composer.json", "composer.json", "fixtures/kept/composer.json"}, relativeFiles(t, root, result, scanner.KIND_COMPOSER_JSON))
+	// Lock files are found although ignored
+	assert.Equal(t, []string{"composer.lock", "lib/composer.lock"}, relativeFiles(t, root, result, scanner.KIND_COMPOSER_LOCK))
+
+	opts := options.Default()
+	opts.RespectGitignore = false
+	opts.ExcludePaths = []string{"app"}
+	result, err = scanner.New(opts, scanner.ComposerJSON("composer.json")).Scan(context.Background(), root)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"build/composer.json", "composer.json", "fixtures/kept/composer.json", "lib/composer.json", "tmp/composer.json"}, relativeFiles(t, root, result, scanner.KIND_COMPOSER_JSON))
+}
+
+func TestScanSymlinkLoop(t *testing.T) {
+	root := t.TempDir()
+	writeManifest(t, filepath.Join(root, "app"), "composer.json", `{}`)
+	if err := os.Symlink(root, filepath.Join(root, "app", "loop")); err != nil {
+		t.Skip("symlinks are not supported:", err)
+	}
+
+	opts := options.Default()
+	opts.FollowSymlinks = true
+	result, err := scanner.New(opts, scanner.ComposerJSON("composer.json")).Scan(context.Background(), root)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"app/composer.json"}, relativeFiles(t, root, result, scanner.KIND_COMPOSER_JSON))
+}
+
+func TestScanUnreadableDirectory(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced for root")
+	}
+	root := t.TempDir()
+	writeManifest(t, root, "composer.json", `{}`)
+	writeManifest(t, filepath.Join(root, "private"), "composer.json", `{}`)
+	assert.Nil(t, os.Chmod(filepath.Join(root, "private"), 0))
+	defer os.Chmod(filepath.Join(root, "private"), 0o755)
+
+	ctx, collector := diagnostics.NewContext(context.Background())
+	result, err := scanner.New(options.Default(), scanner.ComposerJSON("composer.json")).Scan(ctx, root)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"composer.json"}, relativeFiles(t, root, result, scanner.KIND_COMPOSER_JSON))
+	warnings := collector.Warnings()
+	assert.Len(t, warnings, 1)
+	assert.Equal(t, string(scanner.WARNING_UNREADABLE_DIRECTORY), warnings[0].Type)
+	assert.Equal(t, "private", warnings[0].File)
+}
+
+func TestScanCodeclarityIgnore(t *testing.T) {
+	root := t.TempDir()
+	writeManifest(t, root, ".gitignore", "legacy/\n")
+	writeManifest(t, root, ".codeclarityignore", "# test suites\ntests/fixtures/\n!legacy/\n")
+	writeManifest(t, root, "composer.json", `{"name": "acme/root", "repositories": [{"type": "path", "url": "legacy"}]}`)
+	writeManifest(t, filepath.Join(root, "legacy"), "composer.json", `{"name": "acme/legacy"}`)
+	writeManifest(t, filepath.Join(root, "packages", "lib"), "composer.json", `{"name": "acme/lib"}`)
+	writeManifest(t, filepath.Join(root, "packages", "lib"), ".codeclarityignore", "composer.json\n")
+	for _, fixture := range []string{"empty", "broken"} {
+		writeManifest(t, filepath.Join(root, "tests", "fixtures", fixture), "composer.json", `{}`)
+	}
+
+	result, err := parser.ScanProject(context.Background(), root, options.Default())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"composer.json", "legacy/composer.json"}, relativeFiles(t, root, result, scanner.KIND_COMPOSER_JSON))
+	assert.Equal(t, []scanner.Skip{
+		{Path: "packages/lib/composer.json", Reason: "packages/lib/.codeclarityignore:1: composer.json"},
+		{Path: "tests/fixtures/", Reason: ".codeclarityignore:2: tests/fixtures/"},
+	}, result.Skipped)
+
+	// The fixtures are not workspaces of the project
+	project, err := project_finder.FindPHPProjects(context.Background(), root, options.Default())
+	assert.Nil(t, err)
+	assert.Len(t, project.Workspaces, 1)
+	assert.Equal(t, "acme/legacy", project.Workspaces[0].Name)
+
+	opts := options.Default()
+	opts.RespectGitignore = false
+	opts.MaxDepth = 1
+	opts.ExcludePaths = []string{"legacy"}
+	result, err = parser.ScanProject(context.Background(), root, opts)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"composer.json"}, relativeFiles(t, root, result, scanner.KIND_COMPOSER_JSON))
+	assert.Equal(t, []scanner.Skip{
+		{Path: "legacy/", Reason: "exclude_paths"},
+		{Path: "packages/lib/", Reason: "deeper than max_depth 1"},
+		{Path: "tests/fixtures/", Reason: ".codeclarityignore:2: tests/fixtures/"},
+	}, result.Skipped)
+}
+
+func TestScanIgnoredLockFiles(t *testing.T) {
+	root := t.TempDir()
+	writeManifest(t, root, ".codeclarityignore", "apps/legacy/composer.lock\n")
+	for _, app := range []string{"api", "legacy", "lib"} {
+		writeManifest(t, filepath.Join(root, "apps", app), "composer.json", `{"name": "acme/`+app+`"}`)
+		writeManifest(t, filepath.Join(root, "apps", app), "composer.lock", `{"packages": []}`)
+	}
+	// A library ignoring its lock file in git still has one in the checkout
+	writeManifest(t, filepath.Join(root, "apps", "lib"), ".gitignore", "composer.lock\n")
+
+	result, err := parser.ScanProject(context.Background(), root, options.Default())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"apps/api/composer.lock", "apps/lib/composer.lock"}, relativeFiles(t, root, result, scanner.KIND_COMPOSER_LOCK))
+	assert.Equal(t, []scanner.Skip{
+		{Path: "apps/legacy/composer.lock", Reason: ".codeclarityignore:1: apps/legacy/composer.lock"},
+	}, result.Skipped)
+
+	// The legacy application is not a project of its own
+	roots := project_finder.FindProjectRoots(context.Background(), root, result, options.Default())
+	assert.Equal(t, []string{
+		filepath.Join(root, "apps", "api", "composer.json"),
+		filepath.Join(root, "apps", "lib", "composer.json"),
+	}, roots)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/export"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/server"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func multipartManifests(t *testing.T, dir string) (*bytes.Buffer, string) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for _, name := range []string{"composer.json", "composer.lock"} {
+		content, err := os.ReadFile(dir + "/" + name)
+		assert.Nil(t, err)
+		part, err := writer.CreateFormFile(name, name)
+		assert.Nil(t, err)
+		part.Write(content)
+	}
+	assert.Nil(t, writer.Close())
+	return body, writer.FormDataContentType()
+}
+
+func TestServerMultipart(t *testing.T) {
+	ts := httptest.NewServer(server.NewHandler())
+	defer ts.Close()
+
+	body, contentType := multipartManifests(t, "./hygiene")
+	response, err := http.Post(ts.URL+"/analyze", contentType, body)
+	assert.Nil(t, err)
+	defer response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, server.MEDIA_TYPE_JSON, response.Header.Get("Content-Type"))
+
+	var output types.Output
+	assert.Nil(t, json.NewDecoder(response.Body).Decode(&output))
+	assert.Contains(t, output.WorkSpaces["."].Dependencies, "acme/any")
+	assert.Equal(t, ".", output.AnalysisInfo.WorkingDirectory)
+}
+
+func TestServerNegotiation(t *testing.T) {
+	ts := httptest.NewServer(server.NewHandler())
+	defer ts.Close()
+
+	body, contentType := multipartManifests(t, "./hygiene")
+	request, _ := http.NewRequest(http.MethodPost, ts.URL+"/analyze", body)
+	request.Header.Set("Content-Type", contentType)
+	request.Header.Set("Accept", "application/json;q=0.5, application/vnd.cyclonedx+json")
+	response, err := http.DefaultClient.Do(request)
+	assert.Nil(t, err)
+	defer response.Body.Close()
+	assert.Equal(t, server.MEDIA_TYPE_CYCLONEDX, response.Header.Get("Content-Type"))
+
+	var bom export.CycloneDXBOM
+	assert.Nil(t, json.NewDecoder(response.Body).Decode(&bom))
+	assert.Equal(t, "CycloneDX", bom.BOMFormat)
+
+	request, _ = http.NewRequest(http.MethodPost, ts.URL+"/analyze", bytes.NewReader(nil))
+	request.Header.Set("Accept", "text/html")
+	response, err = http.DefaultClient.Do(request)
+	assert.Nil(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusNotAcceptable, response.StatusCode)
+}
+
+func TestServerArchive(t *testing.T) {
+	ts := httptest.NewServer(server.NewHandler())
+	defer ts.Close()
+
+	archive := &bytes.Buffer{}
+	writer := zip.NewWriter(archive)
+	for _, name := range []string{"composer.json", "composer.lock"} {
+		content, err := os.ReadFile("./hygiene/" + name)
+		assert.Nil(t, err)
+		entry, _ := writer.Create("project/" + name)
+		entry.Write(content)
+	}
+	assert.Nil(t, writer.Close())
+
+	response, err := http.Post(ts.URL+"/analyze?format=spdx", "application/zip", archive)
+	assert.Nil(t, err)
+	defer response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, server.MEDIA_TYPE_SPDX, response.Header.Get("Content-Type"))
+
+	// Entries escaping the upload directory are rejected
+	archive.Reset()
+	writer = zip.NewWriter(archive)
+	entry, _ := writer.Create("../composer.json")
+	entry.Write([]byte("{}"))
+	assert.Nil(t, writer.Close())
+	response, err = http.Post(ts.URL+"/analyze", "application/zip", archive)
+	assert.Nil(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+
+	response, err = http.Get(ts.URL + "/health")
+	assert.Nil(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+}
+
+func TestServerTimeout(t *testing.T) {
+	ts := httptest.NewServer(server.NewHandlerWithTimeout(time.Nanosecond))
+	defer ts.Close()
+
+	body, contentType := multipartManifests(t, "./hygiene")
+	response, err := http.Post(ts.URL+"/analyze", contentType, body)
+	assert.Nil(t, err)
+	defer response.Body.Close()
+
+	// The analysis stops at the deadline instead of holding the handler
+	var output types.Output
+	assert.Nil(t, json.NewDecoder(response.Body).Decode(&output))
+	assert.Equal(t, http.StatusUnprocessableEntity, response.StatusCode)
+	if assert.NotEmpty(t, output.AnalysisInfo.Errors) {
+		assert.Equal(t, "The analysis did not complete in time", output.AnalysisInfo.Errors[0].Public.Description)
+	}
+}
+
+func TestServerHidesSandboxPaths(t *testing.T) {
+	ts := httptest.NewServer(server.NewHandler())
+	defer ts.Close()
+
+	archive := &bytes.Buffer{}
+	writer := zip.NewWriter(archive)
+	for name, content := range map[string]string{
+		"composer.json":  `{"name": "acme/app"}`,
+		"tools/bad.phar": "not a PHAR archive",
+	} {
+		entry, _ := writer.Create(name)
+		entry.Write([]byte(content))
+	}
+	assert.Nil(t, writer.Close())
+	response, err := http.Post(ts.URL+"/analyze", "application/zip", archive)
+	assert.Nil(t, err)
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(response.Body)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(data), "php-sbom-upload-")
+	var output types.Output
+	assert.Nil(t, json.Unmarshal(data, &output))
+	if assert.Len(t, output.AnalysisInfo.Extra.PHARFiles, 1) {
+		assert.Equal(t, "tools/bad.phar", output.AnalysisInfo.Extra.PHARFiles[0].Path)
+	}
+}
+
+func TestRelocatePaths(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "sandbox", "upload")
+	sibling := root + "2" + string(filepath.Separator) + "composer.json"
+	output := types.Output{AnalysisInfo: types.AnalysisInfo{
+		WorkingDirectory: root,
+		Paths:            types.Paths{PackageFile: filepath.Join(root, "composer.json"), Lockfile: sibling},
+		Warnings: []types.Diagnostic{{
+			Description: "Cannot read " + filepath.Join(root, "app", "composer.lock") + ": permission denied, see " + sibling,
+		}},
+	}}
+
+	output.RelocatePaths(root, func(p string) string {
+		relative, _ := filepath.Rel(root, p)
+		return filepath.ToSlash(relative)
+	})
+	assert.Equal(t, ".", output.AnalysisInfo.WorkingDirectory)
+	assert.Equal(t, "composer.json", output.AnalysisInfo.Paths.PackageFile)
+	// Siblings sharing the prefix of the root are not under it
+	assert.Equal(t, sibling, output.AnalysisInfo.Paths.Lockfile)
+	assert.Equal(t, "Cannot read app/composer.lock: permission denied, see "+sibling, output.AnalysisInfo.Warnings[0].Description)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	plugin "github.com/CodeClarityCE/plugin-php-sbom/src"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/options"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeSharedLockMonorepo writes a monorepo whose package has no lock file,
+// its requirements being installed by the root lock file
+func writeSharedLockMonorepo(t *testing.T) string {
+	root := t.TempDir()
+	writeManifest(t, root, "composer.json", `{
+		"name": "acme/monorepo",
+		"require": {"acme/api": "*", "monolog/monolog": "^3.0"},
+		"repositories": [{"type": "path", "url": "packages/*"}]
+	}`)
+	writeManifest(t, root, "composer.lock", `{
+		"packages": [
+			{"name": "acme/api", "version": "dev-main", "dist": {"type": "path", "url": "packages/api"}, "require": {"acme/http": "^1.0"}},
+			{"name": "acme/http", "version": "1.2.0", "require": {"psr/log": "^3.0"}},
+			{"name": "psr/log", "version": "3.0.0"},
+			{"name": "monolog/monolog", "version": "3.5.0", "require": {"psr/log": "^3.0"}}
+		],
+		"packages-dev": [
+			{"name": "phpunit/phpunit", "version": "10.5.0"}
+		]
+	}`)
+	writeManifest(t, filepath.Join(root, "packages", "api"), "composer.json", `{
+		"name": "acme/api",
+		"require": {"php": ">=8.1", "acme/http": "^1.0"},
+		"require-dev": {"phpunit/phpunit": "^10.0"}
+	}`)
+	return root
+}
+
+func TestSharedLock(t *testing.T) {
+	root := writeSharedLockMonorepo(t)
+	opts := options.Default()
+	opts.SharedLock = true
+
+	out := plugin.StartWithOptions(root, uuid.UUID{}, nil, opts)
+	api := out.WorkSpaces["packages/api/composer.json"]
+	assert.True(t, api.SharedLock)
+	assert.ElementsMatch(t, []string{"acme/http", "psr/log", "phpunit/phpunit"}, dependencyNames(api.Dependencies))
+
+	http := api.Dependencies["acme/http"]["1.2.0"]
+	assert.True(t, http.Direct)
+	assert.True(t, http.Prod)
+	assert.Equal(t, 1, http.Depth)
+	psrLog := api.Dependencies["psr/log"]["3.0.0"]
+	assert.False(t, psrLog.Direct)
+	assert.True(t, psrLog.Transitive)
+	assert.Equal(t, 2, psrLog.Depth)
+	phpunit := api.Dependencies["phpunit/phpunit"]["10.5.0"]
+	assert.True(t, phpunit.Direct)
+	assert.True(t, phpunit.DevOnly)
+	assert.Equal(t, "1.2.0", api.Start.Dependencies[0].Version)
+
+	// The root keeps the whole lock file
+	assert.False(t, out.WorkSpaces["."].SharedLock)
+	assert.Contains(t, out.WorkSpaces["."].Dependencies, "monolog/monolog")
+
+	assert.Equal(t, filepath.Join(root, "composer.lock"), out.AnalysisInfo.Paths.WorkSpaceLockFile["packages/api/composer.json"])
+	member := out.AnalysisInfo.Workspaces.Members[1]
+	assert.Equal(t, "packages/api/composer.json", member.ID)
+	assert.True(t, member.SharedLock)
+	assert.Equal(t, "composer.lock", member.LockFile)
+}
+
+func TestWithoutSharedLock(t *testing.T) {
+	root := writeSharedLockMonorepo(t)
+
+	out := plugin.StartWithOptions(root, uuid.UUID{}, nil, options.Default())
+	api := out.WorkSpaces["packages/api/composer.json"]
+	assert.False(t, api.SharedLock)
+	assert.Empty(t, api.Dependencies)
+	assert.NotContains(t, out.AnalysisInfo.Paths.WorkSpaceLockFile, "packages/api/composer.json")
+
+	opts, err := options.Parse(map[string]any{"shared_lock": "true"})
+	assert.Nil(t, err)
+	assert.True(t, opts.SharedLock)
+}
+
+func TestSharedLockHygiene(t *testing.T) {
+	root := writeSharedLockMonorepo(t)
+	writeManifest(t, root, "composer.lock", `{
+		"packages": [
+			{"name": "acme/api", "version": "dev-main", "dist": {"type": "path", "url": "packages/api"}, "require": {"acme/http": "^1.0"}},
+			{"name": "acme/http", "version": "1.2.0", "require": {"psr/log": "*"}},
+			{"name": "psr/log", "version": "3.0.0"}
+		]
+	}`)
+	opts := options.Default()
+	opts.SharedLock = true
+
+	out := plugin.StartWithOptions(root, uuid.UUID{}, nil, opts)
+	findings := out.WorkSpaces["packages/api/composer.json"].ConstraintFindings
+
+	// The requirements of the packages taken from the root lock are checked there
+	var wildcard *types.ConstraintFinding
+	for i, finding := range findings {
+		if finding.RequiredBy == "acme/http" && finding.Dependency == "psr/log" {
+			wildcard = &findings[i]
+		}
+	}
+	if assert.NotNil(t, wildcard) {
+		assert.Equal(t, "wildcard", wildcard.Rule)
+		assert.Equal(t, "composer.lock", wildcard.File)
+		assert.Equal(t, 4, wildcard.Line)
+	}
+}
+
+// dependencyNames returns the names of the dependencies of a workspace
+func dependencyNames(dependencies map[string]map[string]types.Versions) []string {
+	names := []string{}
+	for name := range dependencies {
+		names = append(names, name)
+	}
+	return names
+}
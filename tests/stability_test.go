@@ -0,0 +1,94 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	plugin "github.com/CodeClarityCE/plugin-php-sbom/src"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStability(t *testing.T) {
+	out := plugin.Start("./test1", uuid.UUID{}, nil)
+	deps := out.WorkSpaces["."].Dependencies
+
+	// Pinned to a commit in the root and installed from the passbolt fork
+	queue := deps["lorenzo/cakephp-email-queue"]["dev-master"]
+	assert.Equal(t, "dev", queue.Stability)
+	assert.Contains(t, queue.StabilityRisks, "dev-branch")
+	assert.Contains(t, queue.StabilityRisks, "commit-pinned")
+	assert.Contains(t, queue.StabilityRisks, "fork")
+
+	for _, version := range deps["bacon/bacon-qr-code"] {
+		assert.Equal(t, "stable", version.Stability)
+		assert.Empty(t, version.StabilityRisks)
+	}
+
+	summary := out.AnalysisInfo.Extra.StabilitySummary
+	if assert.NotNil(t, summary) {
+		assert.Greater(t, summary.Forks, 0)
+		assert.Greater(t, summary.CommitPinned, 0)
+		found := false
+		for _, risk := range summary.RiskyProduction {
+			if risk.Package == "lorenzo/cakephp-email-queue" {
+				found = true
+				assert.Equal(t, ".", risk.Workspace)
+			}
+		}
+		assert.True(t, found)
+	}
+
+	// Dev branches resolved through aliases are still dev
+	aliases := plugin.Start("./aliases", uuid.UUID{}, nil).WorkSpaces["."].Dependencies
+	assert.Equal(t, "dev", aliases["acme/framework"]["dev-main"].Stability)
+	assert.Contains(t, aliases["acme/tooling"]["dev-master"].StabilityRisks, "dev-branch")
+}
+
+func TestStabilityRisksOrder(t *testing.T) {
+	root := t.TempDir()
+	writeManifest(t, root, "composer.json", `{
+		"name": "acme/monorepo",
+		"require": {"acme/client": "dev-main"},
+		"repositories": [{"type": "path", "url": "packages/*"}]
+	}`)
+	writeManifest(t, root, "composer.lock", `{"packages": [{"name": "acme/client", "version": "dev-main"}]}`)
+	api := filepath.Join(root, "packages", "api")
+	writeManifest(t, api, "composer.json", `{"name": "acme/api", "require": {"acme/client": "^2.0@beta"}}`)
+	writeManifest(t, api, "composer.lock", `{"packages": [{"name": "acme/client", "version": "2.0.0-beta1"}]}`)
+
+	out := plugin.Start(root, uuid.UUID{}, nil)
+	risky := out.AnalysisInfo.Extra.StabilitySummary.RiskyProduction
+	if assert.Len(t, risky, 2) {
+		// The versions of a package are ordered whatever the order of the workspaces
+		assert.Equal(t, "2.0.0-beta1", risky[0].Version)
+		assert.Equal(t, "dev-main", risky[1].Version)
+	}
+}
+
+func TestStabilityForkRepositoryURLs(t *testing.T) {
+	risks := func(repository string) []string {
+		root := t.TempDir()
+		writeManifest(t, root, "composer.json", `{
+			"name": "acme/app",
+			"require": {"lorenzo/cakephp-email-queue": "^1.0"},
+			"repositories": [{"type": "vcs", "url": "`+repository+`"}]
+		}`)
+		writeManifest(t, root, "composer.lock", `{"packages": [{
+			"name": "lorenzo/cakephp-email-queue",
+			"version": "1.0.0",
+			"source": {"type": "git", "url": "`+repository+`", "reference": "abc"}
+		}]}`)
+		return plugin.Start(root, uuid.UUID{}, nil).WorkSpaces["."].Dependencies["lorenzo/cakephp-email-queue"]["1.0.0"].StabilityRisks
+	}
+
+	// The owner comes after the host, whatever its port
+	for _, repository := range []string{
+		"https://git.example.com:8443/passbolt/cakephp-email-queue.git",
+		"ssh://git@git.example.com:2222/passbolt/cakephp-email-queue.git",
+		"git@git.example.com:passbolt/cakephp-email-queue.git",
+	} {
+		assert.Equal(t, []string{"fork"}, risks(repository), repository)
+	}
+	assert.Empty(t, risks("https://git.example.com:8443/lorenzo/cakephp-email-queue.git"))
+}
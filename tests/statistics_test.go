@@ -0,0 +1,66 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	plugin "github.com/CodeClarityCE/plugin-php-sbom/src"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatistics(t *testing.T) {
+	out := plugin.Start("./test1", uuid.UUID{}, nil)
+
+	stats := out.AnalysisInfo.Extra.Statistics
+	defaultWs := out.WorkSpaces["."]
+
+	assert.Equal(t, len(defaultWs.Dependencies), stats.TotalPackages)
+	assert.Greater(t, stats.DirectPackages, 0)
+	assert.Greater(t, stats.TransitivePackages, 0)
+	assert.Greater(t, stats.DevPackages, 0)
+	assert.Greater(t, stats.UniqueAuthors, 0)
+	assert.Greater(t, stats.UniqueLicenses, 0)
+	assert.Greater(t, stats.LicenseBreakdown["MIT"], 0)
+	assert.Greater(t, stats.TypeBreakdown["library"], 0)
+	assert.Greater(t, stats.TypeBreakdown["composer-plugin"], 0)
+
+	// Per workspace statistics match the aggregate for a single workspace
+	wsStats, exists := out.AnalysisInfo.Extra.WorkspaceStatistics["."]
+	assert.True(t, exists)
+	assert.Equal(t, stats, wsStats)
+}
+
+func TestStatisticsWorkspacesDisagree(t *testing.T) {
+	root := t.TempDir()
+	writeManifest(t, root, "composer.json", `{
+		"name": "acme/monorepo",
+		"require": {"acme/api": "*"},
+		"require-dev": {"phpunit/phpunit": "^10.0"},
+		"repositories": [{"type": "path", "url": "packages/*"}]
+	}`)
+	writeManifest(t, root, "composer.lock", `{
+		"packages": [
+			{"name": "acme/api", "version": "dev-main", "dist": {"type": "path", "url": "packages/api"}, "require": {"acme/http": "^1.0"}},
+			{"name": "acme/http", "version": "1.0.0", "require": {"psr/log": "^3.0"}},
+			{"name": "psr/log", "version": "3.0.0"}
+		],
+		"packages-dev": [{"name": "phpunit/phpunit", "version": "10.0.0"}]
+	}`)
+	api := filepath.Join(root, "packages", "api")
+	writeManifest(t, api, "composer.json", `{"name": "acme/api", "require": {"psr/log": "^3.0", "phpunit/phpunit": "^10.0"}}`)
+	writeManifest(t, api, "composer.lock", `{"packages": [
+		{"name": "psr/log", "version": "3.0.0"},
+		{"name": "phpunit/phpunit", "version": "10.0.0"}
+	]}`)
+
+	// psr/log is transitive in the root and direct in packages/api, phpunit
+	// is dev-only in the root and a production dependency of packages/api
+	for i := 0; i < 10; i++ {
+		stats := plugin.Start(root, uuid.UUID{}, nil).AnalysisInfo.Extra.Statistics
+		assert.Equal(t, 4, stats.TotalPackages)
+		assert.Equal(t, 3, stats.DirectPackages)
+		assert.Equal(t, 1, stats.TransitivePackages)
+		assert.Equal(t, 0, stats.DevPackages)
+	}
+}
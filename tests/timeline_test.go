@@ -0,0 +1,111 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/CodeClarityCE/plugin-php-sbom/src/gitobject"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/timeline"
+	"github.com/stretchr/testify/assert"
+)
+
+func lockWith(packages string) string {
+	return `{"packages": [` + packages + `], "packages-dev": [], "platform": [], "platform-dev": []}`
+}
+
+func TestTimeline(t *testing.T) {
+	repository := newTestRepository(t)
+	repository.write("README.md", "project")
+	repository.git("add", "-A")
+	repository.git("commit", "-q", "-m", "Initial commit")
+
+	repository.write("composer.lock", lockWith(`{"name": "acme/lib", "version": "1.0.0"}, {"name": "acme/old", "version": "2.0.0"}`))
+	repository.git("add", "-A")
+	repository.gitAs("alice", "commit", "-q", "-m", "Add dependencies")
+
+	repository.write("README.md", "unrelated change")
+	repository.git("commit", "-q", "-am", "Update readme")
+
+	repository.write("composer.lock", lockWith(`{"name": "acme/lib", "version": "2.1.0"}, {"name": "acme/old", "version": "2.0.0"}, {"name": "evil/package", "version": "0.1.0"}`))
+	repository.gitAs("bob", "commit", "-q", "-am", "Upgrade acme/lib")
+
+	repository.write("composer.lock", lockWith(`{"name": "acme/lib", "version": "2.1.0"}, {"name": "evil/package", "version": "0.1.0"}`))
+	repository.gitAs("carol", "commit", "-q", "-am", "Remove acme/old")
+
+	opened, err := gitobject.Open(repository.dir)
+	assert.Nil(t, err)
+	history, err := timeline.Build(opened, timeline.Options{})
+	assert.Nil(t, err)
+
+	assert.Len(t, history.Commits, 3)
+	assert.Equal(t, "Add dependencies", history.Commits[0].Summary)
+
+	packages := make(map[string]timeline.PackageHistory)
+	for _, pkg := range history.Packages {
+		packages[pkg.Name] = pkg
+	}
+
+	lib := packages["acme/lib"]
+	assert.Equal(t, "2.1.0", lib.Current)
+	if assert.Len(t, lib.Events, 2) {
+		assert.Equal(t, timeline.EVENT_INTRODUCED, lib.Events[0].Type)
+		assert.Equal(t, "alice", lib.Events[0].Commit.Author)
+		assert.Equal(t, timeline.EVENT_UPGRADED, lib.Events[1].Type)
+		assert.Equal(t, "major", lib.Events[1].Change)
+	}
+	spans := history.Span("acme/lib", "1.0.0")
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "bob", spans[0].Until.Author)
+	}
+
+	old := packages["acme/old"]
+	assert.Empty(t, old.Current)
+	assert.Equal(t, timeline.EVENT_REMOVED, old.Events[len(old.Events)-1].Type)
+	assert.Equal(t, "carol", old.Versions[0].Until.Author)
+
+	evil := packages["evil/package"]
+	assert.Equal(t, "bob", evil.Events[0].Commit.Author)
+	assert.Nil(t, evil.Versions[0].Until)
+
+	// The walk can be limited and started from an older revision
+	limited, err := timeline.Build(opened, timeline.Options{Revision: "main~1", MaxCommits: 2})
+	assert.Nil(t, err)
+	assert.True(t, limited.Truncated)
+	assert.Len(t, limited.Commits, 1)
+	for _, pkg := range limited.Packages {
+		assert.NotEqual(t, "acme/old", pkg.Name)
+		if pkg.Name == "acme/lib" {
+			assert.Equal(t, timeline.EVENT_UPGRADED, pkg.Events[0].Type)
+		}
+	}
+}
+
+func TestTimelineShallowClone(t *testing.T) {
+	repository := newTestRepository(t)
+	repository.write("composer.lock", lockWith(`{"name": "acme/lib", "version": "1.0.0"}, {"name": "acme/old", "version": "2.0.0"}`))
+	repository.git("add", "-A")
+	repository.git("commit", "-q", "-m", "Add dependencies")
+	repository.write("composer.lock", lockWith(`{"name": "acme/lib", "version": "2.0.0"}, {"name": "acme/old", "version": "2.0.0"}`))
+	repository.git("commit", "-q", "-am", "Upgrade acme/lib")
+	repository.write("composer.lock", lockWith(`{"name": "acme/lib", "version": "2.0.0"}`))
+	repository.gitAs("carol", "commit", "-q", "-am", "Remove acme/old")
+
+	// CI checkouts only fetch the last commits
+	clone := filepath.Join(t.TempDir(), "clone")
+	repository.git("clone", "-q", "--depth", "2", "file://"+repository.dir, clone)
+	opened, err := gitobject.Open(clone)
+	assert.Nil(t, err)
+
+	history, err := timeline.Build(opened, timeline.Options{})
+	assert.Nil(t, err)
+	assert.True(t, history.Truncated)
+	if assert.Len(t, history.Commits, 1) {
+		assert.Equal(t, "Remove acme/old", history.Commits[0].Summary)
+	}
+	for _, pkg := range history.Packages {
+		// The versions locked at the boundary are not introduced by the clone
+		assert.Equal(t, "acme/old", pkg.Name)
+		assert.Equal(t, timeline.EVENT_REMOVED, pkg.Events[0].Type)
+	}
+	assert.Len(t, history.Packages, 1)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	plugin "github.com/CodeClarityCE/plugin-php-sbom/src"
+	"github.com/CodeClarityCE/plugin-php-sbom/src/types"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkspaceIdentity(t *testing.T) {
+	root := t.TempDir()
+	writeManifest(t, root, "composer.json", `{"name": "acme/app", "repositories": [{"type": "path", "url": "packages/*"}, {"type": "path", "url": "packages/core/plugins/*"}]}`)
+	writeManifest(t, root, "composer.lock", `{"packages": []}`)
+	writeManifest(t, filepath.Join(root, "packages", "core"), "composer.json", `{"name": "acme/core"}`)
+	writeManifest(t, filepath.Join(root, "packages", "core"), "composer.lock", `{"packages": []}`)
+	writeManifest(t, filepath.Join(root, "packages", "core", "plugins", "seo"), "composer.json", `{"name": "acme/core"}`)
+	writeManifest(t, filepath.Join(root, "packages", "unnamed"), "composer.json", `{}`)
+
+	out := plugin.Start(root, uuid.UUID{}, nil)
+	members := out.AnalysisInfo.Workspaces.Members
+	assert.Len(t, members, 4)
+	assert.Equal(t, types.DEFAULT_WORKSPACE_CHARACTER, members[0].ID)
+
+	// Each member is a workspace of the output with its files in the paths
+	for _, member := range members {
+		assert.Contains(t, out.WorkSpaces, member.ID)
+		assert.Contains(t, out.AnalysisInfo.Paths.WorkSpacePackageFile, member.ID)
+		_, hasLock := out.AnalysisInfo.Paths.WorkSpaceLockFile[member.ID]
+		assert.Equal(t, member.LockFile != "", hasLock, member.ID)
+	}
+	assert.Len(t, out.WorkSpaces, len(members))
+
+	byID := map[string]types.Workspace{}
+	for _, member := range members {
+		byID[member.ID] = member
+	}
+	assert.Equal(t, types.Workspace{
+		ID:          "packages/core/composer.json",
+		Name:        "acme/core (packages/core)",
+		PackageName: "acme/core",
+		Directory:   "packages/core",
+		PackageFile: "packages/core/composer.json",
+		LockFile:    "packages/core/composer.lock",
+		Parent:      types.DEFAULT_WORKSPACE_CHARACTER,
+		Children:    []string{"packages/core/plugins/seo/composer.json"},
+		Source:      "path-repository",
+	}, byID["packages/core/composer.json"])
+
+	seo := byID["packages/core/plugins/seo/composer.json"]
+	assert.Equal(t, "acme/core (packages/core/plugins/seo)", seo.Name)
+	assert.Equal(t, "packages/core/composer.json", seo.Parent)
+
+	unnamed := byID["packages/unnamed/composer.json"]
+	assert.Equal(t, "packages/unnamed", unnamed.Name)
+	assert.Empty(t, unnamed.PackageName)
+
+	assert.Equal(t, "acme/app", byID["."].Name)
+	assert.Equal(t, "composer.lock", byID["."].LockFile)
+	assert.ElementsMatch(t, []string{"packages/core/composer.json", "packages/unnamed/composer.json"}, byID["."].Children)
+}
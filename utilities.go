@@ -31,7 +31,7 @@ import (
 // 3. Reads the message and unmarshals it into a dispatcherMessage struct.
 // 4. Starts a timer to measure the execution time.
 // 5. Retrieves the analysis document from the database.
-// 6. Starts the analysis using the startAnalysis function.
+// 6. Starts the analysis using the startAnalysis function, bounded by ANALYSIS_TIMEOUT.
 // 7. Prints the elapsed time.
 // 8. Updates the analysis with the results and status.
 // 9. Commits the transaction.
@@ -94,8 +94,10 @@ func callback(args any, config plugin.Plugin, message []byte) {
 		return
 	}
 
-	// Start analysis
-	result, status, err := startAnalysis(s, dispatcherMessage, config, analysis_document)
+	// Start analysis, within the deadline of the message
+	analysisCtx, cancel := context.WithTimeout(ctx, analysisTimeout())
+	defer cancel()
+	result, status, err := startAnalysis(analysisCtx, s, dispatcherMessage, config, analysis_document)
 	if err != nil {
 		log.Printf("%v", err)
 		return
@@ -122,6 +124,25 @@ func callback(args any, config plugin.Plugin, message []byte) {
 	amqp_helper.Send("plugins_dispatcher", data)
 }
 
+// DEFAULT_ANALYSIS_TIMEOUT bounds an analysis when ANALYSIS_TIMEOUT is not set
+const DEFAULT_ANALYSIS_TIMEOUT = 30 * time.Minute
+
+// analysisTimeout returns the deadline of an analysis message, read from
+// ANALYSIS_TIMEOUT as a duration such as "10m". The timeout option of an
+// analysis can only shorten it.
+func analysisTimeout() time.Duration {
+	value := os.Getenv("ANALYSIS_TIMEOUT")
+	if value == "" {
+		return DEFAULT_ANALYSIS_TIMEOUT
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		log.Printf("Invalid ANALYSIS_TIMEOUT %q, using %s", value, DEFAULT_ANALYSIS_TIMEOUT)
+		return DEFAULT_ANALYSIS_TIMEOUT
+	}
+	return timeout
+}
+
 // readConfig reads the configuration file and returns a Plugin object and an error.
 // The configuration file is expected to be named "config.json" and should be located in the same directory as the source file.
 // If the file cannot be opened or if there is an error decoding the file, an error is returned.